@@ -0,0 +1,412 @@
+package addons
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"zync-stream/httpcache"
+)
+
+const (
+	ResourceCatalog   = "catalog"
+	ResourceMeta      = "meta"
+	ResourceStream    = "stream"
+	ResourceSubtitles = "subtitles"
+
+	DefaultAddonTimeout = 8 * time.Second
+)
+
+// AddonResult reports how one addon answered an aggregated request
+type AddonResult struct {
+	AddonID    string `json:"addon_id"`
+	Name       string `json:"name"`
+	OK         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"`
+	Items      int    `json:"items"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Aggregator fans resource requests out to a user's addons and merges the
+// answers. Every addon request goes through cache, whose client only dials
+// public addresses.
+type Aggregator struct {
+	repo    *AddonRepository
+	cache   *httpcache.Cache
+	timeout time.Duration
+}
+
+func NewAggregator(repo *AddonRepository, cache *httpcache.Cache, timeout time.Duration) *Aggregator {
+	return &Aggregator{
+		repo:    repo,
+		cache:   cache,
+		timeout: timeout,
+	}
+}
+
+// NewAggregatorFromEnv reads the per-addon timeout from ADDON_TIMEOUT
+func NewAggregatorFromEnv(repo *AddonRepository, cache *httpcache.Cache) *Aggregator {
+	timeout := DefaultAddonTimeout
+	if value := os.Getenv("ADDON_TIMEOUT"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			timeout = parsed
+		}
+	}
+	return NewAggregator(repo, cache, timeout)
+}
+
+type installedAddon struct {
+	addon    *Addon
+	manifest Manifest
+}
+
+type addonResponse struct {
+	body   map[string]interface{}
+	result AddonResult
+}
+
+// Catalog merges the metas of every addon serving the catalog, keeping the
+// first occurrence of each id in addon order
+func (a *Aggregator) Catalog(ctx context.Context, userID int, mediaType, catalogID, extra string) ([]interface{}, []AddonResult, error) {
+	addons, err := a.repo.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	responses := a.fanOut(ctx, addons, func(m *Manifest) bool {
+		return m.hasCatalog(mediaType, catalogID)
+	}, ResourceCatalog, mediaType, catalogID, extra)
+
+	return mergeCatalog(responses), results(responses), nil
+}
+
+func mergeCatalog(responses []addonResponse) []interface{} {
+	metas := []interface{}{}
+	seen := make(map[string]bool)
+	for i := range responses {
+		items, _ := responses[i].body["metas"].([]interface{})
+		responses[i].result.Items = len(items)
+		for _, item := range items {
+			meta, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id, _ := meta["id"].(string)
+			if id == "" || seen[id] {
+				continue
+			}
+			seen[id] = true
+			metas = append(metas, meta)
+		}
+	}
+	return metas
+}
+
+// Meta returns the metadata from the highest-priority addon that has it
+func (a *Aggregator) Meta(ctx context.Context, userID int, mediaType, id string) (map[string]interface{}, []AddonResult, error) {
+	addons, err := a.repo.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	responses := a.fanOut(ctx, addons, func(m *Manifest) bool {
+		return m.serves(ResourceMeta, mediaType, id)
+	}, ResourceMeta, mediaType, id, "")
+
+	var meta map[string]interface{}
+	for i := range responses {
+		found, ok := responses[i].body["meta"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		responses[i].result.Items = 1
+		if meta == nil {
+			meta = found
+		}
+	}
+
+	return meta, results(responses), nil
+}
+
+func (a *Aggregator) Streams(ctx context.Context, userID int, mediaType, id string) ([]interface{}, []AddonResult, error) {
+	addons, err := a.repo.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	streams, results := a.StreamsFrom(ctx, addons, mediaType, id)
+	return streams, results, nil
+}
+
+// StreamsFrom concatenates the given addons' streams, dropping any that point
+// at the same torrent file or URL as an earlier one, then ranks them by health
+func (a *Aggregator) StreamsFrom(ctx context.Context, addons []*Addon, mediaType, id string) ([]interface{}, []AddonResult) {
+	responses := a.fanOut(ctx, addons, func(m *Manifest) bool {
+		return m.serves(ResourceStream, mediaType, id)
+	}, ResourceStream, mediaType, id, "")
+
+	streams := mergeStreams(responses)
+	a.rankStreams(ctx, streams)
+
+	return streams, results(responses)
+}
+
+func mergeStreams(responses []addonResponse) []interface{} {
+	streams := []interface{}{}
+	seen := make(map[string]bool)
+	for i := range responses {
+		items, _ := responses[i].body["streams"].([]interface{})
+		responses[i].result.Items = len(items)
+		for _, item := range items {
+			stream, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if key := streamKey(stream); key != "" {
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			stream["addon"] = map[string]string{
+				"id":   responses[i].result.AddonID,
+				"name": responses[i].result.Name,
+			}
+			streams = append(streams, stream)
+		}
+	}
+	return streams
+}
+
+// Subtitles merges the subtitle tracks of the user's addons, dropping
+// repeated URLs and tagging each track with the addon it came from
+func (a *Aggregator) Subtitles(ctx context.Context, userID int, mediaType, id, extra string) ([]map[string]interface{}, []AddonResult, error) {
+	addons, err := a.repo.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	responses := a.fanOut(ctx, addons, func(m *Manifest) bool {
+		return m.serves(ResourceSubtitles, mediaType, id)
+	}, ResourceSubtitles, mediaType, id, extra)
+
+	return mergeSubtitles(responses), results(responses), nil
+}
+
+func mergeSubtitles(responses []addonResponse) []map[string]interface{} {
+	tracks := []map[string]interface{}{}
+	seen := make(map[string]bool)
+	for i := range responses {
+		items, _ := responses[i].body["subtitles"].([]interface{})
+		responses[i].result.Items = len(items)
+		for _, item := range items {
+			track, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			url, _ := track["url"].(string)
+			if url == "" || seen[url] {
+				continue
+			}
+			seen[url] = true
+			track["addon"] = map[string]string{
+				"id":   responses[i].result.AddonID,
+				"name": responses[i].result.Name,
+			}
+			tracks = append(tracks, track)
+		}
+	}
+	return tracks
+}
+
+// fanOut queries every addon accepted by supports in parallel, each under
+// its own timeout. Responses keep the order addons were given in.
+func (a *Aggregator) fanOut(ctx context.Context, addons []*Addon, supports func(*Manifest) bool, resource, mediaType, id, extra string) []addonResponse {
+	var targets []installedAddon
+	for _, addon := range parseManifests(addons) {
+		if supports(&addon.manifest) {
+			targets = append(targets, addon)
+		}
+	}
+
+	responses := make([]addonResponse, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target installedAddon) {
+			defer wg.Done()
+
+			addonCtx, cancel := context.WithTimeout(ctx, a.timeout)
+			defer cancel()
+
+			started := time.Now()
+			body, err := a.get(addonCtx, ResourceURL(target.addon.ManifestURL(), resource, mediaType, id, extra))
+
+			responses[i] = addonResponse{
+				body: body,
+				result: AddonResult{
+					AddonID:    target.addon.AddonID,
+					Name:       target.addon.Name,
+					OK:         err == nil,
+					DurationMS: time.Since(started).Milliseconds(),
+				},
+			}
+			if err != nil {
+				responses[i].result.Error = err.Error()
+			}
+		}(i, target)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, response := range responses {
+		if !response.result.OK {
+			failed++
+		}
+	}
+	log.Printf("Addon %s %s/%s: %d addons, %d failed", resource, mediaType, id, len(responses), failed)
+
+	return responses
+}
+
+// parseManifests skips disabled addons and any whose stored manifest is unreadable
+func parseManifests(addons []*Addon) []installedAddon {
+	installed := make([]installedAddon, 0, len(addons))
+	for _, addon := range addons {
+		if !addon.Enabled {
+			continue
+		}
+		entry := installedAddon{addon: addon}
+		if err := json.Unmarshal(addon.Manifest, &entry.manifest); err != nil {
+			log.Printf("Skipping addon %s with unreadable manifest: %v", addon.AddonID, err)
+			continue
+		}
+		installed = append(installed, entry)
+	}
+	return installed
+}
+
+func (a *Aggregator) get(ctx context.Context, endpoint string) (map[string]interface{}, error) {
+	data, err := a.cache.Get(ctx, endpoint)
+	if err != nil {
+		var statusErr *httpcache.StatusError
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, errors.New("timed out")
+		case errors.As(err, &statusErr):
+			return nil, fmt.Errorf("addon responded with %d", statusErr.StatusCode)
+		default:
+			return nil, errors.New("addon is unreachable")
+		}
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, errors.New("addon returned invalid JSON")
+	}
+	return body, nil
+}
+
+// ResourceURL builds {base}/{resource}/{type}/{id}[/{extra}].json
+func ResourceURL(manifestURL, resource, mediaType, id, extra string) string {
+	endpoint := fmt.Sprintf("%s/%s/%s/%s", BaseURL(manifestURL), resource, url.PathEscape(mediaType), url.PathEscape(id))
+	if extra != "" {
+		endpoint += "/" + extra
+	}
+	return endpoint + ".json"
+}
+
+// ExtraFromQuery encodes catalog query parameters (search, skip, genre) as
+// the already-escaped extra path segment Stremio expects
+func ExtraFromQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, url.PathEscape(key)+"="+url.PathEscape(query.Get(key)))
+	}
+	return strings.Join(parts, "&")
+}
+
+func results(responses []addonResponse) []AddonResult {
+	out := make([]AddonResult, len(responses))
+	for i, response := range responses {
+		out[i] = response.result
+	}
+	return out
+}
+
+func streamKey(stream map[string]interface{}) string {
+	if infoHash, ok := stream["infoHash"].(string); ok && infoHash != "" {
+		fileIdx, _ := stream["fileIdx"].(float64)
+		return fmt.Sprintf("torrent:%s:%d", strings.ToLower(infoHash), int(fileIdx))
+	}
+	for _, field := range []string{"url", "ytId", "externalUrl"} {
+		if value, ok := stream[field].(string); ok && value != "" {
+			return field + ":" + value
+		}
+	}
+	return ""
+}
+
+func (m *Manifest) hasCatalog(mediaType, catalogID string) bool {
+	for _, catalog := range m.Catalogs {
+		if catalog.Type == mediaType && catalog.ID == catalogID {
+			return true
+		}
+	}
+	return false
+}
+
+// serves reports whether the addon handles the resource for this type and id,
+// honouring per-resource types and id prefixes over the manifest-wide ones
+func (m *Manifest) serves(name, mediaType, id string) bool {
+	for _, resource := range m.Resources {
+		if resource.Name != name {
+			continue
+		}
+
+		types, prefixes := resource.Types, resource.IDPrefixes
+		if len(types) == 0 {
+			types = m.Types
+		}
+		if len(prefixes) == 0 {
+			prefixes = m.IDPrefixes
+		}
+
+		if !contains(types, mediaType) {
+			return false
+		}
+		if len(prefixes) == 0 {
+			return true
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(id, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
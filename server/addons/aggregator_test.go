@@ -0,0 +1,175 @@
+package addons
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"zync-stream/httpcache"
+)
+
+const testManifest = `{"id":"%s","version":"1.0.0","name":"%s","resources":["catalog","stream","subtitles"],
+	"types":["movie"],"catalogs":[{"type":"movie","id":"top"}]}`
+
+// fakeAddons serves one addon per path prefix. The first is slow, so its
+// answer arrives after the others.
+func fakeAddons(t *testing.T) *httptest.Server {
+	t.Helper()
+	responses := map[string]string{
+		"/a/catalog/movie/top.json": `{"metas":[{"id":"tt1","name":"One"},{"id":"tt2","name":"Two from A"},{"name":"no id"},"not an object"]}`,
+		"/b/catalog/movie/top.json": `{"metas":[{"id":"tt2","name":"Two from B"},{"id":"tt3","name":"Three"}]}`,
+
+		"/a/stream/movie/tt1.json": `{"streams":[
+			{"infoHash":"ABCDEF","fileIdx":1,"title":"a torrent"},
+			{"url":"https://cdn.example/1.mp4","title":"a url"},
+			{"title":"a keyless"}]}`,
+		"/b/stream/movie/tt1.json": `{"streams":[
+			{"infoHash":"abcdef","fileIdx":1,"title":"b same torrent"},
+			{"infoHash":"abcdef","fileIdx":2,"title":"b other file"},
+			{"url":"https://cdn.example/1.mp4","title":"b same url"},
+			{"title":"b keyless"}]}`,
+
+		"/a/subtitles/movie/tt1.json": `{"subtitles":[{"id":"1","url":"https://subs.example/1.srt","lang":"eng"},{"id":"2","url":""}]}`,
+		"/b/subtitles/movie/tt1.json": `{"subtitles":[{"id":"3","url":"https://subs.example/1.srt","lang":"eng"},{"id":"4","url":"https://subs.example/2.srt","lang":"fre"}]}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/a/") {
+			time.Sleep(30 * time.Millisecond)
+		}
+		if strings.HasPrefix(r.URL.Path, "/broken/") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testAddons(base string) []*Addon {
+	addon := func(id, name, path string, enabled bool) *Addon {
+		return &Addon{
+			AddonID:      id,
+			Name:         name,
+			TransportURL: base + path + "/manifest.json",
+			Manifest:     json.RawMessage(fmt.Sprintf(testManifest, id, name)),
+			Enabled:      enabled,
+		}
+	}
+	return []*Addon{
+		addon("org.example.a", "A", "/a", true),
+		addon("org.example.off", "Disabled", "/off", false),
+		addon("org.example.b", "B", "/b", true),
+		addon("org.example.broken", "Broken", "/broken", true),
+	}
+}
+
+func newTestAggregator() *Aggregator {
+	return NewAggregator(nil, httpcache.NewCache(nil, http.DefaultClient, time.Minute, time.Minute), time.Second)
+}
+
+func field(items []interface{}, name string) []string {
+	values := make([]string, len(items))
+	for i, item := range items {
+		values[i], _ = item.(map[string]interface{})[name].(string)
+	}
+	return values
+}
+
+func TestMergeCatalog(t *testing.T) {
+	server := fakeAddons(t)
+	aggregator := newTestAggregator()
+
+	responses := aggregator.fanOut(context.Background(), testAddons(server.URL), func(m *Manifest) bool {
+		return m.hasCatalog("movie", "top")
+	}, ResourceCatalog, "movie", "top", "")
+	metas := mergeCatalog(responses)
+
+	// the first addon's copy of tt2 wins although it answered last
+	if got := strings.Join(field(metas, "name"), "|"); got != "One|Two from A|Three" {
+		t.Errorf("merged catalog = %s", got)
+	}
+
+	results := results(responses)
+	if len(results) != 3 {
+		t.Fatalf("results = %+v, want the disabled addon skipped", results)
+	}
+	wantItems := []int{4, 2, 0}
+	for i, result := range results {
+		if result.Items != wantItems[i] {
+			t.Errorf("result %d items = %d, want %d", i, result.Items, wantItems[i])
+		}
+	}
+	if results[0].AddonID != "org.example.a" || results[2].OK || !strings.Contains(results[2].Error, "500") {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestMergeStreams(t *testing.T) {
+	server := fakeAddons(t)
+	aggregator := newTestAggregator()
+
+	responses := aggregator.fanOut(context.Background(), testAddons(server.URL), func(m *Manifest) bool {
+		return m.serves(ResourceStream, "movie", "tt1")
+	}, ResourceStream, "movie", "tt1", "")
+	streams := mergeStreams(responses)
+
+	// torrents match case-insensitively per file, urls exactly, and streams
+	// without either are never merged
+	want := "a torrent|a url|a keyless|b other file|b keyless"
+	if got := strings.Join(field(streams, "title"), "|"); got != want {
+		t.Errorf("merged streams = %s, want %s", got, want)
+	}
+
+	for i, item := range streams {
+		addon := item.(map[string]interface{})["addon"].(map[string]string)
+		wantID := "org.example.a"
+		if i >= 3 {
+			wantID = "org.example.b"
+		}
+		if addon["id"] != wantID {
+			t.Errorf("stream %d tagged with %v, want %s", i, addon, wantID)
+		}
+	}
+}
+
+func TestMergeSubtitles(t *testing.T) {
+	server := fakeAddons(t)
+	aggregator := newTestAggregator()
+
+	responses := aggregator.fanOut(context.Background(), testAddons(server.URL), func(m *Manifest) bool {
+		return m.serves(ResourceSubtitles, "movie", "tt1")
+	}, ResourceSubtitles, "movie", "tt1", "")
+	tracks := mergeSubtitles(responses)
+
+	var ids []string
+	for _, track := range tracks {
+		ids = append(ids, track["id"].(string)+"@"+track["addon"].(map[string]string)["name"])
+	}
+	if got := strings.Join(ids, "|"); got != "1@A|4@B" {
+		t.Errorf("merged subtitles = %s", got)
+	}
+}
+
+func TestAggregatorRefusesInternalAddons(t *testing.T) {
+	server := fakeAddons(t)
+	aggregator := NewAggregator(nil, httpcache.NewCacheFromEnv(nil), time.Second)
+
+	responses := aggregator.fanOut(context.Background(), testAddons(server.URL)[:1], func(m *Manifest) bool {
+		return true
+	}, ResourceCatalog, "movie", "top", "")
+	if len(responses) != 1 || responses[0].result.OK || responses[0].body != nil {
+		t.Errorf("response from a loopback addon = %+v", responses)
+	}
+}
@@ -0,0 +1,171 @@
+package addons
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"zync-stream/httpcache"
+)
+
+type AddonHandlers struct {
+	repo       *AddonRepository
+	aggregator *Aggregator
+	cache      *httpcache.Cache
+}
+
+func NewAddonHandlers(repo *AddonRepository, aggregator *Aggregator, cache *httpcache.Cache) *AddonHandlers {
+	return &AddonHandlers{
+		repo:       repo,
+		aggregator: aggregator,
+		cache:      cache,
+	}
+}
+
+// resourceParams reads :type and :id, accepting the .json suffix Stremio clients add
+func resourceParams(c *gin.Context) (string, string, bool) {
+	mediaType := c.Param("type")
+	id := strings.TrimSuffix(c.Param("id"), ".json")
+	if mediaType == "" || id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Type and id are required"})
+		return "", "", false
+	}
+	return mediaType, id, true
+}
+
+func (h *AddonHandlers) GetCatalog(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	mediaType, catalogID, ok := resourceParams(c)
+	if !ok {
+		return
+	}
+
+	metas, results, err := h.aggregator.Catalog(c.Request.Context(), userID.(int), mediaType, catalogID, ExtraFromQuery(c.Request.URL.Query()))
+	if err != nil {
+		log.Printf("Error aggregating catalog: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load catalog"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"metas":  metas,
+		"addons": results,
+	})
+}
+
+func (h *AddonHandlers) GetMeta(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	mediaType, id, ok := resourceParams(c)
+	if !ok {
+		return
+	}
+
+	meta, results, err := h.aggregator.Meta(c.Request.Context(), userID.(int), mediaType, id)
+	if err != nil {
+		log.Printf("Error aggregating meta: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load metadata"})
+		return
+	}
+
+	if meta == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":  "No addon returned metadata for this title",
+			"addons": results,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"meta":   meta,
+		"addons": results,
+	})
+}
+
+func (h *AddonHandlers) GetStreams(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	mediaType, id, ok := resourceParams(c)
+	if !ok {
+		return
+	}
+
+	streams, results, err := h.aggregator.Streams(c.Request.Context(), userID.(int), mediaType, id)
+	if err != nil {
+		log.Printf("Error aggregating streams: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load streams"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"streams": streams,
+		"addons":  results,
+	})
+}
+
+type streamReportRequest struct {
+	Stream          map[string]interface{} `json:"stream" binding:"required"`
+	Outcome         string                 `json:"outcome" binding:"required,oneof=loaded failed"`
+	StartupMS       *int                   `json:"startup_ms" binding:"omitempty,min=0"`
+	BufferingEvents int                    `json:"buffering_events" binding:"min=0"`
+}
+
+// ReportStream records how a stream behaved for the caller: whether it
+// loaded, how long it took to start and how often it buffered
+func (h *AddonHandlers) ReportStream(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req streamReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	key := streamKey(req.Stream)
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Stream has no infoHash or url"})
+		return
+	}
+
+	report := StreamReport{
+		Outcome:         req.Outcome,
+		StartupMS:       req.StartupMS,
+		BufferingEvents: req.BufferingEvents,
+	}
+	if req.Outcome == OutcomeFailed {
+		report.StartupMS = nil
+	}
+
+	if err := h.repo.ReportStream(c.Request.Context(), userID.(int), key, report); err != nil {
+		log.Printf("Error recording stream report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record stream report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Report recorded"})
+}
+
+// GetCacheStats reports hit rates of the shared addon and metadata response
+// cache. It is routed for admins only.
+func (h *AddonHandlers) GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.cache.Stats())
+}
@@ -0,0 +1,56 @@
+package addons
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// StartRegistryHealthChecker re-fetches every registry manifest on each tick,
+// refreshing the stored copy or counting towards marking the entry dead
+func StartRegistryHealthChecker(ctx context.Context, repo *RegistryRepository, manifests *ManifestClient, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				CheckRegistry(ctx, repo, manifests)
+			}
+		}
+	}()
+}
+
+func CheckRegistry(ctx context.Context, repo *RegistryRepository, manifests *ManifestClient) {
+	entries, err := repo.List(ctx, RegistryFilter{IncludeDead: true})
+	if err != nil {
+		log.Printf("Failed to list addon registry for health check: %v", err)
+		return
+	}
+
+	for _, entry := range entries {
+		manifest, raw, err := manifests.Fetch(ctx, entry.ManifestURL)
+		if err == nil && manifest.ID != entry.AddonID {
+			err = errAddonIDChanged
+		}
+
+		if err == nil {
+			if err := repo.MarkHealthy(ctx, entry.ID, manifest, raw); err != nil {
+				log.Printf("Failed to update registry entry %d: %v", entry.ID, err)
+			}
+			continue
+		}
+
+		status, markErr := repo.MarkFailed(ctx, entry.ID, err.Error())
+		if markErr != nil {
+			log.Printf("Failed to update registry entry %d: %v", entry.ID, markErr)
+			continue
+		}
+		if status == HealthDead && entry.HealthStatus != HealthDead {
+			log.Printf("Registry addon %s marked dead: %v", entry.AddonID, err)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package addons
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"zync-stream/netguard"
+)
+
+var versionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+([-+][0-9A-Za-z.-]+)?$`)
+
+// ManifestClient fetches and validates addon manifests
+type ManifestClient struct {
+	client *http.Client
+}
+
+// NewManifestClient only reaches public addresses, checking every redirect
+func NewManifestClient() *ManifestClient {
+	return &ManifestClient{
+		client: netguard.NewClient(10 * time.Second),
+	}
+}
+
+// NormalizeURL turns what users paste into the manifest URL of an addon:
+// stremio:// links become https and a missing manifest.json is appended.
+func NormalizeURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "stremio://") {
+		raw = "https://" + strings.TrimPrefix(raw, "stremio://")
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", errors.New("must be an absolute http or https URL")
+	}
+
+	parsed.Fragment = ""
+	if !strings.HasSuffix(parsed.Path, "/manifest.json") {
+		parsed.Path = strings.TrimRight(parsed.Path, "/") + "/manifest.json"
+	}
+
+	return parsed.String(), nil
+}
+
+// BaseURL is the addon's transport URL without the trailing manifest.json,
+// which resource paths are appended to
+func BaseURL(manifestURL string) string {
+	return strings.TrimSuffix(manifestURL, "/manifest.json")
+}
+
+// ConfiguredURL inserts a configurable addon's settings segment, as in
+// https://addon.example/{config}/manifest.json
+func ConfiguredURL(manifestURL, config string) string {
+	if config == "" {
+		return manifestURL
+	}
+	return BaseURL(manifestURL) + "/" + config + "/manifest.json"
+}
+
+// ValidateConfig accepts the settings segments Stremio addons produce, which
+// are query-string like ("providers=yts,eztv|sort=size") but must stay one path segment
+func ValidateConfig(config string) error {
+	if len(config) > maxConfigLength {
+		return errors.New("config is too long")
+	}
+	if strings.ContainsAny(config, "/?#\\ \t\r\n") {
+		return errors.New("config may not contain slashes, whitespace, ? or #")
+	}
+	return nil
+}
+
+// Fetch downloads and validates a manifest, returning it both parsed and raw
+func (c *ManifestClient) Fetch(ctx context.Context, manifestURL string) (*Manifest, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if errors.Is(err, netguard.ErrBlockedAddress) {
+			return nil, nil, errors.New("manifest must be on a public address")
+		}
+		return nil, nil, errors.New("manifest is unreachable")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("manifest responded with %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes+1))
+	if err != nil {
+		return nil, nil, errors.New("failed to read manifest")
+	}
+	if len(body) > maxManifestBytes {
+		return nil, nil, errors.New("manifest is too large")
+	}
+
+	manifest, err := ParseManifest(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return manifest, body, nil
+}
+
+// ParseManifest decodes a manifest and checks the fields clients depend on
+func ParseManifest(body []byte) (*Manifest, error) {
+	var manifest Manifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, errors.New("manifest is not valid JSON")
+	}
+
+	if err := Validate(&manifest, body); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+func Validate(manifest *Manifest, raw []byte) error {
+	if strings.TrimSpace(manifest.ID) == "" {
+		return errors.New("manifest is missing an id")
+	}
+	if !versionPattern.MatchString(manifest.Version) {
+		return errors.New("manifest version must be semver")
+	}
+	if strings.TrimSpace(manifest.Name) == "" {
+		return errors.New("manifest is missing a name")
+	}
+
+	if len(manifest.Resources) == 0 {
+		return errors.New("manifest must declare at least one resource")
+	}
+	for _, resource := range manifest.Resources {
+		if resource.Name == "" {
+			return errors.New("manifest has a resource without a name")
+		}
+	}
+
+	if len(manifest.Types) == 0 {
+		return errors.New("manifest must declare at least one type")
+	}
+	for _, mediaType := range manifest.Types {
+		if mediaType == "" {
+			return errors.New("manifest has an empty type")
+		}
+	}
+
+	// catalogs may be empty but must be present
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return errors.New("manifest must be a JSON object")
+	}
+	if _, ok := fields["catalogs"]; !ok {
+		return errors.New("manifest is missing catalogs")
+	}
+	for _, catalog := range manifest.Catalogs {
+		if catalog.ID == "" || catalog.Type == "" {
+			return errors.New("manifest has a catalog without an id or type")
+		}
+	}
+
+	return nil
+}
+
+// Resolved is the outcome of checking one URL of a batch
+type Resolved struct {
+	URL      string
+	Manifest *Manifest
+	Raw      []byte
+}
+
+// ResolveAll fetches every URL concurrently and reports a per-item error for
+// each one that is invalid, unreachable, or a duplicate of an earlier entry.
+// The resolved list is only complete when no errors are returned.
+func (c *ManifestClient) ResolveAll(ctx context.Context, urls []string) ([]Resolved, []ItemError) {
+	resolved := make([]Resolved, len(urls))
+	failures := make([]string, len(urls))
+
+	seen := make(map[string]int)
+	var wg sync.WaitGroup
+	for i, raw := range urls {
+		manifestURL, err := NormalizeURL(raw)
+		if err != nil {
+			failures[i] = err.Error()
+			continue
+		}
+		if first, ok := seen[manifestURL]; ok {
+			failures[i] = fmt.Sprintf("duplicate of extension %d", first)
+			continue
+		}
+		seen[manifestURL] = i
+		resolved[i].URL = manifestURL
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			manifest, body, err := c.Fetch(ctx, resolved[i].URL)
+			if err != nil {
+				failures[i] = err.Error()
+				return
+			}
+			resolved[i].Manifest = manifest
+			resolved[i].Raw = body
+		}(i)
+	}
+	wg.Wait()
+
+	// the same addon can be served from more than one URL
+	seenIDs := make(map[string]int)
+	for i := range resolved {
+		if failures[i] != "" || resolved[i].Manifest == nil {
+			continue
+		}
+		if first, ok := seenIDs[resolved[i].Manifest.ID]; ok {
+			failures[i] = fmt.Sprintf("addon %s duplicates extension %d", resolved[i].Manifest.ID, first)
+			continue
+		}
+		seenIDs[resolved[i].Manifest.ID] = i
+	}
+
+	var errs []ItemError
+	for i, failure := range failures {
+		if failure != "" {
+			errs = append(errs, ItemError{Index: i, URL: urls[i], Error: failure})
+		}
+	}
+	return resolved, errs
+}
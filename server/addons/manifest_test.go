@@ -0,0 +1,211 @@
+package addons
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"zync-stream/netguard"
+)
+
+const validManifest = `{
+	"id": "org.example.addon",
+	"version": "1.2.3",
+	"name": "Example",
+	"resources": ["stream", {"name": "meta", "types": ["movie"], "idPrefixes": ["tt"]}],
+	"types": ["movie", "series"],
+	"catalogs": []
+}`
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{"manifest url", "https://addon.example/manifest.json", "https://addon.example/manifest.json", false},
+		{"base url", "https://addon.example", "https://addon.example/manifest.json", false},
+		{"trailing slash", "https://addon.example/sub/", "https://addon.example/sub/manifest.json", false},
+		{"stremio scheme", "stremio://addon.example/manifest.json", "https://addon.example/manifest.json", false},
+		{"surrounding space", "  http://addon.example/manifest.json ", "http://addon.example/manifest.json", false},
+		{"fragment dropped", "https://addon.example/manifest.json#top", "https://addon.example/manifest.json", false},
+		{"relative", "/manifest.json", "", true},
+		{"other scheme", "ftp://addon.example/manifest.json", "", true},
+		{"empty", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeURL(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeURL(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeURL(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"valid", validManifest, ""},
+		{"invalid json", `{"id":`, "not valid JSON"},
+		{"missing id", strings.Replace(validManifest, `"org.example.addon"`, `""`, 1), "missing an id"},
+		{"non semver version", strings.Replace(validManifest, `"1.2.3"`, `"1.2"`, 1), "semver"},
+		{"prerelease version", strings.Replace(validManifest, `"1.2.3"`, `"1.2.3-beta.1"`, 1), ""},
+		{"missing name", strings.Replace(validManifest, `"Example"`, `" "`, 1), "missing a name"},
+		{"no resources", strings.Replace(validManifest, `["stream", {"name": "meta", "types": ["movie"], "idPrefixes": ["tt"]}]`, `[]`, 1), "at least one resource"},
+		{"bad resource", strings.Replace(validManifest, `["stream",`, `[1,`, 1), "not valid JSON"},
+		{"no types", strings.Replace(validManifest, `["movie", "series"]`, `[]`, 1), "at least one type"},
+		{"missing catalogs", strings.Replace(validManifest, `,
+	"catalogs": []`, ``, 1), "missing catalogs"},
+		{"catalog without id", strings.Replace(validManifest, `"catalogs": []`, `"catalogs": [{"type": "movie"}]`, 1), "catalog without an id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := ParseManifest([]byte(tt.body))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ParseManifest() error = %v", err)
+				}
+				if !manifest.HasResource(ResourceMeta) || manifest.Resources[1].IDPrefixes[0] != "tt" {
+					t.Errorf("resources not decoded: %+v", manifest.Resources)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParseManifest() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveAll(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(validManifest))
+	})
+	mux.HandleFunc("/b/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Replace(validManifest, "org.example.addon", "org.example.other", 1)))
+	})
+	// the same addon served from a second URL
+	mux.HandleFunc("/mirror/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(validManifest))
+	})
+	mux.HandleFunc("/missing/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/huge/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"` + strings.Repeat("x", maxManifestBytes) + `"}`))
+	})
+	mux.HandleFunc("/redirect/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/manifest.json", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachableURL := unreachable.URL
+	unreachable.Close()
+
+	tests := []struct {
+		name    string
+		urls    []string
+		wantErr map[int]string // index -> error substring
+	}{
+		{
+			name: "all valid",
+			urls: []string{server.URL + "/a", server.URL + "/b/manifest.json"},
+		},
+		{
+			name:    "duplicate url",
+			urls:    []string{server.URL + "/a", server.URL + "/a/manifest.json"},
+			wantErr: map[int]string{1: "duplicate of extension 0"},
+		},
+		{
+			name:    "duplicate addon id",
+			urls:    []string{server.URL + "/a", server.URL + "/mirror"},
+			wantErr: map[int]string{1: "addon org.example.addon duplicates extension 0"},
+		},
+		{
+			name:    "unreachable",
+			urls:    []string{server.URL + "/a", unreachableURL},
+			wantErr: map[int]string{1: "unreachable"},
+		},
+		{
+			name:    "non-200",
+			urls:    []string{server.URL + "/missing"},
+			wantErr: map[int]string{0: "responded with 404"},
+		},
+		{
+			name:    "oversized",
+			urls:    []string{server.URL + "/huge"},
+			wantErr: map[int]string{0: "too large"},
+		},
+		{
+			name:    "redirect to an internal address",
+			urls:    []string{server.URL + "/redirect"},
+			wantErr: map[int]string{0: "public address"},
+		},
+		{
+			name:    "invalid url",
+			urls:    []string{"not a url", server.URL + "/b"},
+			wantErr: map[int]string{0: "absolute http or https URL"},
+		},
+	}
+
+	// the test server is on loopback, so only the redirect check applies
+	client := &ManifestClient{client: &http.Client{Timeout: 10 * time.Second, CheckRedirect: netguard.CheckRedirect}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, errs := client.ResolveAll(context.Background(), tt.urls)
+
+			if len(errs) != len(tt.wantErr) {
+				t.Fatalf("ResolveAll() errors = %+v, want %d", errs, len(tt.wantErr))
+			}
+			for _, itemErr := range errs {
+				want, ok := tt.wantErr[itemErr.Index]
+				if !ok || !strings.Contains(itemErr.Error, want) {
+					t.Errorf("item %d error = %q, want it to contain %q", itemErr.Index, itemErr.Error, want)
+				}
+				if itemErr.URL != tt.urls[itemErr.Index] {
+					t.Errorf("item %d url = %q, want the submitted %q", itemErr.Index, itemErr.URL, tt.urls[itemErr.Index])
+				}
+			}
+
+			for i := range tt.urls {
+				if _, failed := tt.wantErr[i]; failed {
+					continue
+				}
+				if resolved[i].Manifest == nil || len(resolved[i].Raw) == 0 {
+					t.Errorf("item %d was not resolved", i)
+				}
+				if !strings.HasSuffix(resolved[i].URL, "/manifest.json") {
+					t.Errorf("item %d url %q is not normalized", i, resolved[i].URL)
+				}
+			}
+		})
+	}
+}
+
+func TestManifestClientRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(validManifest))
+	}))
+	defer server.Close()
+
+	_, _, err := NewManifestClient().Fetch(context.Background(), server.URL+"/manifest.json")
+	if err == nil || !strings.Contains(err.Error(), "public address") {
+		t.Errorf("Fetch() of a loopback addon error = %v", err)
+	}
+}
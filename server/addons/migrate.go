@@ -0,0 +1,52 @@
+package addons
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// MigrateExtensions converts users.extensions arrays written before
+// user_addons existed. URLs that fail validation are logged and left in
+// users.extensions; users with nothing valid are retried on the next start.
+func MigrateExtensions(ctx context.Context, repo *AddonRepository, manifests *ManifestClient) {
+	pending, err := repo.UnmigratedUsers(ctx)
+	if err != nil {
+		log.Printf("Failed to list extensions to migrate: %v", err)
+		return
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	migrated := 0
+	for userID, extensions := range pending {
+		fetchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		resolved, itemErrors := manifests.ResolveAll(fetchCtx, extensions)
+		cancel()
+
+		failed := make(map[int]bool, len(itemErrors))
+		for _, itemError := range itemErrors {
+			failed[itemError.Index] = true
+			log.Printf("Skipping extension %s of user %d: %s", itemError.URL, userID, itemError.Error)
+		}
+
+		valid := make([]Resolved, 0, len(resolved))
+		for i, addon := range resolved {
+			if !failed[i] {
+				valid = append(valid, addon)
+			}
+		}
+		if len(valid) == 0 {
+			continue
+		}
+
+		if err := repo.ImportForUser(ctx, userID, valid); err != nil {
+			log.Printf("Failed to migrate extensions of user %d: %v", userID, err)
+			continue
+		}
+		migrated++
+	}
+
+	log.Printf("Migrated extensions of %d/%d users to user_addons", migrated, len(pending))
+}
@@ -0,0 +1,119 @@
+package addons
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	MaxAddonsPerUser = 50
+
+	// manifests larger than this are rejected outright
+	maxManifestBytes = 1 << 20
+
+	maxConfigLength = 2048
+)
+
+// Resource is one entry of a manifest's "resources" array, which Stremio
+// allows to be either a bare name or an object narrowing types and ID prefixes
+type Resource struct {
+	Name       string   `json:"name"`
+	Types      []string `json:"types,omitempty"`
+	IDPrefixes []string `json:"idPrefixes,omitempty"`
+}
+
+func (r *Resource) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		r.Name = name
+		return nil
+	}
+
+	type resource Resource
+	var object resource
+	if err := json.Unmarshal(data, &object); err != nil {
+		return errors.New("resource must be a string or an object")
+	}
+	*r = Resource(object)
+	return nil
+}
+
+type Catalog struct {
+	Type  string            `json:"type"`
+	ID    string            `json:"id"`
+	Name  string            `json:"name,omitempty"`
+	Extra []json.RawMessage `json:"extra,omitempty"`
+}
+
+type Manifest struct {
+	ID          string     `json:"id"`
+	Version     string     `json:"version"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Logo        string     `json:"logo,omitempty"`
+	Resources   []Resource `json:"resources"`
+	Types       []string   `json:"types"`
+	Catalogs    []Catalog  `json:"catalogs"`
+	IDPrefixes  []string   `json:"idPrefixes,omitempty"`
+}
+
+// HasResource reports whether the addon serves the named resource
+func (m *Manifest) HasResource(name string) bool {
+	for _, resource := range m.Resources {
+		if resource.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Addon is a validated addon installed by a user. Manifest keeps the
+// document exactly as the addon served it. Config is the settings segment
+// of a configurable addon, inserted before manifest.json.
+type Addon struct {
+	ID           int             `json:"id"`
+	UserID       int             `json:"user_id"`
+	TransportURL string          `json:"transport_url"`
+	AddonID      string          `json:"addon_id"`
+	Version      string          `json:"version"`
+	Name         string          `json:"name"`
+	Manifest     json.RawMessage `json:"manifest"`
+	Position     int             `json:"position"`
+	Enabled      bool            `json:"enabled"`
+	Config       string          `json:"config"`
+	CreatedAt    time.Time       `json:"installed_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// ManifestURL is the transport URL with the addon's settings applied
+func (a *Addon) ManifestURL() string {
+	return ConfiguredURL(a.TransportURL, a.Config)
+}
+
+// PublicAddon is what room members see of another user's addon: the
+// transport URL and settings can carry that user's API tokens
+type PublicAddon struct {
+	AddonID  string          `json:"addon_id"`
+	Version  string          `json:"version"`
+	Name     string          `json:"name"`
+	Manifest json.RawMessage `json:"manifest"`
+	Position int             `json:"position"`
+}
+
+func (a *Addon) Public() PublicAddon {
+	return PublicAddon{
+		AddonID:  a.AddonID,
+		Version:  a.Version,
+		Name:     a.Name,
+		Manifest: a.Manifest,
+		Position: a.Position,
+	}
+}
+
+// ItemError explains why one URL of a batch was rejected
+type ItemError struct {
+	Index int    `json:"index"`
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
@@ -0,0 +1,198 @@
+package addons
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	HealthHealthy = "healthy"
+	HealthFailing = "failing"
+	HealthDead    = "dead"
+
+	// failed checks in a row before an entry is marked dead
+	deadAfterFailures = 3
+)
+
+var (
+	ErrAlreadyListed  = errors.New("addon is already in the registry")
+	errAddonIDChanged = errors.New("manifest now reports a different addon id")
+)
+
+// RegistryEntry is a vetted addon users can install in one click
+type RegistryEntry struct {
+	ID                  int             `json:"id"`
+	ManifestURL         string          `json:"manifest_url"`
+	AddonID             string          `json:"addon_id"`
+	Version             string          `json:"version"`
+	Name                string          `json:"name"`
+	Description         string          `json:"description"`
+	Tags                []string        `json:"tags"`
+	Manifest            json.RawMessage `json:"manifest"`
+	HealthStatus        string          `json:"health_status"`
+	ConsecutiveFailures int             `json:"consecutive_failures"`
+	LastError           *string         `json:"last_error,omitempty"`
+	LastCheckedAt       *time.Time      `json:"last_checked_at,omitempty"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+}
+
+// RegistryFilter narrows a registry listing. Zero values match everything.
+type RegistryFilter struct {
+	Query       string
+	Tag         string
+	IncludeDead bool
+}
+
+// RegistryRepository stores the admin-curated addon registry
+type RegistryRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewRegistryRepository creates a new RegistryRepository
+func NewRegistryRepository(db *pgxpool.Pool) *RegistryRepository {
+	return &RegistryRepository{db: db}
+}
+
+const registryColumns = `id, manifest_url, addon_id, version, name, description, tags, manifest,
+               health_status, consecutive_failures, last_error, last_checked_at, created_at, updated_at`
+
+func scanRegistryEntry(row pgx.Row) (*RegistryEntry, error) {
+	var entry RegistryEntry
+	var manifest []byte
+	err := row.Scan(&entry.ID, &entry.ManifestURL, &entry.AddonID, &entry.Version, &entry.Name,
+		&entry.Description, &entry.Tags, &manifest, &entry.HealthStatus, &entry.ConsecutiveFailures,
+		&entry.LastError, &entry.LastCheckedAt, &entry.CreatedAt, &entry.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan registry entry: %w", err)
+	}
+	entry.Manifest = manifest
+	return &entry, nil
+}
+
+func (r *RegistryRepository) List(ctx context.Context, filter RegistryFilter) ([]*RegistryEntry, error) {
+	query := strings.TrimSpace(filter.Query)
+
+	rows, err := r.db.Query(ctx, `
+        SELECT `+registryColumns+`
+        FROM addon_registry
+        WHERE ($1 = '' OR name ILIKE '%' || $1 || '%' OR description ILIKE '%' || $1 || '%'
+               OR addon_id ILIKE '%' || $1 || '%')
+          AND ($2 = '' OR $2 = ANY(tags))
+          AND ($3 OR health_status <> 'dead')
+        ORDER BY health_status = 'dead', lower(name)
+    `, query, strings.ToLower(filter.Tag), filter.IncludeDead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query addon registry: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*RegistryEntry{}
+	for rows.Next() {
+		entry, err := scanRegistryEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func (r *RegistryRepository) GetByID(ctx context.Context, id int) (*RegistryEntry, error) {
+	entry, err := scanRegistryEntry(r.db.QueryRow(ctx, `
+        SELECT `+registryColumns+` FROM addon_registry WHERE id = $1
+    `, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return entry, err
+}
+
+func (r *RegistryRepository) Create(ctx context.Context, addon Resolved, description string, tags []string, createdBy int) (*RegistryEntry, error) {
+	entry, err := scanRegistryEntry(r.db.QueryRow(ctx, `
+        INSERT INTO addon_registry (manifest_url, addon_id, version, name, description, tags, manifest,
+                                    last_checked_at, created_by)
+        VALUES ($1, $2, $3, $4, $5, $6, $7::jsonb, NOW(), $8)
+        RETURNING `+registryColumns,
+		addon.URL, addon.Manifest.ID, addon.Manifest.Version, addon.Manifest.Name, description, tags,
+		string(addon.Raw), createdBy))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrAlreadyListed
+		}
+		return nil, fmt.Errorf("failed to create registry entry: %w", err)
+	}
+	return entry, nil
+}
+
+// UpdateListing changes the curated fields; nil leaves a field unchanged
+func (r *RegistryRepository) UpdateListing(ctx context.Context, id int, description *string, tags []string) (*RegistryEntry, error) {
+	entry, err := scanRegistryEntry(r.db.QueryRow(ctx, `
+        UPDATE addon_registry
+        SET description = COALESCE($2, description),
+            tags = COALESCE($3, tags),
+            updated_at = NOW()
+        WHERE id = $1
+        RETURNING `+registryColumns,
+		id, description, tags))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update registry entry: %w", err)
+	}
+	return entry, nil
+}
+
+func (r *RegistryRepository) Delete(ctx context.Context, id int) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM addon_registry WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete registry entry: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MarkHealthy stores a fresh copy of the manifest after a successful check
+func (r *RegistryRepository) MarkHealthy(ctx context.Context, id int, manifest *Manifest, raw []byte) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE addon_registry
+        SET health_status = 'healthy', consecutive_failures = 0, last_error = NULL,
+            last_checked_at = NOW(), version = $2, name = $3, manifest = $4::jsonb
+        WHERE id = $1
+    `, id, manifest.Version, manifest.Name, string(raw))
+	if err != nil {
+		return fmt.Errorf("failed to mark registry entry healthy: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records a failed check, marking the entry dead after several in a row
+func (r *RegistryRepository) MarkFailed(ctx context.Context, id int, checkErr string) (string, error) {
+	var status string
+	err := r.db.QueryRow(ctx, `
+        UPDATE addon_registry
+        SET consecutive_failures = consecutive_failures + 1,
+            health_status = CASE WHEN consecutive_failures + 1 >= $3 THEN 'dead' ELSE 'failing' END,
+            last_error = $2,
+            last_checked_at = NOW()
+        WHERE id = $1
+        RETURNING health_status
+    `, id, checkErr, deadAfterFailures).Scan(&status)
+	if err != nil {
+		return "", fmt.Errorf("failed to mark registry entry failing: %w", err)
+	}
+	return status, nil
+}
@@ -0,0 +1,259 @@
+package addons
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	MaxRegistryTags   = 10
+	MaxRegistryTagLen = 30
+)
+
+type RegistryHandlers struct {
+	repo      *RegistryRepository
+	addons    *AddonRepository
+	manifests *ManifestClient
+}
+
+func NewRegistryHandlers(repo *RegistryRepository, addonRepo *AddonRepository, manifests *ManifestClient) *RegistryHandlers {
+	return &RegistryHandlers{
+		repo:      repo,
+		addons:    addonRepo,
+		manifests: manifests,
+	}
+}
+
+// normalizeTags lowercases, trims and de-duplicates tags
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > MaxRegistryTagLen {
+			return nil, fmt.Errorf("tags may be at most %d characters", MaxRegistryTagLen)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > MaxRegistryTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", MaxRegistryTags)
+	}
+	return normalized, nil
+}
+
+func entryID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("entryId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid registry entry ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// GetRegistry lists vetted addons, searchable by name and filterable by tag.
+// Dead addons are hidden unless include_dead=true.
+func (h *RegistryHandlers) GetRegistry(c *gin.Context) {
+	filter := RegistryFilter{
+		Query:       c.Query("q"),
+		Tag:         c.Query("tag"),
+		IncludeDead: c.Query("include_dead") == "true",
+	}
+
+	entries, err := h.repo.List(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("Error retrieving addon registry: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve addon registry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"addons": entries})
+}
+
+type createEntryRequest struct {
+	ManifestURL string   `json:"manifest_url" binding:"required"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+func (h *RegistryHandlers) CreateEntry(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req createEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	manifestURL, err := NormalizeURL(req.ManifestURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Manifest URL " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	manifest, raw, err := h.manifests.Fetch(ctx, manifestURL)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Addon is invalid: " + err.Error()})
+		return
+	}
+
+	description := strings.TrimSpace(req.Description)
+	if description == "" {
+		description = manifest.Description
+	}
+
+	entry, err := h.repo.Create(ctx, Resolved{URL: manifestURL, Manifest: manifest, Raw: raw}, description, tags, userID.(int))
+	if err != nil {
+		if errors.Is(err, ErrAlreadyListed) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Addon is already in the registry"})
+			return
+		}
+		log.Printf("Error creating registry entry: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add addon to registry"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"addon": entry})
+}
+
+type updateEntryRequest struct {
+	Description *string  `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+func (h *RegistryHandlers) UpdateEntry(c *gin.Context) {
+	id, ok := entryID(c)
+	if !ok {
+		return
+	}
+
+	var req updateEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	var tags []string
+	if req.Tags != nil {
+		var err error
+		if tags, err = normalizeTags(req.Tags); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Description != nil {
+		description := strings.TrimSpace(*req.Description)
+		req.Description = &description
+	}
+
+	entry, err := h.repo.UpdateListing(c.Request.Context(), id, req.Description, tags)
+	if err != nil {
+		log.Printf("Error updating registry entry: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update registry entry"})
+		return
+	}
+	if entry == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registry entry not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"addon": entry})
+}
+
+func (h *RegistryHandlers) DeleteEntry(c *gin.Context) {
+	id, ok := entryID(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.repo.Delete(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Error deleting registry entry: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete registry entry"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registry entry not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Registry entry deleted"})
+}
+
+// InstallEntry adds a registry addon to the end of the caller's addon list
+func (h *RegistryHandlers) InstallEntry(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, ok := entryID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	entry, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		log.Printf("Error retrieving registry entry: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve registry entry"})
+		return
+	}
+	if entry == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registry entry not found"})
+		return
+	}
+	if entry.HealthStatus == HealthDead {
+		c.JSON(http.StatusConflict, gin.H{"error": "Addon is currently unavailable"})
+		return
+	}
+
+	manifest, err := ParseManifest(entry.Manifest)
+	if err != nil {
+		log.Printf("Registry entry %d has an invalid stored manifest: %v", entry.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to install addon"})
+		return
+	}
+
+	extensions, err := h.addons.InstallForUser(ctx, userID.(int), Resolved{URL: entry.ManifestURL, Manifest: manifest, Raw: entry.Manifest})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrAlreadyInstalled):
+			c.JSON(http.StatusConflict, gin.H{"error": "Addon is already installed"})
+		case errors.Is(err, ErrTooManyAddons):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Error installing addon: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to install addon"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Addon installed",
+		"extensions": extensions,
+	})
+}
@@ -0,0 +1,489 @@
+package addons
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AddonRepository stores the validated addons each user has installed
+type AddonRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAddonRepository creates a new AddonRepository
+func NewAddonRepository(db *pgxpool.Pool) *AddonRepository {
+	return &AddonRepository{db: db}
+}
+
+var (
+	ErrInvalidOrder     = errors.New("addon ids must list every installed addon exactly once")
+	ErrNotInstalled     = errors.New("addon is not installed")
+	ErrAlreadyInstalled = errors.New("addon is already installed")
+	ErrTooManyAddons    = fmt.Errorf("at most %d addons can be installed", MaxAddonsPerUser)
+)
+
+const userAddonColumns = `id, user_id, transport_url, addon_id, version, name, manifest,
+               position, enabled, config, created_at, updated_at`
+
+// ReplaceForUser makes the given list the user's enabled addons, in order.
+// URLs are matched back to installed addons with or without their settings
+// applied, as users.extensions lists them, so addons that stay installed keep
+// their enabled flag, settings and install date. Disabled addons are never in
+// that list and are kept, after the others.
+func (r *AddonRepository) ReplaceForUser(ctx context.Context, userID int, addons []Resolved) ([]string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	installed, err := installedRows(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	addons = append([]Resolved(nil), addons...)
+	urls := make([]string, 0, len(addons))
+	for i := range addons {
+		for _, row := range installed {
+			if addons[i].URL == row.transportURL || addons[i].URL == ConfiguredURL(row.transportURL, row.config) {
+				addons[i].URL = row.transportURL
+				break
+			}
+		}
+		urls = append(urls, addons[i].URL)
+	}
+
+	if _, err := tx.Exec(ctx, `
+        DELETE FROM user_addons
+        WHERE user_id = $1 AND enabled AND NOT (transport_url = ANY($2))
+    `, userID, urls); err != nil {
+		return nil, fmt.Errorf("failed to remove user addons: %w", err)
+	}
+
+	for position, addon := range addons {
+		if err := upsertUserAddon(ctx, tx, userID, addon, position); err != nil {
+			return nil, err
+		}
+	}
+
+	position := len(addons)
+	for _, row := range installed {
+		if row.enabled || contains(urls, row.transportURL) {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `
+            UPDATE user_addons SET position = $3 WHERE user_id = $1 AND transport_url = $2
+        `, userID, row.transportURL, position); err != nil {
+			return nil, fmt.Errorf("failed to reorder user addons: %w", err)
+		}
+		position++
+	}
+
+	extensions, err := syncExtensions(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit addons: %w", err)
+	}
+
+	return extensions, nil
+}
+
+type installedRow struct {
+	transportURL string
+	config       string
+	enabled      bool
+}
+
+func installedRows(ctx context.Context, tx pgx.Tx, userID int) ([]installedRow, error) {
+	rows, err := tx.Query(ctx, `
+        SELECT transport_url, config, enabled FROM user_addons
+        WHERE user_id = $1
+        ORDER BY position, id
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user addons: %w", err)
+	}
+	defer rows.Close()
+
+	installed := []installedRow{}
+	for rows.Next() {
+		var row installedRow
+		if err := rows.Scan(&row.transportURL, &row.config, &row.enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan user addon: %w", err)
+		}
+		installed = append(installed, row)
+	}
+	return installed, rows.Err()
+}
+
+func upsertUserAddon(ctx context.Context, tx pgx.Tx, userID int, addon Resolved, position int) error {
+	_, err := tx.Exec(ctx, `
+        INSERT INTO user_addons (user_id, transport_url, addon_id, version, name, manifest, position)
+        VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7)
+        ON CONFLICT (user_id, transport_url) DO UPDATE SET
+            addon_id = EXCLUDED.addon_id,
+            version = EXCLUDED.version,
+            name = EXCLUDED.name,
+            manifest = EXCLUDED.manifest,
+            position = EXCLUDED.position,
+            updated_at = NOW()
+    `, userID, addon.URL, addon.Manifest.ID, addon.Manifest.Version, addon.Manifest.Name,
+		string(addon.Raw), position)
+	if err != nil {
+		return fmt.Errorf("failed to save user addon: %w", err)
+	}
+	return nil
+}
+
+// syncExtensions mirrors the user's enabled addons, with their settings
+// applied, into users.extensions for clients that still read it
+func syncExtensions(ctx context.Context, tx pgx.Tx, userID int) ([]string, error) {
+	rows, err := tx.Query(ctx, `
+        SELECT transport_url, config FROM user_addons
+        WHERE user_id = $1 AND enabled
+        ORDER BY position, id
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user addons: %w", err)
+	}
+
+	extensions := []string{}
+	for rows.Next() {
+		var transportURL, config string
+		if err := rows.Scan(&transportURL, &config); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan user addon: %w", err)
+		}
+		extensions = append(extensions, ConfiguredURL(transportURL, config))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read user addons: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+        UPDATE users SET extensions = $1, updated_at = NOW() WHERE id = $2
+    `, extensions, userID); err != nil {
+		return nil, fmt.Errorf("failed to update extensions: %w", err)
+	}
+
+	return extensions, nil
+}
+
+func (r *AddonRepository) ListForUser(ctx context.Context, userID int) ([]*Addon, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT `+userAddonColumns+`
+        FROM user_addons
+        WHERE user_id = $1
+        ORDER BY position, id
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user addons: %w", err)
+	}
+	defer rows.Close()
+
+	addons := []*Addon{}
+	for rows.Next() {
+		addon, err := scanUserAddon(rows)
+		if err != nil {
+			return nil, err
+		}
+		addons = append(addons, addon)
+	}
+
+	return addons, rows.Err()
+}
+
+func (r *AddonRepository) GetForUser(ctx context.Context, userID, addonID int) (*Addon, error) {
+	row := r.db.QueryRow(ctx, `
+        SELECT `+userAddonColumns+`
+        FROM user_addons
+        WHERE user_id = $1 AND id = $2
+    `, userID, addonID)
+
+	addon, err := scanUserAddon(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return addon, err
+}
+
+func scanUserAddon(row pgx.Row) (*Addon, error) {
+	var addon Addon
+	var manifest []byte
+	err := row.Scan(&addon.ID, &addon.UserID, &addon.TransportURL, &addon.AddonID, &addon.Version,
+		&addon.Name, &manifest, &addon.Position, &addon.Enabled, &addon.Config,
+		&addon.CreatedAt, &addon.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan user addon: %w", err)
+	}
+	addon.Manifest = manifest
+	return &addon, nil
+}
+
+// UpdateSettings changes an addon's enabled flag and, when manifest is not
+// nil, its configuration along with the manifest served for it
+func (r *AddonRepository) UpdateSettings(ctx context.Context, userID, addonID int, enabled bool, config string, manifest *Resolved) ([]string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var tag pgconn.CommandTag
+	if manifest != nil {
+		tag, err = tx.Exec(ctx, `
+            UPDATE user_addons
+            SET enabled = $3, config = $4, version = $5, name = $6, manifest = $7::jsonb, updated_at = NOW()
+            WHERE user_id = $1 AND id = $2
+        `, userID, addonID, enabled, config, manifest.Manifest.Version, manifest.Manifest.Name, string(manifest.Raw))
+	} else {
+		tag, err = tx.Exec(ctx, `
+            UPDATE user_addons SET enabled = $3, updated_at = NOW()
+            WHERE user_id = $1 AND id = $2
+        `, userID, addonID, enabled)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user addon: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrNotInstalled
+	}
+
+	extensions, err := syncExtensions(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit addon settings: %w", err)
+	}
+
+	return extensions, nil
+}
+
+// Reorder sets the user's addon order; ids must name every installed addon once
+func (r *AddonRepository) Reorder(ctx context.Context, userID int, addonIDs []int) ([]string, error) {
+	seen := make(map[int]bool, len(addonIDs))
+	for _, id := range addonIDs {
+		if seen[id] {
+			return nil, ErrInvalidOrder
+		}
+		seen[id] = true
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT id FROM user_addons WHERE user_id = $1 FOR UPDATE`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock user addons: %w", err)
+	}
+	existing := 0
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan user addon: %w", err)
+		}
+		if !seen[id] {
+			rows.Close()
+			return nil, ErrInvalidOrder
+		}
+		existing++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read user addons: %w", err)
+	}
+	if existing != len(addonIDs) {
+		return nil, ErrInvalidOrder
+	}
+
+	for position, id := range addonIDs {
+		if _, err := tx.Exec(ctx, `UPDATE user_addons SET position = $3 WHERE user_id = $1 AND id = $2`,
+			userID, id, position); err != nil {
+			return nil, fmt.Errorf("failed to reorder user addons: %w", err)
+		}
+	}
+
+	extensions, err := syncExtensions(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit addon order: %w", err)
+	}
+
+	return extensions, nil
+}
+
+// InstallForUser appends one addon to the end of the user's list
+func (r *AddonRepository) InstallForUser(ctx context.Context, userID int, addon Resolved) ([]string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// lock the user row so concurrent installs cannot exceed the limit
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return nil, fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	var count, next int
+	if err := tx.QueryRow(ctx, `
+        SELECT COUNT(*), COALESCE(MAX(position) + 1, 0) FROM user_addons WHERE user_id = $1
+    `, userID).Scan(&count, &next); err != nil {
+		return nil, fmt.Errorf("failed to count user addons: %w", err)
+	}
+	if count >= MaxAddonsPerUser {
+		return nil, ErrTooManyAddons
+	}
+
+	_, err = tx.Exec(ctx, `
+        INSERT INTO user_addons (user_id, transport_url, addon_id, version, name, manifest, position)
+        VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7)
+    `, userID, addon.URL, addon.Manifest.ID, addon.Manifest.Version, addon.Manifest.Name,
+		string(addon.Raw), next)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrAlreadyInstalled
+		}
+		return nil, fmt.Errorf("failed to install addon: %w", err)
+	}
+
+	extensions, err := syncExtensions(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit addon install: %w", err)
+	}
+
+	return extensions, nil
+}
+
+// UnmigratedUsers lists users whose extensions array predates user_addons
+func (r *AddonRepository) UnmigratedUsers(ctx context.Context) (map[int][]string, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT u.id, u.extensions
+        FROM users u
+        WHERE cardinality(u.extensions) > 0
+          AND NOT EXISTS (SELECT 1 FROM user_addons a WHERE a.user_id = u.id)
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unmigrated extensions: %w", err)
+	}
+	defer rows.Close()
+
+	users := make(map[int][]string)
+	for rows.Next() {
+		var userID int
+		var extensions []string
+		if err := rows.Scan(&userID, &extensions); err != nil {
+			return nil, fmt.Errorf("failed to scan unmigrated extensions: %w", err)
+		}
+		users[userID] = extensions
+	}
+
+	return users, rows.Err()
+}
+
+// ImportForUser inserts migrated addons without touching users.extensions,
+// so URLs that failed validation are not lost from it
+func (r *AddonRepository) ImportForUser(ctx context.Context, userID int, addons []Resolved) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for position, addon := range addons {
+		if err := upsertUserAddon(ctx, tx, userID, addon, position); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit migrated addons: %w", err)
+	}
+	return nil
+}
+
+// ReplaceForRoom pins the room's addon set, in order. An empty list unpins it.
+func (r *AddonRepository) ReplaceForRoom(ctx context.Context, roomID int, addons []Resolved) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM room_addons WHERE room_id = $1`, roomID); err != nil {
+		return fmt.Errorf("failed to clear room addons: %w", err)
+	}
+
+	for position, addon := range addons {
+		_, err := tx.Exec(ctx, `
+            INSERT INTO room_addons (room_id, transport_url, addon_id, version, name, manifest, position)
+            VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7)
+        `, roomID, addon.URL, addon.Manifest.ID, addon.Manifest.Version, addon.Manifest.Name,
+			string(addon.Raw), position)
+		if err != nil {
+			return fmt.Errorf("failed to insert room addon: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit room addons: %w", err)
+	}
+
+	return nil
+}
+
+// ListForRoom returns the room's pinned addons. UserID is left zero.
+func (r *AddonRepository) ListForRoom(ctx context.Context, roomID int) ([]*Addon, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT id, transport_url, addon_id, version, name, manifest, position, created_at, updated_at
+        FROM room_addons
+        WHERE room_id = $1
+        ORDER BY position, id
+    `, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query room addons: %w", err)
+	}
+	defer rows.Close()
+
+	addons := []*Addon{}
+	for rows.Next() {
+		var addon Addon
+		var manifest []byte
+		if err := rows.Scan(&addon.ID, &addon.TransportURL, &addon.AddonID, &addon.Version,
+			&addon.Name, &manifest, &addon.Position, &addon.CreatedAt, &addon.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan room addon: %w", err)
+		}
+		addon.Manifest = manifest
+		addon.Enabled = true
+		addons = append(addons, &addon)
+	}
+
+	return addons, rows.Err()
+}
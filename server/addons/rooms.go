@@ -0,0 +1,341 @@
+package addons
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"zync-stream/rooms"
+)
+
+const (
+	StreamSourceRoom  = "room"
+	StreamSourceOwner = "owner"
+)
+
+// RoomPublisher posts an event into a room's realtime channel
+type RoomPublisher func(roomID int, eventType string, userID int, username string, data map[string]interface{}) error
+
+// RoomAddonHandlers let a room resolve streams through one shared addon set:
+// the owner's pinned addons, or the owner's own addons when nothing is pinned
+type RoomAddonHandlers struct {
+	repo        *AddonRepository
+	manifests   *ManifestClient
+	aggregator  *Aggregator
+	roomRepo    *rooms.RoomRepository
+	playback    *rooms.PlaybackStore
+	publishRoom RoomPublisher
+}
+
+func NewRoomAddonHandlers(repo *AddonRepository, manifests *ManifestClient, aggregator *Aggregator, roomRepo *rooms.RoomRepository, playback *rooms.PlaybackStore, publishRoom RoomPublisher) *RoomAddonHandlers {
+	return &RoomAddonHandlers{
+		repo:        repo,
+		manifests:   manifests,
+		aggregator:  aggregator,
+		roomRepo:    roomRepo,
+		playback:    playback,
+		publishRoom: publishRoom,
+	}
+}
+
+// memberRoom loads the room from :id and checks the caller belongs to it
+func (h *RoomAddonHandlers) memberRoom(c *gin.Context) (*rooms.Room, int, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, 0, false
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return nil, 0, false
+	}
+
+	ctx := c.Request.Context()
+
+	room, err := h.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		log.Printf("Error retrieving room: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve room"})
+		return nil, 0, false
+	}
+	if room == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return nil, 0, false
+	}
+
+	isMember, _, err := h.roomRepo.IsRoomMember(ctx, roomID, userID.(int))
+	if err != nil {
+		log.Printf("Error checking room membership: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check room membership"})
+		return nil, 0, false
+	}
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this room"})
+		return nil, 0, false
+	}
+
+	return room, userID.(int), true
+}
+
+// roomAddons returns the addons the room resolves through and where they came from
+func (h *RoomAddonHandlers) roomAddons(ctx context.Context, room *rooms.Room) ([]*Addon, string, error) {
+	pinned, err := h.repo.ListForRoom(ctx, room.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(pinned) > 0 {
+		return pinned, StreamSourceRoom, nil
+	}
+
+	owned, err := h.repo.ListForUser(ctx, room.OwnerID)
+	if err != nil {
+		return nil, "", err
+	}
+	return owned, StreamSourceOwner, nil
+}
+
+func (h *RoomAddonHandlers) GetRoomAddons(c *gin.Context) {
+	room, _, ok := h.memberRoom(c)
+	if !ok {
+		return
+	}
+
+	addons, source, err := h.roomAddons(c.Request.Context(), room)
+	if err != nil {
+		log.Printf("Error retrieving room addons: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve room addons"})
+		return
+	}
+
+	respondWithRoomAddons(c, addons, source)
+}
+
+// SetRoomAddons pins the room's addon set. Only the owner may change it, and
+// an empty list falls back to the owner's own addons.
+func (h *RoomAddonHandlers) SetRoomAddons(c *gin.Context) {
+	room, userID, ok := h.memberRoom(c)
+	if !ok {
+		return
+	}
+
+	if room.OwnerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the room owner can change room addons"})
+		return
+	}
+
+	var req struct {
+		Extensions []string `json:"extensions" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	if len(req.Extensions) > MaxAddonsPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d addons are allowed", MaxAddonsPerUser)})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	resolved, itemErrors := h.manifests.ResolveAll(ctx, req.Extensions)
+	if len(itemErrors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Some addons could not be added",
+			"errors": itemErrors,
+		})
+		return
+	}
+
+	if err := h.repo.ReplaceForRoom(ctx, room.ID, resolved); err != nil {
+		log.Printf("Error updating room addons: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update room addons"})
+		return
+	}
+
+	addons, source, err := h.roomAddons(ctx, room)
+	if err != nil {
+		log.Printf("Error retrieving room addons: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve room addons"})
+		return
+	}
+
+	respondWithRoomAddons(c, addons, source)
+}
+
+// respondWithRoomAddons lists pinned addons in full. The owner's own addons
+// are used on their behalf but only their public details are shown, since
+// transport URLs and settings can hold the owner's API tokens.
+func respondWithRoomAddons(c *gin.Context, addons []*Addon, source string) {
+	if source == StreamSourceRoom {
+		c.JSON(http.StatusOK, gin.H{
+			"addons": addons,
+			"source": source,
+		})
+		return
+	}
+
+	public := make([]PublicAddon, 0, len(addons))
+	for _, addon := range addons {
+		if addon.Enabled {
+			public = append(public, addon.Public())
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"addons": public,
+		"source": source,
+	})
+}
+
+// GetRoomStreams resolves streams through the room's addons so every member
+// picks from the same list
+func (h *RoomAddonHandlers) GetRoomStreams(c *gin.Context) {
+	room, _, ok := h.memberRoom(c)
+	if !ok {
+		return
+	}
+
+	mediaType := c.Param("type")
+	videoID := strings.TrimSuffix(c.Param("videoId"), ".json")
+	if mediaType == "" || videoID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Type and id are required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	addons, source, err := h.roomAddons(ctx, room)
+	if err != nil {
+		log.Printf("Error retrieving room addons: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve room addons"})
+		return
+	}
+
+	streams, results := h.aggregator.StreamsFrom(ctx, addons, mediaType, videoID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"streams": streams,
+		"addons":  results,
+		"source":  source,
+	})
+}
+
+type selectStreamRequest struct {
+	Type   string                 `json:"type" binding:"required"`
+	ID     string                 `json:"id" binding:"required"`
+	Stream map[string]interface{} `json:"stream" binding:"required"`
+}
+
+// SelectRoomStream records the chosen stream in the room's playback state and
+// tells members to load it. The stream must be one the room's addons offer.
+func (h *RoomAddonHandlers) SelectRoomStream(c *gin.Context) {
+	room, userID, ok := h.memberRoom(c)
+	if !ok {
+		return
+	}
+
+	var req selectStreamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	key := streamKey(req.Stream)
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Stream has no infoHash or url"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	addons, _, err := h.roomAddons(ctx, room)
+	if err != nil {
+		log.Printf("Error retrieving room addons: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve room addons"})
+		return
+	}
+
+	var selected map[string]interface{}
+	streams, _ := h.aggregator.StreamsFrom(ctx, addons, req.Type, req.ID)
+	for _, item := range streams {
+		if stream := item.(map[string]interface{}); streamKey(stream) == key {
+			selected = stream
+			break
+		}
+	}
+	if selected == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Stream is not offered by the room's addons"})
+		return
+	}
+
+	encoded, err := json.Marshal(selected)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode stream"})
+		return
+	}
+
+	mediaURL, _ := selected["url"].(string)
+	if err := h.playback.SetStream(ctx, room.ID, userID, req.Type, req.ID, mediaURL, encoded); err != nil {
+		log.Printf("Error saving room stream: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save room stream"})
+		return
+	}
+
+	data := map[string]interface{}{
+		"media_type": req.Type,
+		"media_id":   req.ID,
+		"stream":     selected,
+	}
+	if err := h.publishRoom(room.ID, "stream_selected", userID, c.GetString("username"), data); err != nil {
+		log.Printf("Failed to publish stream selection for room %d: %v", room.ID, err)
+	}
+
+	state, err := h.playback.Get(ctx, room.ID)
+	if err != nil {
+		log.Printf("Error retrieving playback state: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve playback state"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"playback": state})
+}
+
+// GetRoomPlayback returns the room's playback state along with the caller's
+// own subtitle choice when they have overridden the room's
+func (h *RoomAddonHandlers) GetRoomPlayback(c *gin.Context) {
+	room, userID, ok := h.memberRoom(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	state, err := h.playback.Get(ctx, room.ID)
+	if err != nil {
+		log.Printf("Error retrieving playback state: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve playback state"})
+		return
+	}
+
+	override, err := h.playback.GetSubtitleOverride(ctx, room.ID, userID)
+	if err != nil {
+		log.Printf("Error retrieving subtitle override: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve playback state"})
+		return
+	}
+
+	response := gin.H{"playback": state}
+	if override != nil {
+		response["subtitle_override"] = json.RawMessage(override)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,144 @@
+package addons
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	OutcomeLoaded = "loaded"
+	OutcomeFailed = "failed"
+
+	// reports older than this no longer count towards a stream's score
+	reportWindow = 30 * 24 * time.Hour
+
+	// score given to streams nobody has reported on yet
+	NeutralScore = 50
+)
+
+// StreamHealth summarises client reports for one stream
+type StreamHealth struct {
+	Score        int     `json:"score"`
+	Reports      int     `json:"reports"`
+	SuccessRate  float64 `json:"success_rate"`
+	AvgStartupMS int     `json:"avg_startup_ms,omitempty"`
+	AvgBuffering float64 `json:"avg_buffering_events,omitempty"`
+	loaded       int
+}
+
+// StreamReport is what a client sends after trying to play a stream
+type StreamReport struct {
+	Outcome         string
+	StartupMS       *int
+	BufferingEvents int
+}
+
+// ReportStream records the user's latest outcome for a stream. Each user
+// counts once per stream, so repeated reports cannot skew the score.
+func (r *AddonRepository) ReportStream(ctx context.Context, userID int, key string, report StreamReport) error {
+	_, err := r.db.Exec(ctx, `
+        INSERT INTO stream_reports (stream_key, user_id, outcome, startup_ms, buffering_events)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (stream_key, user_id) DO UPDATE SET
+            outcome = EXCLUDED.outcome,
+            startup_ms = EXCLUDED.startup_ms,
+            buffering_events = EXCLUDED.buffering_events,
+            reported_at = NOW()
+    `, key, userID, report.Outcome, report.StartupMS, report.BufferingEvents)
+	if err != nil {
+		return fmt.Errorf("failed to record stream report: %w", err)
+	}
+	return nil
+}
+
+// StreamHealth scores every key that has recent reports
+func (r *AddonRepository) StreamHealth(ctx context.Context, keys []string) (map[string]*StreamHealth, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT stream_key,
+               COUNT(*),
+               COUNT(*) FILTER (WHERE outcome = 'loaded'),
+               COALESCE(AVG(startup_ms) FILTER (WHERE outcome = 'loaded'), 0)::int,
+               COALESCE(AVG(buffering_events) FILTER (WHERE outcome = 'loaded'), 0)::float8
+        FROM stream_reports
+        WHERE stream_key = ANY($1) AND reported_at > $2
+        GROUP BY stream_key
+    `, keys, time.Now().Add(-reportWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stream health: %w", err)
+	}
+	defer rows.Close()
+
+	health := make(map[string]*StreamHealth)
+	for rows.Next() {
+		var key string
+		var h StreamHealth
+		if err := rows.Scan(&key, &h.Reports, &h.loaded, &h.AvgStartupMS, &h.AvgBuffering); err != nil {
+			return nil, fmt.Errorf("failed to scan stream health: %w", err)
+		}
+		h.score()
+		health[key] = &h
+	}
+
+	return health, rows.Err()
+}
+
+// score combines a smoothed success rate with penalties for slow starts and
+// buffering, so a couple of lucky reports do not outrank a proven stream
+func (h *StreamHealth) score() {
+	h.SuccessRate = float64(h.loaded) / float64(h.Reports)
+
+	smoothed := float64(h.loaded+1) / float64(h.Reports+2)
+	startupPenalty := math.Min(float64(h.AvgStartupMS)/30000, 0.5)
+	bufferingPenalty := math.Min(h.AvgBuffering*0.05, 0.5)
+
+	h.Score = int(math.Round(100 * smoothed * (1 - startupPenalty) * (1 - bufferingPenalty)))
+}
+
+// rankStreams annotates streams with their health and orders them by score,
+// keeping addon order between equal scores
+func (a *Aggregator) rankStreams(ctx context.Context, streams []interface{}) {
+	keys := make([]string, 0, len(streams))
+	for _, item := range streams {
+		if key := streamKey(item.(map[string]interface{})); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	health, err := a.repo.StreamHealth(ctx, keys)
+	if err != nil {
+		log.Printf("Failed to rank streams: %v", err)
+		return
+	}
+
+	scores := make([]int, len(streams))
+	for i, item := range streams {
+		stream := item.(map[string]interface{})
+		h, ok := health[streamKey(stream)]
+		if !ok {
+			h = &StreamHealth{Score: NeutralScore}
+		}
+		stream["health"] = h
+		scores[i] = h.Score
+	}
+
+	order := make([]int, len(streams))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+
+	ranked := make([]interface{}, len(streams))
+	for i, index := range order {
+		ranked[i] = streams[index]
+	}
+	copy(streams, ranked)
+}
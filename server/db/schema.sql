@@ -81,4 +81,280 @@ CREATE TABLE IF NOT EXISTS watch_history (
 );
 
 CREATE INDEX idx_watch_history_user_time 
-ON watch_history(user_id, last_watched DESC);
\ No newline at end of file
+ON watch_history(user_id, last_watched DESC);
+
+-- account deletion: users are soft-deleted first and purged after a grace period
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMP;
+-- tokens issued before this are refused, even after the user logs back in
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_valid_after TIMESTAMPTZ;
+
+-- authored messages survive account deletion anonymised
+ALTER TABLE room_messages ALTER COLUMN user_id DROP NOT NULL;
+
+CREATE TABLE IF NOT EXISTS user_privacy_settings (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    hide_activity BOOLEAN NOT NULL DEFAULT false,
+    invisible BOOLEAN NOT NULL DEFAULT false,
+    friend_requests VARCHAR(20) NOT NULL DEFAULT 'everyone' CHECK (friend_requests IN ('everyone', 'nobody')),
+    profile_visibility VARCHAR(20) NOT NULL DEFAULT 'everyone' CHECK (profile_visibility IN ('everyone', 'friends', 'nobody')),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_blocks (
+    blocker_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    blocked_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_blocks_blocked ON user_blocks(blocked_id);
+
+CREATE TABLE IF NOT EXISTS direct_messages (
+    id SERIAL PRIMARY KEY,
+    sender_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recipient_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    read_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_direct_messages_pair
+ON direct_messages(LEAST(sender_id, recipient_id), GREATEST(sender_id, recipient_id), id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_direct_messages_unread
+ON direct_messages(recipient_id, sender_id) WHERE read_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    notification_type VARCHAR(50) NOT NULL,
+    channel VARCHAR(10) NOT NULL DEFAULT 'in_app' CHECK (channel IN ('in_app', 'email', 'off')),
+    PRIMARY KEY (user_id, notification_type)
+);
+
+CREATE TABLE IF NOT EXISTS notification_settings (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    quiet_hours_enabled BOOLEAN NOT NULL DEFAULT false,
+    quiet_hours_start VARCHAR(5) NOT NULL DEFAULT '22:00',
+    quiet_hours_end VARCHAR(5) NOT NULL DEFAULT '08:00',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    room_id INTEGER REFERENCES watch_rooms(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(100) NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks(user_id) WHERE room_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_webhooks_room ON webhooks(room_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_status_code INTEGER,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id DESC);
+
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    endpoint TEXT NOT NULL UNIQUE,
+    p256dh VARCHAR(200) NOT NULL,
+    auth VARCHAR(100) NOT NULL,
+    expires_at TIMESTAMP,
+    user_agent TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user ON push_subscriptions(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_watch_history_user_title
+ON watch_history(user_id, imdb_id, last_watched DESC);
+
+CREATE TABLE IF NOT EXISTS watchlists (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    is_default BOOLEAN NOT NULL DEFAULT false,
+    shared BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(user_id, name)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_watchlists_default ON watchlists(user_id) WHERE is_default;
+
+CREATE TABLE IF NOT EXISTS watchlist_items (
+    id SERIAL PRIMARY KEY,
+    list_id INTEGER NOT NULL REFERENCES watchlists(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    imdb_id VARCHAR(20) NOT NULL,
+    media_type VARCHAR(10) NOT NULL CHECK (media_type IN ('movie', 'series')),
+    season_number INTEGER,
+    episode_number INTEGER,
+    position INTEGER NOT NULL DEFAULT 0,
+    note TEXT,
+    added_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(list_id, imdb_id, media_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_watchlist_items_list ON watchlist_items(list_id, position);
+
+CREATE TABLE IF NOT EXISTS title_reviews (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    imdb_id VARCHAR(20) NOT NULL,
+    media_type VARCHAR(10) NOT NULL CHECK (media_type IN ('movie', 'series')),
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 10),
+    review TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(user_id, imdb_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_title_reviews_title ON title_reviews(imdb_id, updated_at DESC);
+
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('trakt', 'letterboxd')),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    imported INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    errors TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_jobs_user ON import_jobs(user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS room_sessions (
+    id SERIAL PRIMARY KEY,
+    room_id INTEGER NOT NULL REFERENCES watch_rooms(id) ON DELETE CASCADE,
+    imdb_id VARCHAR(20),
+    media_type VARCHAR(10) CHECK (media_type IN ('movie', 'series')),
+    season_number INTEGER,
+    episode_number INTEGER,
+    furthest_position INTEGER NOT NULL DEFAULT 0,
+    duration_seconds INTEGER,
+    started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    ended_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_sessions_room ON room_sessions(room_id, id DESC);
+
+CREATE TABLE IF NOT EXISTS room_session_attendees (
+    session_id INTEGER NOT NULL REFERENCES room_sessions(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    joined_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    left_at TIMESTAMP,
+    PRIMARY KEY (session_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_addons (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    transport_url TEXT NOT NULL,
+    addon_id VARCHAR(255) NOT NULL,
+    version VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    manifest JSONB NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, transport_url),
+    UNIQUE (user_id, addon_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_addons_user ON user_addons(user_id, position);
+
+CREATE TABLE IF NOT EXISTS room_addons (
+    id SERIAL PRIMARY KEY,
+    room_id INTEGER NOT NULL REFERENCES watch_rooms(id) ON DELETE CASCADE,
+    transport_url TEXT NOT NULL,
+    addon_id VARCHAR(255) NOT NULL,
+    version VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    manifest JSONB NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (room_id, transport_url),
+    UNIQUE (room_id, addon_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_addons_room ON room_addons(room_id, position);
+
+ALTER TABLE user_addons ADD COLUMN IF NOT EXISTS enabled BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE user_addons ADD COLUMN IF NOT EXISTS config TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS addon_registry (
+    id SERIAL PRIMARY KEY,
+    manifest_url TEXT NOT NULL UNIQUE,
+    addon_id VARCHAR(255) NOT NULL UNIQUE,
+    version VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    manifest JSONB NOT NULL,
+    health_status VARCHAR(10) NOT NULL DEFAULT 'healthy' CHECK (health_status IN ('healthy', 'failing', 'dead')),
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    last_checked_at TIMESTAMP,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_addon_registry_tags ON addon_registry USING GIN (tags);
+
+CREATE TABLE IF NOT EXISTS stream_reports (
+    stream_key TEXT NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    outcome VARCHAR(10) NOT NULL CHECK (outcome IN ('loaded', 'failed')),
+    startup_ms INTEGER,
+    buffering_events INTEGER NOT NULL DEFAULT 0,
+    reported_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (stream_key, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stream_reports_recent ON stream_reports(stream_key, reported_at DESC);
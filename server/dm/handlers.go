@@ -0,0 +1,164 @@
+package dm
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Notifier pushes a realtime event to a user's notification channel
+type Notifier func(userID int, eventType string, data map[string]interface{}) error
+
+type DMHandlers struct {
+	repo   *DMRepository
+	notify Notifier
+}
+
+func NewDMHandlers(repo *DMRepository, notify Notifier) *DMHandlers {
+	return &DMHandlers{
+		repo:   repo,
+		notify: notify,
+	}
+}
+
+func (h *DMHandlers) GetConversation(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	otherID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	limit := DefaultPageSize
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = min(limit, MaxPageSize)
+	}
+
+	beforeID := 0
+	if beforeStr := c.Query("before"); beforeStr != "" {
+		beforeID, err = strconv.Atoi(beforeStr)
+		if err != nil || beforeID < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	// fetch one extra row to know whether another page exists
+	messages, err := h.repo.GetConversation(ctx, userID.(int), otherID, beforeID, limit+1)
+	if err != nil {
+		log.Printf("Error loading conversation %d/%d: %v", userID.(int), otherID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve messages"})
+		return
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	response := gin.H{
+		"messages": messages,
+		"has_more": hasMore,
+	}
+	if hasMore {
+		response["next_before"] = messages[len(messages)-1].ID
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *DMHandlers) GetUnreadCounts(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	counts, err := h.repo.GetUnreadCounts(ctx, userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve unread counts"})
+		return
+	}
+
+	total := 0
+	for _, count := range counts {
+		total += count.Count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversations": counts,
+		"total":         total,
+	})
+}
+
+func (h *DMHandlers) MarkRead(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	otherID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		UpToID int `json:"up_to_id" binding:"min=0"`
+	}
+
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	receipt, err := h.repo.MarkRead(ctx, userID.(int), otherID, req.UpToID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark messages read"})
+		return
+	}
+
+	if receipt.ReadCount > 0 && h.notify != nil {
+		if err := h.notify(otherID, "direct_message_read", ReceiptData(receipt)); err != nil {
+			log.Printf("Failed to send read receipt to user %d: %v", otherID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, receipt)
+}
+
+// ReceiptData is the realtime payload of a read receipt
+func ReceiptData(receipt *ReadReceipt) map[string]interface{} {
+	return map[string]interface{}{
+		"reader_id":  receipt.ReaderID,
+		"up_to_id":   receipt.UpToID,
+		"read_count": receipt.ReadCount,
+		"read_at":    receipt.ReadAt.Unix(),
+	}
+}
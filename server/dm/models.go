@@ -0,0 +1,35 @@
+package dm
+
+import (
+	"time"
+)
+
+const (
+	MaxMessageLength = 2000
+	DefaultPageSize  = 50
+	MaxPageSize      = 100
+)
+
+type DirectMessage struct {
+	ID          int        `json:"id"`
+	SenderID    int        `json:"sender_id"`
+	RecipientID int        `json:"recipient_id"`
+	Content     string     `json:"content"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ReadAt      *time.Time `json:"read_at,omitempty"`
+}
+
+type UnreadCount struct {
+	UserID        int       `json:"user_id"`
+	Username      string    `json:"username"`
+	Count         int       `json:"count"`
+	LastMessageAt time.Time `json:"last_message_at"`
+}
+
+// ReadReceipt is sent back to the sender when messages are marked read
+type ReadReceipt struct {
+	ReaderID  int       `json:"reader_id"`
+	UpToID    int       `json:"up_to_id"`
+	ReadCount int64     `json:"read_count"`
+	ReadAt    time.Time `json:"read_at"`
+}
@@ -0,0 +1,150 @@
+package dm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DMRepository handles database operations for direct messages
+type DMRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewDMRepository creates a new DMRepository
+func NewDMRepository(db *pgxpool.Pool) *DMRepository {
+	return &DMRepository{db: db}
+}
+
+// CanMessage - only accepted friends that haven't blocked each other can talk
+func (r *DMRepository) CanMessage(ctx context.Context, userID, otherID int) (bool, error) {
+	query := `
+        SELECT EXISTS (
+            SELECT 1 FROM friendships
+            WHERE 
+                ((user_id = $1 AND friend_id = $2) OR
+                (user_id = $2 AND friend_id = $1)) AND
+                status = 'accepted'
+        ) AND NOT EXISTS (
+            SELECT 1 FROM user_blocks
+            WHERE 
+                (blocker_id = $1 AND blocked_id = $2) OR
+                (blocker_id = $2 AND blocked_id = $1)
+        )
+    `
+
+	var allowed bool
+	if err := r.db.QueryRow(ctx, query, userID, otherID).Scan(&allowed); err != nil {
+		return false, fmt.Errorf("failed to check messaging permission: %w", err)
+	}
+
+	return allowed, nil
+}
+
+func (r *DMRepository) Create(ctx context.Context, senderID, recipientID int, content string) (*DirectMessage, error) {
+	query := `
+        INSERT INTO direct_messages (sender_id, recipient_id, content)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at
+    `
+
+	msg := &DirectMessage{
+		SenderID:    senderID,
+		RecipientID: recipientID,
+		Content:     content,
+	}
+
+	if err := r.db.QueryRow(ctx, query, senderID, recipientID, content).Scan(&msg.ID, &msg.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to create direct message: %w", err)
+	}
+
+	return msg, nil
+}
+
+// GetConversation returns messages between two users, newest first. When
+// beforeID is set only older messages are returned.
+func (r *DMRepository) GetConversation(ctx context.Context, userID, otherID, beforeID, limit int) ([]DirectMessage, error) {
+	query := `
+        SELECT id, sender_id, recipient_id, content, created_at, read_at
+        FROM direct_messages
+        WHERE 
+            LEAST(sender_id, recipient_id) = LEAST($1::int, $2::int) AND
+            GREATEST(sender_id, recipient_id) = GREATEST($1::int, $2::int) AND
+            ($3 = 0 OR id < $3)
+        ORDER BY id DESC
+        LIMIT $4
+    `
+
+	rows, err := r.db.Query(ctx, query, userID, otherID, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversation: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []DirectMessage{}
+	for rows.Next() {
+		var msg DirectMessage
+		if err := rows.Scan(&msg.ID, &msg.SenderID, &msg.RecipientID, &msg.Content, &msg.CreatedAt, &msg.ReadAt); err != nil {
+			return nil, fmt.Errorf("failed to scan direct message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
+
+// MarkRead marks messages from otherID to readerID as read. upToID limits the
+// receipt to messages the reader has actually seen; 0 marks everything.
+func (r *DMRepository) MarkRead(ctx context.Context, readerID, otherID, upToID int) (*ReadReceipt, error) {
+	query := `
+        WITH updated AS (
+            UPDATE direct_messages
+            SET read_at = NOW()
+            WHERE recipient_id = $1 AND sender_id = $2 AND read_at IS NULL
+                AND ($3 = 0 OR id <= $3)
+            RETURNING id
+        )
+        SELECT COUNT(*), COALESCE(MAX(id), 0) FROM updated
+    `
+
+	receipt := &ReadReceipt{
+		ReaderID: readerID,
+		ReadAt:   time.Now(),
+	}
+
+	if err := r.db.QueryRow(ctx, query, readerID, otherID, upToID).Scan(&receipt.ReadCount, &receipt.UpToID); err != nil {
+		return nil, fmt.Errorf("failed to mark messages read: %w", err)
+	}
+
+	return receipt, nil
+}
+
+func (r *DMRepository) GetUnreadCounts(ctx context.Context, userID int) ([]UnreadCount, error) {
+	query := `
+        SELECT m.sender_id, u.username, COUNT(*), MAX(m.created_at)
+        FROM direct_messages m
+        JOIN users u ON u.id = m.sender_id
+        WHERE m.recipient_id = $1 AND m.read_at IS NULL
+        GROUP BY m.sender_id, u.username
+        ORDER BY MAX(m.created_at) DESC
+    `
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unread counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []UnreadCount{}
+	for rows.Next() {
+		var count UnreadCount
+		if err := rows.Scan(&count.UserID, &count.Username, &count.Count, &count.LastMessageAt); err != nil {
+			return nil, fmt.Errorf("failed to scan unread count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
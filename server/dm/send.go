@@ -0,0 +1,58 @@
+package dm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// PreviewLength caps how much of a message is shown in a push notification
+const PreviewLength = 100
+
+var (
+	ErrEmptyMessage   = errors.New("message is empty")
+	ErrMessageTooLong = errors.New("message is too long")
+	ErrNotAllowed     = errors.New("users are not friends or one blocked the other")
+)
+
+// messageStore is the part of DMRepository that sending needs
+type messageStore interface {
+	CanMessage(ctx context.Context, userID, otherID int) (bool, error)
+	Create(ctx context.Context, senderID, recipientID int, content string) (*DirectMessage, error)
+}
+
+// Send trims and validates the content, then stores the message if the two
+// users are accepted friends that haven't blocked each other
+func (r *DMRepository) Send(ctx context.Context, senderID, recipientID int, content string) (*DirectMessage, error) {
+	return send(ctx, r, senderID, recipientID, content)
+}
+
+func send(ctx context.Context, store messageStore, senderID, recipientID int, content string) (*DirectMessage, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrEmptyMessage
+	}
+	if utf8.RuneCountInString(content) > MaxMessageLength {
+		return nil, ErrMessageTooLong
+	}
+
+	allowed, err := store.CanMessage(ctx, senderID, recipientID)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrNotAllowed
+	}
+
+	return store.Create(ctx, senderID, recipientID, content)
+}
+
+// Preview shortens content to PreviewLength characters
+func Preview(content string) string {
+	if utf8.RuneCountInString(content) <= PreviewLength {
+		return content
+	}
+	runes := []rune(content)
+	return string(runes[:PreviewLength-1]) + "…"
+}
@@ -0,0 +1,107 @@
+package dm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// fakeStore allows messages between the pairs in friends and records what
+// it stores
+type fakeStore struct {
+	friends map[[2]int]bool
+	stored  []string
+	err     error
+}
+
+func (f *fakeStore) CanMessage(ctx context.Context, userID, otherID int) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	return f.friends[[2]int{userID, otherID}] || f.friends[[2]int{otherID, userID}], nil
+}
+
+func (f *fakeStore) Create(ctx context.Context, senderID, recipientID int, content string) (*DirectMessage, error) {
+	f.stored = append(f.stored, content)
+	return &DirectMessage{ID: len(f.stored), SenderID: senderID, RecipientID: recipientID, Content: content}, nil
+}
+
+func TestSend(t *testing.T) {
+	// 1 and 2 are friends; 3 is not a friend of 1, or blocked
+	store := &fakeStore{friends: map[[2]int]bool{{1, 2}: true}}
+	ctx := context.Background()
+
+	msg, err := send(ctx, store, 2, 1, "  hi there \n")
+	if err != nil {
+		t.Fatalf("send() between friends error = %v", err)
+	}
+	if msg.Content != "hi there" || msg.SenderID != 2 || msg.RecipientID != 1 {
+		t.Errorf("send() = %+v", msg)
+	}
+
+	if _, err := send(ctx, store, 1, 3, "hello"); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("send() to a stranger error = %v, want ErrNotAllowed", err)
+	}
+	if len(store.stored) != 1 {
+		t.Errorf("stored %d messages, want only the one between friends", len(store.stored))
+	}
+
+	store.err = errors.New("database is down")
+	if _, err := send(ctx, store, 1, 2, "hello"); err == nil || errors.Is(err, ErrNotAllowed) {
+		t.Errorf("send() with a failing permission check error = %v", err)
+	}
+}
+
+func TestSendLength(t *testing.T) {
+	store := &fakeStore{friends: map[[2]int]bool{{1, 2}: true}}
+	ctx := context.Background()
+
+	// the limit counts characters, so a message of multi-byte runes at the
+	// limit is accepted even though it is far longer in bytes
+	atLimit := strings.Repeat("é", MaxMessageLength)
+	if _, err := send(ctx, store, 1, 2, atLimit); err != nil {
+		t.Errorf("send() of %d characters error = %v", MaxMessageLength, err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    error
+	}{
+		{"one character too long", strings.Repeat("a", MaxMessageLength+1), ErrMessageTooLong},
+		{"emoji over the limit", strings.Repeat("🎬", MaxMessageLength+1), ErrMessageTooLong},
+		{"empty", "", ErrEmptyMessage},
+		{"whitespace only", " \t\n ", ErrEmptyMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := send(ctx, store, 1, 2, tt.content); !errors.Is(err, tt.want) {
+				t.Errorf("send() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// surrounding whitespace does not count towards the limit
+	padded := "   " + strings.Repeat("a", MaxMessageLength) + "   "
+	if _, err := send(ctx, store, 1, 2, padded); err != nil {
+		t.Errorf("send() of a padded message at the limit error = %v", err)
+	}
+
+	if len(store.stored) != 2 {
+		t.Errorf("stored %d messages, want 2", len(store.stored))
+	}
+}
+
+func TestPreview(t *testing.T) {
+	if got := Preview("short"); got != "short" {
+		t.Errorf("Preview() = %q", got)
+	}
+
+	got := Preview(strings.Repeat("ü", PreviewLength+10))
+	if n := utf8.RuneCountInString(got); n != PreviewLength || !strings.HasSuffix(got, "…") {
+		t.Errorf("Preview() = %q (%d characters)", got, n)
+	}
+}
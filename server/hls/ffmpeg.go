@@ -0,0 +1,141 @@
+package hls
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var durationLine = regexp.MustCompile(`Duration: (\d+):(\d+):(\d+(?:\.\d+)?)`)
+
+// convert runs ffmpeg for the job, writing an event playlist so players can
+// start before the conversion is done, and reports progress as it goes
+func (m *Manager) convert(ctx context.Context, job *Job) error {
+	// the host may have re-resolved while the job was queued
+	if m.remote(job.input) {
+		if err := checkRemote(ctx, job.input); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(job.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create segment dir: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, m.ffmpeg, ffmpegArgs(job.input, job.dir, job.Transcode)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	lastError := make(chan string, 1)
+	go func() {
+		lastError <- m.readLog(job, stderr)
+	}()
+	m.readProgress(job, stdout)
+
+	message := <-lastError
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if message != "" {
+			return fmt.Errorf("ffmpeg failed: %s", message)
+		}
+		return fmt.Errorf("ffmpeg failed: %w", err)
+	}
+	return nil
+}
+
+// remote reports whether input is a user supplied URL rather than a file or
+// a stream served by this server
+func (m *Manager) remote(input string) bool {
+	return isURL(input) && !strings.HasPrefix(input, m.localBase+"/")
+}
+
+func isURL(input string) bool {
+	return strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://")
+}
+
+// ffmpegArgs pins the protocols ffmpeg may open to those of the input, so a
+// playlist or container cannot make it read local files or other protocols
+func ffmpegArgs(input, dir string, transcode bool) []string {
+	args := []string{"-hide_banner", "-nostdin", "-nostats", "-y"}
+	if isURL(input) {
+		args = append(args,
+			"-protocol_whitelist", "http,https,tcp,tls",
+			"-reconnect", "1", "-reconnect_streamed", "1", "-reconnect_delay_max", "5")
+	} else {
+		args = append(args, "-protocol_whitelist", "file")
+	}
+	args = append(args, "-i", input, "-map", "0:v:0", "-map", "0:a:0?")
+
+	if transcode {
+		args = append(args,
+			"-c:v", "libx264", "-preset", "veryfast", "-crf", "23",
+			"-c:a", "aac", "-b:a", "160k", "-ac", "2")
+	} else {
+		args = append(args, "-c", "copy")
+	}
+
+	return append(args,
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(segmentDuration),
+		"-hls_playlist_type", "event",
+		"-hls_segment_filename", filepath.Join(dir, "segment_%05d.ts"),
+		"-progress", "pipe:1",
+		filepath.Join(dir, PlaylistName))
+}
+
+// readProgress follows ffmpeg's -progress key=value output
+func (m *Manager) readProgress(job *Job, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		// out_time_ms is in microseconds too, it predates out_time_us
+		if key == "out_time_us" || key == "out_time_ms" {
+			if micros, err := strconv.ParseInt(value, 10, 64); err == nil {
+				m.setProgress(job, float64(micros)/1e6, 0)
+			}
+		}
+	}
+}
+
+// readLog picks the input duration out of ffmpeg's log and returns its last
+// line, which explains the failure when ffmpeg exits with an error
+func (m *Manager) readLog(job *Job, r io.Reader) string {
+	var last string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		last = line
+
+		if match := durationLine.FindStringSubmatch(line); match != nil {
+			hours, _ := strconv.Atoi(match[1])
+			minutes, _ := strconv.Atoi(match[2])
+			seconds, _ := strconv.ParseFloat(match[3], 64)
+			m.setProgress(job, 0, float64(hours*3600+minutes*60)+seconds)
+		}
+	}
+	return last
+}
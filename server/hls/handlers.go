@@ -0,0 +1,189 @@
+package hls
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"zync-stream/rooms"
+)
+
+// RoomPublisher posts an event into a room's realtime channel
+type RoomPublisher func(roomID int, eventType string, userID int, username string, data map[string]interface{}) error
+
+type HLSHandlers struct {
+	manager     *Manager
+	roomRepo    *rooms.RoomRepository
+	publishRoom RoomPublisher
+}
+
+func NewHLSHandlers(manager *Manager, roomRepo *rooms.RoomRepository, publishRoom RoomPublisher) *HLSHandlers {
+	return &HLSHandlers{
+		manager:     manager,
+		roomRepo:    roomRepo,
+		publishRoom: publishRoom,
+	}
+}
+
+// StartJob queues a conversion, or joins the one already running for the
+// room or source. Room jobs are announced so every member switches to them;
+// only the room owner or the job's creator may switch the room to a new source.
+func (h *HLSHandlers) StartJob(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req StartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	roomOwner := false
+	if req.RoomID != nil {
+		role, ok := h.checkMember(c, *req.RoomID, userID.(int))
+		if !ok {
+			return
+		}
+		roomOwner = role == rooms.RoleOwner
+	}
+
+	job, created, err := h.manager.Start(c.Request.Context(), userID.(int), roomOwner, req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSource) || errors.Is(err, ErrPrivateSource) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrJobInUse) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrTooManyJobs) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error starting HLS job: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start HLS job"})
+		return
+	}
+
+	if created && req.RoomID != nil {
+		data := map[string]interface{}{"job": job}
+		if err := h.publishRoom(*req.RoomID, "hls_started", userID.(int), c.GetString("username"), data); err != nil {
+			log.Printf("Failed to publish HLS job for room %d: %v", *req.RoomID, err)
+		}
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, gin.H{"job": job})
+}
+
+// GetJob reports a job's status, queue position and progress
+func (h *HLSHandlers) GetJob(c *gin.Context) {
+	job, err := h.manager.Get(c.Param("job"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// CancelJob stops a job. Only the member who started it may cancel it.
+func (h *HLSHandlers) CancelJob(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	job, err := h.manager.Get(c.Param("job"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	if job.CreatedBy != userID.(int) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the user who started this job can cancel it"})
+		return
+	}
+
+	if err := h.manager.Cancel(job.ID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetRoomJob returns the job the room's members are sharing
+func (h *HLSHandlers) GetRoomJob(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+	if _, ok := h.checkMember(c, roomID, userID.(int)); !ok {
+		return
+	}
+
+	job, err := h.manager.ForRoom(roomID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "This room has no HLS job"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// ServeFile serves a job's playlist and segments. It is public so players
+// can fetch segments without a token; job ids are random and unguessable.
+func (h *HLSHandlers) ServeFile(c *gin.Context) {
+	path, err := h.manager.FilePath(c.Param("job"), c.Param("file"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+	if path == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not ready yet"})
+		return
+	}
+
+	if c.Param("file") == PlaylistName {
+		c.Header("Content-Type", "application/vnd.apple.mpegurl")
+		c.Header("Cache-Control", "no-cache")
+	} else {
+		c.Header("Content-Type", "video/mp2t")
+		c.Header("Cache-Control", "public, max-age=86400")
+	}
+	c.File(path)
+}
+
+// checkMember returns the user's role in the room, or responds and reports
+// false when they are not a member
+func (h *HLSHandlers) checkMember(c *gin.Context, roomID, userID int) (string, bool) {
+	isMember, role, err := h.roomRepo.IsRoomMember(c.Request.Context(), roomID, userID)
+	if err != nil {
+		log.Printf("Error checking room membership: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check room membership"})
+		return "", false
+	}
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this room"})
+		return "", false
+	}
+	return role, true
+}
@@ -0,0 +1,465 @@
+package hls
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"zync-stream/netguard"
+)
+
+var (
+	segmentName  = regexp.MustCompile(`^segment_\d+\.ts$`)
+	jobIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+// Manager queues ffmpeg jobs and runs at most maxJobs at a time. Jobs whose
+// playlist nobody fetched for ttl are cancelled, then deleted with their
+// segments.
+type Manager struct {
+	ffmpeg    string
+	dir       string
+	sourceDir string // local files must live under it; empty disables them
+	localBase string // prefix for server paths such as /api/torrents/stream/...
+	maxJobs   int
+	ttl       time.Duration
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	byKey   map[string]*Job
+	queue   []*Job
+	running int
+}
+
+// NewManagerFromEnv finds ffmpeg (FFMPEG_PATH or $PATH) and reads HLS_DIR,
+// HLS_SOURCE_DIR, HLS_MAX_JOBS and HLS_JOB_TTL. It fails when no ffmpeg
+// binary is available.
+func NewManagerFromEnv() (*Manager, error) {
+	ffmpeg := os.Getenv("FFMPEG_PATH")
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	ffmpeg, err := exec.LookPath(ffmpeg)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+
+	dir := os.Getenv("HLS_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "zync-hls")
+	}
+
+	maxJobs := DefaultMaxJobs
+	if value := os.Getenv("HLS_MAX_JOBS"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			maxJobs = parsed
+		}
+	}
+
+	ttl := DefaultJobTTL
+	if value := os.Getenv("HLS_JOB_TTL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			ttl = parsed
+		}
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	return NewManager(ffmpeg, dir, os.Getenv("HLS_SOURCE_DIR"), "http://127.0.0.1:"+port, maxJobs, ttl)
+}
+
+// NewManager clears segments left in dir by a previous run
+func NewManager(ffmpeg, dir, sourceDir, localBase string, maxJobs int, ttl time.Duration) (*Manager, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create HLS dir: %w", err)
+	}
+	removeStaleJobs(dir)
+
+	return &Manager{
+		ffmpeg:    ffmpeg,
+		dir:       dir,
+		sourceDir: sourceDir,
+		localBase: strings.TrimSuffix(localBase, "/"),
+		maxJobs:   maxJobs,
+		ttl:       ttl,
+		jobs:      make(map[string]*Job),
+		byKey:     make(map[string]*Job),
+	}, nil
+}
+
+// Start queues a conversion, or returns the job already converting the same
+// source for this room (or for anyone, outside rooms). created reports
+// whether a new job was queued. A live room job for another source is only
+// replaced for its creator or, with roomOwner set, the room's owner. Each
+// user may have MaxJobsPerUser jobs queued or running.
+func (m *Manager) Start(ctx context.Context, userID int, roomOwner bool, req StartRequest) (job *Job, created bool, err error) {
+	input, err := m.resolveSource(ctx, req.Source)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := fmt.Sprintf("source:%t:%s", req.Transcode, input)
+	if req.RoomID != nil {
+		key = fmt.Sprintf("room:%d", *req.RoomID)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	existing := m.byKey[key]
+	if existing != nil {
+		if existing.input == input && existing.Transcode == req.Transcode &&
+			existing.Status != StatusFailed && existing.Status != StatusCancelled {
+			existing.lastAccess = now
+			return m.snapshotLocked(existing), false, nil
+		}
+		if existing.Status != StatusFailed && existing.Status != StatusCancelled &&
+			existing.CreatedBy != userID && !roomOwner {
+			return nil, false, ErrJobInUse
+		}
+	}
+
+	// the job being replaced no longer counts against its creator
+	active := 0
+	for _, other := range m.jobs {
+		if other.CreatedBy == userID && other != existing && !other.finished() {
+			active++
+		}
+	}
+	if active >= MaxJobsPerUser {
+		return nil, false, ErrTooManyJobs
+	}
+
+	if existing != nil {
+		m.cancelLocked(existing)
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, false, err
+	}
+
+	job = &Job{
+		ID:          id,
+		Source:      req.Source,
+		Transcode:   req.Transcode,
+		RoomID:      req.RoomID,
+		CreatedBy:   userID,
+		Status:      StatusQueued,
+		PlaylistURL: fmt.Sprintf("%s/%s/%s", PathPrefix, id, PlaylistName),
+		CreatedAt:   now,
+		key:         key,
+		input:       input,
+		dir:         filepath.Join(m.dir, id),
+		lastAccess:  now,
+	}
+	m.jobs[id] = job
+	m.byKey[key] = job
+	m.queue = append(m.queue, job)
+	log.Printf("Queued HLS job %s for %s", id, req.Source)
+
+	m.dispatchLocked()
+	return m.snapshotLocked(job), true, nil
+}
+
+func (m *Manager) Get(id string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job := m.jobs[id]
+	if job == nil {
+		return nil, ErrNotFound
+	}
+	job.lastAccess = time.Now()
+	return m.snapshotLocked(job), nil
+}
+
+// ForRoom returns the job members of the room are sharing, if any
+func (m *Manager) ForRoom(roomID int) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job := m.byKey[fmt.Sprintf("room:%d", roomID)]
+	if job == nil {
+		return nil, ErrNotFound
+	}
+	job.lastAccess = time.Now()
+	return m.snapshotLocked(job), nil
+}
+
+func (m *Manager) Cancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job := m.jobs[id]
+	if job == nil {
+		return ErrNotFound
+	}
+	m.cancelLocked(job)
+	return nil
+}
+
+// FilePath returns the on-disk path of a job's playlist or segment, or ""
+// when the name is neither or the file has not been written yet
+func (m *Manager) FilePath(id, name string) (string, error) {
+	if name != PlaylistName && !segmentName.MatchString(name) {
+		return "", ErrNotFound
+	}
+
+	m.mu.Lock()
+	job := m.jobs[id]
+	if job != nil {
+		job.lastAccess = time.Now()
+	}
+	m.mu.Unlock()
+
+	if job == nil {
+		return "", ErrNotFound
+	}
+
+	path := filepath.Join(job.dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", nil
+	}
+	return path, nil
+}
+
+// Cleanup cancels jobs nobody has polled or played for ttl and deletes
+// finished ones along with their segments
+func (m *Manager) Cleanup(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, job := range m.jobs {
+		if now.Sub(job.lastAccess) <= m.ttl {
+			continue
+		}
+		if !job.finished() {
+			log.Printf("Cancelling abandoned HLS job %s", id)
+			m.cancelLocked(job)
+			continue
+		}
+		if job.Status == StatusReady {
+			m.removeSegments(job)
+		}
+		m.cancelLocked(job)
+		delete(m.jobs, id)
+	}
+}
+
+// StartCleaner runs Cleanup every interval until ctx is done, then cancels
+// the remaining jobs
+func StartCleaner(ctx context.Context, manager *Manager, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				manager.cancelAll()
+				return
+			case now := <-ticker.C:
+				manager.Cleanup(now)
+			}
+		}
+	}()
+}
+
+func (m *Manager) cancelAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, job := range m.jobs {
+		m.cancelLocked(job)
+	}
+}
+
+// dispatchLocked starts queued jobs while there are free slots
+func (m *Manager) dispatchLocked() {
+	for m.running < m.maxJobs && len(m.queue) > 0 {
+		job := m.queue[0]
+		m.queue = m.queue[1:]
+
+		ctx, cancel := context.WithCancel(context.Background())
+		startedAt := time.Now()
+		job.Status = StatusRunning
+		job.StartedAt = &startedAt
+		job.cancel = cancel
+		m.running++
+
+		go m.run(ctx, job)
+	}
+}
+
+func (m *Manager) run(ctx context.Context, job *Job) {
+	err := m.convert(ctx, job)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	job.cancel()
+	m.running--
+
+	switch {
+	case job.Status == StatusCancelled:
+		m.removeSegments(job)
+		log.Printf("HLS job %s cancelled", job.ID)
+	case err != nil:
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		m.removeSegments(job)
+		log.Printf("HLS job %s failed: %v", job.ID, err)
+	default:
+		job.Status = StatusReady
+		job.Progress = 100
+		log.Printf("HLS job %s ready", job.ID)
+	}
+
+	m.dispatchLocked()
+}
+
+// cancelLocked stops a job and releases its share key. A running job is
+// cleaned up by run once ffmpeg exits.
+func (m *Manager) cancelLocked(job *Job) {
+	if m.byKey[job.key] == job {
+		delete(m.byKey, job.key)
+	}
+	if job.finished() {
+		return
+	}
+
+	if job.Status == StatusQueued {
+		for i, queued := range m.queue {
+			if queued == job {
+				m.queue = append(m.queue[:i], m.queue[i+1:]...)
+				break
+			}
+		}
+		finishedAt := time.Now()
+		job.FinishedAt = &finishedAt
+	} else {
+		job.cancel()
+	}
+	job.Status = StatusCancelled
+}
+
+func (m *Manager) removeSegments(job *Job) {
+	if err := os.RemoveAll(job.dir); err != nil {
+		log.Printf("Failed to delete segments of HLS job %s: %v", job.ID, err)
+	}
+}
+
+func (m *Manager) setProgress(job *Job, processed, duration float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if duration > 0 {
+		job.DurationSeconds = duration
+	}
+	if processed > 0 {
+		job.ProcessedSeconds = processed
+	}
+	if job.DurationSeconds > 0 {
+		job.Progress = min(99, job.ProcessedSeconds/job.DurationSeconds*100)
+	}
+}
+
+func (m *Manager) snapshotLocked(job *Job) *Job {
+	snapshot := *job
+	snapshot.QueuePosition = 0
+	for i, queued := range m.queue {
+		if queued == job {
+			snapshot.QueuePosition = i + 1
+			break
+		}
+	}
+	return &snapshot
+}
+
+// resolveSource accepts http(s) URLs of public hosts, paths served by this
+// server (such as torrent stream URLs) and files under sourceDir
+func (m *Manager) resolveSource(ctx context.Context, source string) (string, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		if err := checkRemote(ctx, source); err != nil {
+			return "", err
+		}
+		return source, nil
+	}
+
+	if strings.HasPrefix(source, "/api/") {
+		return m.localBase + source, nil
+	}
+
+	if m.sourceDir == "" {
+		return "", ErrInvalidSource
+	}
+	path := source
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.sourceDir, path)
+	}
+	rel, err := filepath.Rel(m.sourceDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidSource
+	}
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return "", ErrInvalidSource
+	}
+	return path, nil
+}
+
+// checkRemote refuses URLs that do not resolve to public addresses. ffmpeg
+// resolves the host itself, so convert checks again right before starting it.
+func checkRemote(ctx context.Context, source string) error {
+	err := netguard.CheckURL(ctx, source)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, netguard.ErrBlockedAddress):
+		return ErrPrivateSource
+	default:
+		return ErrInvalidSource
+	}
+}
+
+// removeStaleJobs deletes the job directories of an earlier run, leaving
+// anything else in the HLS dir alone
+func removeStaleJobs(dir string) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() || !jobIDPattern.MatchString(dirEntry.Name()) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, dirEntry.Name())); err != nil {
+			log.Printf("Failed to delete stale HLS job %s: %v", dirEntry.Name(), err)
+		}
+	}
+}
+
+func newJobID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate job id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
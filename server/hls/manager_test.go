@@ -0,0 +1,355 @@
+package hls
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newQueuedManager never runs ffmpeg: with no job slots every job stays queued
+func newQueuedManager(t *testing.T) *Manager {
+	t.Helper()
+	manager, err := NewManager("ffmpeg", t.TempDir(), "", "http://127.0.0.1:8080", 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+func TestStartReplacesRoomJob(t *testing.T) {
+	manager := newQueuedManager(t)
+	roomID := 5
+	first := StartRequest{Source: "https://203.0.113.10/a.mkv", RoomID: &roomID}
+	second := StartRequest{Source: "https://203.0.113.10/b.mkv", RoomID: &roomID}
+
+	job, created, err := manager.Start(context.Background(), 1, false, first)
+	if err != nil || !created {
+		t.Fatalf("Start() = %v, %v", created, err)
+	}
+
+	// another member asking for the same source shares the job
+	shared, created, err := manager.Start(context.Background(), 2, false, first)
+	if err != nil || created || shared.ID != job.ID {
+		t.Fatalf("Start() for the same source = %+v, %v, %v", shared, created, err)
+	}
+
+	if _, _, err := manager.Start(context.Background(), 2, false, second); !errors.Is(err, ErrJobInUse) {
+		t.Fatalf("Start() by another member error = %v, want ErrJobInUse", err)
+	}
+	if current, _ := manager.ForRoom(roomID); current.ID != job.ID || current.Status != StatusQueued {
+		t.Fatalf("room job after a refused replace = %+v", current)
+	}
+
+	replaced, created, err := manager.Start(context.Background(), 3, true, second)
+	if err != nil || !created {
+		t.Fatalf("Start() by the room owner = %v, %v", created, err)
+	}
+	if old, _ := manager.Get(job.ID); old.Status != StatusCancelled {
+		t.Errorf("replaced job status = %s", old.Status)
+	}
+
+	// the creator may switch the room to another source
+	again, created, err := manager.Start(context.Background(), 3, false, first)
+	if err != nil || !created {
+		t.Fatalf("Start() by the job creator = %v, %v", created, err)
+	}
+
+	// and once cancelled, any member may start a new one
+	if err := manager.Cancel(again.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, created, err := manager.Start(context.Background(), 2, false, second); err != nil || !created {
+		t.Errorf("Start() after cancel = %v, %v", created, err)
+	}
+	if old, _ := manager.Get(replaced.ID); old.Status != StatusCancelled {
+		t.Errorf("job %s status = %s", replaced.ID, old.Status)
+	}
+}
+
+func TestStartRejectsInvalidSources(t *testing.T) {
+	manager := newQueuedManager(t)
+
+	for _, source := range []string{"ftp://example.com/a.mkv", "https://", "/etc/passwd", "movie.mkv"} {
+		if _, _, err := manager.Start(context.Background(), 1, false, StartRequest{Source: source}); !errors.Is(err, ErrInvalidSource) {
+			t.Errorf("Start(%q) error = %v, want ErrInvalidSource", source, err)
+		}
+	}
+}
+
+func TestStartRejectsPrivateSources(t *testing.T) {
+	manager := newQueuedManager(t)
+
+	for _, source := range []string{"http://127.0.0.1:6379/", "http://169.254.169.254/latest/meta-data", "http://[::1]/a.mkv", "http://localhost/a.mkv", "https://10.0.0.8/a.mkv"} {
+		if _, _, err := manager.Start(context.Background(), 1, false, StartRequest{Source: source}); !errors.Is(err, ErrPrivateSource) {
+			t.Errorf("Start(%q) error = %v, want ErrPrivateSource", source, err)
+		}
+	}
+
+	// streams served by this server are read over loopback on purpose
+	job, _, err := manager.Start(context.Background(), 1, false, StartRequest{Source: "/api/torrents/stream/abc/0"})
+	if err != nil {
+		t.Fatalf("Start() of a server path error = %v", err)
+	}
+	if got := manager.jobs[job.ID].input; got != "http://127.0.0.1:8080/api/torrents/stream/abc/0" {
+		t.Errorf("input = %q", got)
+	}
+	if manager.remote(manager.jobs[job.ID].input) {
+		t.Error("a server path counts as a remote source")
+	}
+}
+
+func TestStartLimitsJobsPerUser(t *testing.T) {
+	manager := newQueuedManager(t)
+	ctx := context.Background()
+	source := func(i int) StartRequest {
+		return StartRequest{Source: "https://203.0.113.10/" + strings.Repeat("a", i+1) + ".mkv"}
+	}
+
+	var first *Job
+	for i := 0; i < MaxJobsPerUser; i++ {
+		job, _, err := manager.Start(ctx, 1, false, source(i))
+		if err != nil {
+			t.Fatalf("Start() %d error = %v", i, err)
+		}
+		if first == nil {
+			first = job
+		}
+	}
+
+	if _, _, err := manager.Start(ctx, 1, false, source(MaxJobsPerUser)); !errors.Is(err, ErrTooManyJobs) {
+		t.Fatalf("Start() over the limit error = %v, want ErrTooManyJobs", err)
+	}
+
+	// joining a job someone else started does not count
+	if _, created, err := manager.Start(ctx, 2, false, source(0)); err != nil || created {
+		t.Errorf("Start() of a shared source = %v, %v", created, err)
+	}
+	if _, _, err := manager.Start(ctx, 2, false, source(MaxJobsPerUser)); err != nil {
+		t.Errorf("Start() by another user error = %v", err)
+	}
+
+	// replacing one's own room job frees its slot
+	roomID := 9
+	if err := manager.Cancel(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := manager.Start(ctx, 1, false, StartRequest{Source: source(5).Source, RoomID: &roomID}); err != nil {
+		t.Fatalf("Start() after cancelling a job error = %v", err)
+	}
+	if _, created, err := manager.Start(ctx, 1, false, StartRequest{Source: source(6).Source, RoomID: &roomID}); err != nil || !created {
+		t.Errorf("Start() replacing the user's room job = %v, %v", created, err)
+	}
+}
+
+func TestNewManagerRemovesOnlyJobDirs(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "0123456789abcdef0123456789abcdef")
+	keep := []string{
+		filepath.Join(dir, "recordings"),
+		filepath.Join(dir, "0123456789ABCDEF0123456789ABCDEF"),
+		filepath.Join(dir, "0123456789abcdef"),
+	}
+	for _, path := range append(keep, stale) {
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file := filepath.Join(dir, "fedcba9876543210fedcba9876543210")
+	if err := os.WriteFile(file, []byte("not a job"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewManager("ffmpeg", dir, "", "http://127.0.0.1:8080", 0, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale job dir survived: %v", err)
+	}
+	for _, path := range append(keep, file) {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed: %v", filepath.Base(path), err)
+		}
+	}
+}
+
+func TestFFmpegArgs(t *testing.T) {
+	dir := filepath.Join("tmp", "job")
+
+	tests := []struct {
+		name      string
+		input     string
+		transcode bool
+		want      []string
+	}{
+		{
+			name:  "remote copy",
+			input: "https://203.0.113.10/a.mkv",
+			want: []string{"-hide_banner", "-nostdin", "-nostats", "-y",
+				"-protocol_whitelist", "http,https,tcp,tls",
+				"-reconnect", "1", "-reconnect_streamed", "1", "-reconnect_delay_max", "5",
+				"-i", "https://203.0.113.10/a.mkv", "-map", "0:v:0", "-map", "0:a:0?",
+				"-c", "copy"},
+		},
+		{
+			name:      "local transcode",
+			input:     "/media/a.mkv",
+			transcode: true,
+			want: []string{"-hide_banner", "-nostdin", "-nostats", "-y",
+				"-protocol_whitelist", "file",
+				"-i", "/media/a.mkv", "-map", "0:v:0", "-map", "0:a:0?",
+				"-c:v", "libx264", "-preset", "veryfast", "-crf", "23",
+				"-c:a", "aac", "-b:a", "160k", "-ac", "2"},
+		},
+	}
+
+	output := []string{"-f", "hls", "-hls_time", "6", "-hls_playlist_type", "event",
+		"-hls_segment_filename", filepath.Join(dir, "segment_%05d.ts"),
+		"-progress", "pipe:1", filepath.Join(dir, PlaylistName)}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ffmpegArgs(tt.input, dir, tt.transcode)
+			if want := append(tt.want, output...); !reflect.DeepEqual(got, want) {
+				t.Errorf("ffmpegArgs() =\n%q\nwant\n%q", got, want)
+			}
+		})
+	}
+}
+
+func TestReadProgressAndLog(t *testing.T) {
+	manager := newQueuedManager(t)
+	job := &Job{}
+
+	log := `Input #0, matroska,webm, from 'https://203.0.113.10/a.mkv':
+  Duration: 01:02:03.50, start: 0.000000, bitrate: 4000 kb/s
+
+[hls @ 0x55] Opening 'segment_00001.ts' for writing
+Conversion failed!
+`
+	if last := manager.readLog(job, strings.NewReader(log)); last != "Conversion failed!" {
+		t.Errorf("readLog() = %q, want the last line", last)
+	}
+	if job.DurationSeconds != 3723.5 {
+		t.Errorf("DurationSeconds = %v", job.DurationSeconds)
+	}
+
+	progress := `frame=100
+out_time_us=1861750000
+progress=continue
+out_time_ms=not a number
+garbage
+out_time_ms=3723500000
+progress=end
+`
+	manager.readProgress(job, strings.NewReader(progress))
+	if job.ProcessedSeconds != 3723.5 {
+		t.Errorf("ProcessedSeconds = %v", job.ProcessedSeconds)
+	}
+	// done is only reported once ffmpeg exits cleanly
+	if job.Progress != 99 {
+		t.Errorf("Progress = %v, want 99 until ffmpeg exits", job.Progress)
+	}
+
+	half := &Job{DurationSeconds: 100}
+	manager.readProgress(half, strings.NewReader("out_time_us=50000000\n"))
+	if half.Progress != 50 {
+		t.Errorf("Progress = %v, want 50", half.Progress)
+	}
+
+	// without a duration the progress stays unknown
+	unknown := &Job{}
+	manager.readProgress(unknown, strings.NewReader("out_time_us=50000000\n"))
+	if unknown.Progress != 0 || unknown.ProcessedSeconds != 50 {
+		t.Errorf("job without duration = %+v", unknown)
+	}
+}
+
+func TestCleanupExpiresJobs(t *testing.T) {
+	manager := newQueuedManager(t)
+	ctx := context.Background()
+
+	abandoned, _, err := manager.Start(ctx, 1, false, StartRequest{Source: "https://203.0.113.10/a.mkv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, _, err := manager.Start(ctx, 2, false, StartRequest{Source: "https://203.0.113.10/b.mkv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ready, _, err := manager.Start(ctx, 3, false, StartRequest{Source: "https://203.0.113.10/c.mkv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readyJob := manager.jobs[ready.ID]
+	if err := os.MkdirAll(readyJob.dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	manager.mu.Lock()
+	manager.cancelLocked(readyJob) // drop it from the queue
+	readyJob.Status = StatusReady
+	manager.mu.Unlock()
+
+	now := time.Now()
+	manager.jobs[abandoned.ID].lastAccess = now.Add(-2 * time.Minute)
+	manager.jobs[ready.ID].lastAccess = now.Add(-2 * time.Minute)
+	manager.jobs[fresh.ID].lastAccess = now.Add(-30 * time.Second)
+
+	manager.Cleanup(now)
+
+	if job, err := manager.Get(abandoned.ID); err != nil || job.Status != StatusCancelled {
+		t.Errorf("abandoned job after Cleanup() = %+v, %v, want it cancelled but kept", job, err)
+	}
+	if _, err := manager.Get(ready.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expired ready job error = %v, want ErrNotFound", err)
+	}
+	if _, err := os.Stat(readyJob.dir); !os.IsNotExist(err) {
+		t.Errorf("segments of the expired job survived: %v", err)
+	}
+	if job, err := manager.Get(fresh.ID); err != nil || job.Status != StatusQueued {
+		t.Errorf("fresh job after Cleanup() = %+v, %v", job, err)
+	}
+
+	// Get refreshed lastAccess, so the cancelled job needs another ttl
+	manager.Cleanup(now.Add(2 * time.Minute))
+	if _, err := manager.Get(abandoned.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("cancelled job error = %v, want it deleted", err)
+	}
+}
+
+func TestFilePath(t *testing.T) {
+	manager := newQueuedManager(t)
+	job, _, err := manager.Start(context.Background(), 1, false, StartRequest{Source: "https://203.0.113.10/a.mkv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := manager.jobs[job.ID].dir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "segment_00001.ts"), []byte("ts"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"../../etc/passwd", "..", "segment_1.ts.bak", "segment_.ts", "segment_00001.ts/", "index.m3u8.tmp", ""} {
+		if _, err := manager.FilePath(job.ID, name); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FilePath(%q) error = %v, want ErrNotFound", name, err)
+		}
+	}
+	if _, err := manager.FilePath("0123456789abcdef0123456789abcdef", PlaylistName); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FilePath() of an unknown job error = %v", err)
+	}
+
+	if path, err := manager.FilePath(job.ID, PlaylistName); err != nil || path != "" {
+		t.Errorf("FilePath() before the playlist exists = %q, %v", path, err)
+	}
+	if path, err := manager.FilePath(job.ID, "segment_00001.ts"); err != nil || path != filepath.Join(dir, "segment_00001.ts") {
+		t.Errorf("FilePath() of a written segment = %q, %v", path, err)
+	}
+}
@@ -0,0 +1,72 @@
+package hls
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusReady     = "ready"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+
+	DefaultMaxJobs = 2
+	DefaultJobTTL  = 30 * time.Minute
+	MaxJobsPerUser = 3 // queued or running
+
+	PlaylistName = "index.m3u8"
+	PathPrefix   = "/hls"
+
+	segmentDuration = 6 // seconds
+)
+
+var (
+	ErrInvalidSource = errors.New("source must be an http(s) URL, a server stream path or a file under the media directory")
+	ErrPrivateSource = errors.New("source URL must point to a public address")
+	ErrTooManyJobs   = errors.New("you already have the maximum number of HLS jobs queued or running")
+	ErrNotFound      = errors.New("job not found")
+	ErrJobInUse      = errors.New("only the room owner or the member who started the room's job can replace it")
+)
+
+// Job converts one source into an HLS playlist. Jobs started for a room
+// are shared by every member, other jobs by everyone asking for the same
+// source, so each source is only converted once.
+type Job struct {
+	ID               string     `json:"id"`
+	Source           string     `json:"source"`
+	Transcode        bool       `json:"transcode"`
+	RoomID           *int       `json:"room_id,omitempty"`
+	CreatedBy        int        `json:"created_by"`
+	Status           string     `json:"status"`
+	QueuePosition    int        `json:"queue_position,omitempty"`
+	Progress         float64    `json:"progress"` // percent, 0 while the duration is unknown
+	ProcessedSeconds float64    `json:"processed_seconds"`
+	DurationSeconds  float64    `json:"duration_seconds,omitempty"`
+	PlaylistURL      string     `json:"playlist_url"`
+	Error            string     `json:"error,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	StartedAt        *time.Time `json:"started_at,omitempty"`
+	FinishedAt       *time.Time `json:"finished_at,omitempty"`
+
+	key        string
+	input      string // Source resolved to what ffmpeg reads
+	dir        string
+	cancel     context.CancelFunc
+	lastAccess time.Time
+}
+
+func (j *Job) finished() bool {
+	return j.Status == StatusReady || j.Status == StatusFailed || j.Status == StatusCancelled
+}
+
+// StartRequest asks for a source to be converted. With RoomID set the job
+// becomes the room's, replacing any job the room had for another source
+// when the requester is allowed to.
+type StartRequest struct {
+	Source    string `json:"source" binding:"required"`
+	RoomID    *int   `json:"room_id"`
+	Transcode bool   `json:"transcode"` // re-encode to H.264/AAC instead of copying the streams
+}
@@ -0,0 +1,273 @@
+package httpcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+
+	"zync-stream/netguard"
+)
+
+const (
+	DefaultTTL         = 5 * time.Minute
+	DefaultNegativeTTL = time.Minute
+	MaxTTL             = 24 * time.Hour
+
+	keyPrefix = "httpcache:"
+
+	// bodies larger than this are neither returned nor cached
+	maxBodyBytes = 5 << 20
+
+	// a shared fetch outlives the caller that started it, up to this long
+	fetchTimeout = 20 * time.Second
+)
+
+// StatusError is returned for non-200 upstream responses, live or cached
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("upstream responded with %d", e.StatusCode)
+}
+
+// ErrUnreachable is returned, live or cached, when the upstream could not be reached
+var ErrUnreachable = errors.New("upstream is unreachable")
+
+type entry struct {
+	StatusCode int    `json:"status"`
+	Body       []byte `json:"body,omitempty"`
+}
+
+// Stats counts cache traffic since the process started
+type Stats struct {
+	Hits         uint64  `json:"hits"`
+	NegativeHits uint64  `json:"negative_hits"`
+	Misses       uint64  `json:"misses"`
+	Shared       uint64  `json:"shared"`
+	Stores       uint64  `json:"stores"`
+	RedisErrors  uint64  `json:"redis_errors"`
+	HitRatio     float64 `json:"hit_ratio"`
+}
+
+// Cache stores outbound GET responses in Redis. Concurrent requests for the
+// same URL share one upstream fetch, and failures are remembered briefly so a
+// dead addon is not hammered by every user.
+type Cache struct {
+	redis       *redis.Client
+	client      *http.Client
+	group       singleflight.Group
+	defaultTTL  time.Duration
+	negativeTTL time.Duration
+
+	hits, negativeHits, misses, shared, stores, redisErrors atomic.Uint64
+}
+
+// NewCache fetches with client. URLs come from users and addons, so outside
+// tests it should be a netguard client.
+func NewCache(redisClient *redis.Client, client *http.Client, defaultTTL, negativeTTL time.Duration) *Cache {
+	return &Cache{
+		redis:       redisClient,
+		client:      client,
+		defaultTTL:  defaultTTL,
+		negativeTTL: negativeTTL,
+	}
+}
+
+// NewCacheFromEnv reads HTTP_CACHE_TTL and HTTP_CACHE_NEGATIVE_TTL
+func NewCacheFromEnv(redisClient *redis.Client) *Cache {
+	return NewCache(redisClient, netguard.NewClient(fetchTimeout),
+		durationFromEnv("HTTP_CACHE_TTL", DefaultTTL),
+		durationFromEnv("HTTP_CACHE_NEGATIVE_TTL", DefaultNegativeTTL))
+}
+
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	if value := os.Getenv(name); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+// Get returns the body of a 200 response for url, from Redis when possible.
+// Other statuses come back as *StatusError and network failures as ErrUnreachable.
+func (c *Cache) Get(ctx context.Context, url string) ([]byte, error) {
+	key := cacheKey(url)
+
+	if cached, ok := c.lookup(ctx, key); ok {
+		if cached.StatusCode != http.StatusOK {
+			c.negativeHits.Add(1)
+		} else {
+			c.hits.Add(1)
+		}
+		return cached.result()
+	}
+
+	c.misses.Add(1)
+	result := c.group.DoChan(key, func() (interface{}, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+		defer cancel()
+		return c.fetch(fetchCtx, key, url), nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		if res.Shared {
+			c.shared.Add(1)
+		}
+		return res.Val.(*entry).result()
+	}
+}
+
+func (c *Cache) lookup(ctx context.Context, key string) (*entry, bool) {
+	if c.redis == nil {
+		return nil, false
+	}
+
+	data, err := c.redis.Get(ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			c.redisErrors.Add(1)
+		}
+		return nil, false
+	}
+
+	var cached entry
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, false
+	}
+	return &cached, true
+}
+
+func (c *Cache) fetch(ctx context.Context, key, url string) *entry {
+	fetched, ttl := c.request(ctx, url)
+	if ttl <= 0 || c.redis == nil {
+		return fetched
+	}
+
+	data, err := json.Marshal(fetched)
+	if err != nil {
+		return fetched
+	}
+	if err := c.redis.Set(ctx, key, data, ttl).Err(); err != nil {
+		c.redisErrors.Add(1)
+		log.Printf("Failed to cache %s: %v", url, err)
+		return fetched
+	}
+	c.stores.Add(1)
+
+	return fetched
+}
+
+// request performs the upstream GET and decides how long its answer may be kept
+func (c *Cache) request(ctx context.Context, url string) (*entry, time.Duration) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return &entry{}, 0
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return &entry{}, c.negativeTTL
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &entry{StatusCode: resp.StatusCode}, c.negativeTTL
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes+1))
+	if err != nil || len(body) > maxBodyBytes {
+		return &entry{}, c.negativeTTL
+	}
+
+	return &entry{StatusCode: http.StatusOK, Body: body}, c.freshness(resp.Header.Get("Cache-Control"), body)
+}
+
+// freshness prefers a Stremio cacheMaxAge in the body, then Cache-Control
+// max-age, then the default. no-store and no-cache disable caching.
+func (c *Cache) freshness(cacheControl string, body []byte) time.Duration {
+	var stremio struct {
+		CacheMaxAge *int64 `json:"cacheMaxAge"`
+	}
+	if json.Unmarshal(body, &stremio) == nil && stremio.CacheMaxAge != nil {
+		return clampTTL(time.Duration(*stremio.CacheMaxAge) * time.Second)
+	}
+
+	maxAge := time.Duration(-1)
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(strings.ToLower(directive)), "=")
+		switch name {
+		case "no-store", "no-cache":
+			return 0
+		case "max-age", "s-maxage":
+			if seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64); err == nil {
+				// s-maxage is meant for shared caches like this one and wins
+				if name == "s-maxage" || maxAge < 0 {
+					maxAge = time.Duration(seconds) * time.Second
+				}
+			}
+		}
+	}
+	if maxAge >= 0 {
+		return clampTTL(maxAge)
+	}
+
+	return c.defaultTTL
+}
+
+func clampTTL(ttl time.Duration) time.Duration {
+	if ttl < 0 {
+		return 0
+	}
+	return min(ttl, MaxTTL)
+}
+
+func (e *entry) result() ([]byte, error) {
+	switch e.StatusCode {
+	case http.StatusOK:
+		return e.Body, nil
+	case 0:
+		return nil, ErrUnreachable
+	default:
+		return nil, &StatusError{StatusCode: e.StatusCode}
+	}
+}
+
+func (c *Cache) Stats() Stats {
+	stats := Stats{
+		Hits:         c.hits.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Misses:       c.misses.Load(),
+		Shared:       c.shared.Load(),
+		Stores:       c.stores.Load(),
+		RedisErrors:  c.redisErrors.Load(),
+	}
+	if total := stats.Hits + stats.NegativeHits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits+stats.NegativeHits) / float64(total)
+	}
+	return stats
+}
+
+func cacheKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return keyPrefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,231 @@
+package httpcache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestCache(t *testing.T) (*Cache, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewCache(client, http.DefaultClient, DefaultTTL, 30*time.Second), mr
+}
+
+// origin counts requests and answers each path with a fixed response
+func origin(t *testing.T, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/shared":
+			w.Header().Set("Cache-Control", "public, s-maxage=120, max-age=10")
+			w.Write([]byte(`{"metas":[]}`))
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFreshness(t *testing.T) {
+	cache := NewCache(nil, http.DefaultClient, DefaultTTL, DefaultNegativeTTL)
+
+	tests := []struct {
+		name         string
+		cacheControl string
+		body         string
+		want         time.Duration
+	}{
+		{"default", "", `{}`, DefaultTTL},
+		{"max-age", "public, max-age=60", `{}`, time.Minute},
+		{"s-maxage wins after max-age", "max-age=60, s-maxage=600", `{}`, 10 * time.Minute},
+		{"s-maxage wins before max-age", "s-maxage=600, max-age=60", `{}`, 10 * time.Minute},
+		{"quoted and upper case", `MAX-AGE="90"`, `{}`, 90 * time.Second},
+		{"no-store", "no-store, max-age=60", `{}`, 0},
+		{"no-cache", "max-age=60, no-cache", `{}`, 0},
+		{"malformed max-age", "max-age=soon", `{}`, DefaultTTL},
+		{"capped", "max-age=31536000", `{}`, MaxTTL},
+		{"stremio cacheMaxAge beats headers", "max-age=60", `{"streams":[],"cacheMaxAge":3600}`, time.Hour},
+		{"negative cacheMaxAge", "", `{"cacheMaxAge":-5}`, 0},
+		{"non-json body", "max-age=30", `WEBVTT`, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cache.freshness(tt.cacheControl, []byte(tt.body)); got != tt.want {
+				t.Errorf("freshness(%q, %s) = %v, want %v", tt.cacheControl, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetStoresForSharedMaxAge(t *testing.T) {
+	var hits atomic.Int32
+	server := origin(t, &hits)
+	cache, mr := newTestCache(t)
+	ctx := context.Background()
+	url := server.URL + "/shared"
+
+	for i := 0; i < 2; i++ {
+		body, err := cache.Get(ctx, url)
+		if err != nil || string(body) != `{"metas":[]}` {
+			t.Fatalf("Get() = %s, %v", body, err)
+		}
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("origin hit %d times, want 1", hits.Load())
+	}
+	if ttl := mr.TTL(cacheKey(url)); ttl != 120*time.Second {
+		t.Errorf("stored TTL = %v, want the s-maxage", ttl)
+	}
+
+	mr.FastForward(121 * time.Second)
+	if _, err := cache.Get(ctx, url); err != nil {
+		t.Fatalf("Get() after expiry error = %v", err)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("origin hit %d times after expiry, want 2", hits.Load())
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Stores != 2 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestGetRemembersFailures(t *testing.T) {
+	var hits atomic.Int32
+	server := origin(t, &hits)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	cache, mr := newTestCache(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := cache.Get(ctx, server.URL+"/down")
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("Get() error = %v, want a 503 StatusError", err)
+		}
+	}
+	if hits.Load() != 1 {
+		t.Errorf("origin hit %d times, want the failure served from cache", hits.Load())
+	}
+	if ttl := mr.TTL(cacheKey(server.URL + "/down")); ttl != 30*time.Second {
+		t.Errorf("negative TTL = %v, want 30s", ttl)
+	}
+
+	if _, err := cache.Get(ctx, closed.URL); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("Get() of a closed server error = %v, want ErrUnreachable", err)
+	}
+	if _, err := cache.Get(ctx, closed.URL); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("cached Get() of a closed server error = %v, want ErrUnreachable", err)
+	}
+	if stats := cache.Stats(); stats.NegativeHits != 2 {
+		t.Errorf("NegativeHits = %d, want 2", stats.NegativeHits)
+	}
+
+	mr.FastForward(31 * time.Second)
+	if _, err := cache.Get(ctx, server.URL+"/down"); err == nil {
+		t.Fatal("Get() after the negative TTL succeeded")
+	}
+	if hits.Load() != 2 {
+		t.Errorf("origin hit %d times after the negative TTL, want 2", hits.Load())
+	}
+}
+
+func TestGetSharesConcurrentFetches(t *testing.T) {
+	const callers = 8
+
+	var hits atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		w.Write([]byte(`{"streams":[]}`))
+	}))
+	defer server.Close()
+
+	cache := NewCache(nil, http.DefaultClient, DefaultTTL, DefaultNegativeTTL)
+
+	var wg sync.WaitGroup
+	bodies := make([]string, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, err := cache.Get(context.Background(), server.URL+"/stream/movie/tt1.json")
+			if err != nil {
+				t.Errorf("Get() error = %v", err)
+			}
+			bodies[i] = string(body)
+		}()
+	}
+
+	// hold the origin until every caller has missed and joined the fetch
+	deadline := time.Now().Add(5 * time.Second)
+	for cache.Stats().Misses < callers && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if hits.Load() != 1 {
+		t.Errorf("origin hit %d times, want 1", hits.Load())
+	}
+	for i, body := range bodies {
+		if body != `{"streams":[]}` {
+			t.Errorf("caller %d body = %q", i, body)
+		}
+	}
+	if stats := cache.Stats(); stats.Shared != callers {
+		t.Errorf("Shared = %d, want %d", stats.Shared, callers)
+	}
+}
+
+func TestGetCallerCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cache := NewCache(nil, http.DefaultClient, DefaultTTL, DefaultNegativeTTL)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := cache.Get(ctx, server.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() error = %v, want the caller's deadline", err)
+	}
+}
+
+func TestFromEnvRefusesInternalOrigins(t *testing.T) {
+	var hits atomic.Int32
+	server := origin(t, &hits)
+
+	cache := NewCacheFromEnv(nil)
+	if _, err := cache.Get(context.Background(), server.URL); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("Get() of a loopback origin error = %v, want ErrUnreachable", err)
+	}
+	if hits.Load() != 0 {
+		t.Error("the default cache client reached a loopback origin")
+	}
+}
@@ -0,0 +1,101 @@
+package imports
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"zync-stream/users"
+)
+
+const exportPageSize = 500
+
+// eachHistoryEntry pages through a user's complete watch history
+func eachHistoryEntry(ctx context.Context, repo *users.UserRepo, userID int, fn func(*users.WatchHistoryEntry) error) error {
+	filter := users.WatchHistoryFilter{Limit: exportPageSize}
+	for {
+		entries, err := repo.GetWatchHistory(ctx, userID, filter)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+		if len(entries) < exportPageSize {
+			return nil
+		}
+		filter.BeforeID = entries[len(entries)-1].ID
+	}
+}
+
+type traktExportItem struct {
+	WatchedAt time.Time     `json:"watched_at"`
+	Action    string        `json:"action"`
+	Type      string        `json:"type"`
+	Movie     *traktMedia   `json:"movie,omitempty"`
+	Show      *traktMedia   `json:"show,omitempty"`
+	Episode   *traktEpisode `json:"episode,omitempty"`
+}
+
+// WriteTrakt writes the history in the layout of Trakt's watched-history.json,
+// which ParseTrakt reads back
+func WriteTrakt(ctx context.Context, w io.Writer, repo *users.UserRepo, userID int) error {
+	items := []traktExportItem{}
+	err := eachHistoryEntry(ctx, repo, userID, func(entry *users.WatchHistoryEntry) error {
+		media := &traktMedia{IDs: traktIDs{Imdb: entry.ImdbID}}
+		item := traktExportItem{WatchedAt: entry.LastWatched.UTC(), Action: "watch"}
+
+		if entry.MediaType == "series" && entry.SeasonNumber != nil && entry.EpisodeNumber != nil &&
+			*entry.EpisodeNumber > 0 {
+			item.Type = "episode"
+			item.Show = media
+			item.Episode = &traktEpisode{Season: *entry.SeasonNumber, Number: *entry.EpisodeNumber}
+		} else {
+			item.Type = "movie"
+			item.Movie = media
+		}
+
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(items)
+}
+
+// WriteLetterboxd writes watched movies as a CSV in Letterboxd's import format.
+// Letterboxd only tracks films, so series are left out.
+func WriteLetterboxd(ctx context.Context, w io.Writer, repo *users.UserRepo, ratings map[string]int, userID int) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"imdbID", "WatchedDate", "Rating10"}); err != nil {
+		return err
+	}
+
+	err := eachHistoryEntry(ctx, repo, userID, func(entry *users.WatchHistoryEntry) error {
+		if entry.MediaType != "movie" {
+			return nil
+		}
+
+		rating := ""
+		if value, ok := ratings[entry.ImdbID]; ok {
+			rating = strconv.Itoa(value)
+		}
+
+		return writer.Write([]string{entry.ImdbID, entry.LastWatched.Format(time.DateOnly), rating})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,195 @@
+package imports
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"zync-stream/users"
+)
+
+type ImportHandlers struct {
+	repo     *ImportRepository
+	userRepo *users.UserRepo
+	runner   *Runner
+}
+
+func NewImportHandlers(repo *ImportRepository, userRepo *users.UserRepo, runner *Runner) *ImportHandlers {
+	return &ImportHandlers{
+		repo:     repo,
+		userRepo: userRepo,
+		runner:   runner,
+	}
+}
+
+func (h *ImportHandlers) ImportTrakt(c *gin.Context) {
+	h.startImport(c, SourceTrakt, func(data []byte, _ string) ([]Record, error) {
+		return ParseTrakt(data)
+	})
+}
+
+func (h *ImportHandlers) ImportLetterboxd(c *gin.Context) {
+	h.startImport(c, SourceLetterboxd, ParseLetterboxd)
+}
+
+// startImport parses the uploaded "file" up front, so malformed exports are
+// rejected immediately, then hands the records to the background runner
+func (h *ImportHandlers) startImport(c *gin.Context, source string, parse func([]byte, string) ([]Record, error)) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxUploadBytes+1<<20)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload the export as the \"file\" form field"})
+		return
+	}
+	if fileHeader.Size > MaxUploadBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Export file is too large"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload"})
+		return
+	}
+	defer file.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(file, MaxUploadBytes)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload"})
+		return
+	}
+
+	records, err := parse(buf.Bytes(), fileHeader.Filename)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(records) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No importable entries found"})
+		return
+	}
+	if len(records) > MaxRecordsPerJob {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Exports are limited to %d entries", MaxRecordsPerJob)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	active, err := h.repo.HasActiveJob(ctx, userID.(int))
+	if err != nil {
+		log.Printf("Error checking import jobs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start import"})
+		return
+	}
+	if active {
+		c.JSON(http.StatusConflict, gin.H{"error": "An import is already in progress"})
+		return
+	}
+
+	job, err := h.repo.CreateJob(ctx, userID.(int), source, len(records))
+	if err != nil {
+		log.Printf("Error creating import job: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start import"})
+		return
+	}
+
+	h.runner.Start(job, records)
+
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
+}
+
+func (h *ImportHandlers) GetJobs(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	jobs, err := h.repo.ListJobs(c.Request.Context(), userID.(int), 20)
+	if err != nil {
+		log.Printf("Error listing import jobs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve imports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+func (h *ImportHandlers) GetJob(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	jobID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	job, err := h.repo.GetJob(c.Request.Context(), jobID)
+	if err != nil {
+		log.Printf("Error loading import job %d: %v", jobID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve import"})
+		return
+	}
+	if job == nil || job.UserID != userID.(int) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job, "progress": job.Progress()})
+}
+
+// ExportWatchHistory downloads the history as Trakt JSON or Letterboxd CSV
+func (h *ImportHandlers) ExportWatchHistory(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	date := time.Now().Format("2006-01-02")
+
+	var buf bytes.Buffer
+	switch format := c.DefaultQuery("format", SourceTrakt); format {
+	case SourceTrakt:
+		if err := WriteTrakt(ctx, &buf, h.userRepo, userID.(int)); err != nil {
+			log.Printf("Error exporting Trakt history for user %d: %v", userID.(int), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export watch history"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="zync-watched-history-%s.json"`, date))
+		c.Data(http.StatusOK, "application/json", buf.Bytes())
+
+	case SourceLetterboxd:
+		ratings, err := h.repo.UserRatings(ctx, userID.(int))
+		if err == nil {
+			err = WriteLetterboxd(ctx, &buf, h.userRepo, ratings, userID.(int))
+		}
+		if err != nil {
+			log.Printf("Error exporting Letterboxd history for user %d: %v", userID.(int), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export watch history"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="zync-letterboxd-%s.csv"`, date))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be trakt or letterboxd"})
+	}
+}
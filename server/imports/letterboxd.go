@@ -0,0 +1,164 @@
+package imports
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseLetterboxd reads a Letterboxd export zip or one of its CSV files.
+// filename decides what a lone CSV holds (watchlist.csv, ratings.csv, ...).
+func ParseLetterboxd(data []byte, filename string) ([]Record, error) {
+	if !isZip(data) {
+		return parseLetterboxdCSV(bytes.NewReader(data), path.Base(filename))
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	var records []Record
+	for _, file := range reader.File {
+		name := path.Base(file.Name)
+		// top-level files only; deleted/ and orphaned/ hold stale entries
+		if strings.Contains(file.Name, "/") || letterboxdKind(name) == "" {
+			continue
+		}
+		content, err := readZipFile(file)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := parseLetterboxdCSV(bytes.NewReader(content), name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Name, err)
+		}
+		records = append(records, parsed...)
+	}
+
+	return records, nil
+}
+
+func letterboxdKind(filename string) string {
+	switch strings.ToLower(filename) {
+	case "watched.csv", "diary.csv":
+		return KindHistory
+	case "ratings.csv":
+		return KindRating
+	case "watchlist.csv":
+		return KindWatchlist
+	}
+	return ""
+}
+
+func parseLetterboxdCSV(r io.Reader, filename string) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	field := func(row []string, names ...string) string {
+		for _, name := range names {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+		}
+		return ""
+	}
+
+	if _, hasName := columns["name"]; !hasName {
+		if _, hasTitle := columns["title"]; !hasTitle {
+			if _, hasImdb := columns["imdbid"]; !hasImdb {
+				return nil, fmt.Errorf("CSV has no Name, Title or imdbID column")
+			}
+		}
+	}
+
+	kind := letterboxdKind(filename)
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		base := Record{
+			ImdbID:    field(row, "imdbid"),
+			MediaType: "movie",
+			Title:     field(row, "name", "title"),
+			At:        parseLetterboxdDate(field(row, "watched date", "watcheddate", "date")),
+		}
+		base.Year, _ = strconv.Atoi(field(row, "year"))
+		if base.ImdbID == "" && base.Title == "" {
+			continue
+		}
+
+		rating := parseLetterboxdRating(field(row, "rating"), field(row, "rating10"))
+
+		switch kind {
+		case KindWatchlist:
+			base.Kind = KindWatchlist
+			records = append(records, base)
+		case KindRating:
+			if rating > 0 {
+				base.Kind = KindRating
+				base.Rating = rating
+				records = append(records, base)
+			}
+		default:
+			// diary.csv, watched.csv and third-party files in Letterboxd's import format
+			history := base
+			history.Kind = KindHistory
+			records = append(records, history)
+			if rating > 0 {
+				rated := base
+				rated.Kind = KindRating
+				rated.Rating = rating
+				records = append(records, rated)
+			}
+		}
+	}
+
+	return records, nil
+}
+
+// parseLetterboxdRating maps 0.5-5 stars (or a 1-10 Rating10) onto 1-10
+func parseLetterboxdRating(stars, rating10 string) int {
+	if value, err := strconv.ParseFloat(rating10, 64); err == nil && value > 0 {
+		return clampRating(int(math.Round(value)))
+	}
+	if value, err := strconv.ParseFloat(stars, 64); err == nil && value > 0 {
+		return clampRating(int(math.Round(value * 2)))
+	}
+	return 0
+}
+
+func clampRating(rating int) int {
+	return max(1, min(10, rating))
+}
+
+func parseLetterboxdDate(value string) time.Time {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t
+	}
+	return time.Now()
+}
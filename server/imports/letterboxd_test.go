@@ -0,0 +1,164 @@
+package imports
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixtures follow the shape of Letterboxd's export
+const (
+	letterboxdDiary = "Date,Name,Year,Letterboxd URI,Rating,Rewatch,Tags,Watched Date\n" +
+		"2024-01-10,Heat,1995,https://boxd.it/2a8s,4.5,,,2024-01-09\n" +
+		"2024-01-11,\"Crouching Tiger, Hidden Dragon\",2000,https://boxd.it/1Z8k,,Yes,\"wuxia, rewatch\",2024-01-11\n" +
+		"2024-01-12,,,https://boxd.it/29kA,3,,,2024-01-12\n" +
+		"2024-01-13,Ran,1985\n"
+
+	letterboxdRatings = "Date,Name,Year,Letterboxd URI,Rating\n" +
+		"2023-05-01,Heat,1995,https://boxd.it/2a8s,0.5\n" +
+		"2023-05-02,Ran,1985,https://boxd.it/1RrA,\n"
+
+	letterboxdWatchlist = "\ufeffDate,Name,Year,Letterboxd URI\n" +
+		"2023-06-01,Perfect Days,2023,https://boxd.it/ddb4\n"
+
+	// the import format other services export to Letterboxd
+	letterboxdThirdParty = "imdbID,Title,Year,Rating10,WatchedDate\n" +
+		"tt0113277,Heat,1995,8,2022-11-05\n" +
+		"tt0089881,,,,\n"
+)
+
+func TestParseLetterboxd(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		input    string
+		want     string
+	}{
+		{
+			name:     "diary with ratings, a row without a title and a short row",
+			filename: "diary.csv",
+			input:    letterboxdDiary,
+			want: "history movie  Heat\nrating movie  Heat rated 9\n" +
+				"history movie  Crouching Tiger, Hidden Dragon\nhistory movie  Ran",
+		},
+		{
+			name:     "unrated ratings rows are dropped",
+			filename: "ratings.csv",
+			input:    letterboxdRatings,
+			want:     "rating movie  Heat rated 1",
+		},
+		{
+			name:     "watchlist with a byte order mark",
+			filename: "export/watchlist.csv",
+			input:    letterboxdWatchlist,
+			want:     "watchlist movie  Perfect Days",
+		},
+		{
+			name:     "third-party import format",
+			filename: "from-another-app.csv",
+			input:    letterboxdThirdParty,
+			want:     "history movie tt0113277 Heat\nrating movie tt0113277 Heat rated 8\nhistory movie tt0089881 ",
+		},
+		{
+			name:     "windows line endings",
+			filename: "watched.csv",
+			input:    "Date,Name,Year,Letterboxd URI\r\n2024-01-01,Heat,1995,https://boxd.it/2a8s\r\n",
+			want:     "history movie  Heat",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ParseLetterboxd([]byte(tt.input), tt.filename)
+			if err != nil {
+				t.Fatalf("ParseLetterboxd() error = %v", err)
+			}
+			if got := recordSummary(records); got != tt.want {
+				t.Errorf("ParseLetterboxd() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLetterboxdFields(t *testing.T) {
+	records, err := ParseLetterboxd([]byte(letterboxdDiary), "diary.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	heat := records[0]
+	if heat.Year != 1995 || !heat.At.Equal(time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Heat = year %d, watched %v; want the watched date, not the diary date", heat.Year, heat.At)
+	}
+	if ran := records[3]; ran.Year != 1985 || ran.At.IsZero() {
+		t.Errorf("short row = %+v", ran)
+	}
+}
+
+func TestParseLetterboxdZip(t *testing.T) {
+	archive := zipOf(t, map[string]string{
+		"diary.csv":           letterboxdDiary,
+		"watchlist.csv":       letterboxdWatchlist,
+		"profile.csv":         "Date Joined,Username\n2020-01-01,someone\n",
+		"deleted/diary.csv":   letterboxdDiary,
+		"orphaned/diary.csv":  letterboxdDiary,
+		"lists/favorites.csv": "not,read\n",
+	})
+
+	records, err := ParseLetterboxd(archive, "letterboxd-someone-2024.zip")
+	if err != nil {
+		t.Fatalf("ParseLetterboxd() error = %v", err)
+	}
+
+	counts := map[string]int{}
+	for _, record := range records {
+		counts[record.Kind]++
+	}
+	if counts[KindHistory] != 3 || counts[KindRating] != 1 || counts[KindWatchlist] != 1 {
+		t.Errorf("ParseLetterboxd() kinds = %v", counts)
+	}
+}
+
+func TestParseLetterboxdRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"missing title columns", "Date,Year,Rating\n2024-01-01,1995,4\n", "no Name, Title or imdbID column"},
+		{"empty file", "", "invalid CSV"},
+		{"unterminated quote", "Date,Name,Year\n2024-01-01,\"Heat,1995\n", "invalid CSV"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseLetterboxd([]byte(tt.input), "diary.csv")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseLetterboxd() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLetterboxdRating(t *testing.T) {
+	tests := []struct {
+		stars, rating10 string
+		want            int
+	}{
+		{"0.5", "", 1},
+		{"2.5", "", 5},
+		{"5", "", 10},
+		{"", "7", 7},
+		{"3", "7.6", 8},
+		{"", "12", 10},
+		{"", "", 0},
+		{"★★★", "", 0},
+		{"-1", "0", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseLetterboxdRating(tt.stars, tt.rating10); got != tt.want {
+			t.Errorf("parseLetterboxdRating(%q, %q) = %d, want %d", tt.stars, tt.rating10, got, tt.want)
+		}
+	}
+}
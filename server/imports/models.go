@@ -0,0 +1,69 @@
+package imports
+
+import "time"
+
+const (
+	SourceTrakt      = "trakt"
+	SourceLetterboxd = "letterboxd"
+
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+
+	KindHistory   = "history"
+	KindRating    = "rating"
+	KindWatchlist = "watchlist"
+
+	MaxUploadBytes    = 50 << 20
+	MaxRecordsPerJob  = 100000
+	MaxJobErrors      = 20
+	MaxConcurrentJobs = 2
+	progressInterval  = 50
+)
+
+type Job struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Source     string     `json:"source"`
+	Status     string     `json:"status"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Imported   int        `json:"imported"`
+	Skipped    int        `json:"skipped"`
+	Failed     int        `json:"failed"`
+	Errors     []string   `json:"errors"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Progress is the percentage of records processed so far
+func (j *Job) Progress() float64 {
+	if j.Total == 0 {
+		return 100
+	}
+	return float64(j.Processed) / float64(j.Total) * 100
+}
+
+// Record is one parsed history entry, rating or watchlist item. Titles
+// without an imdb id are resolved by name and year before import.
+type Record struct {
+	Kind          string
+	ImdbID        string
+	MediaType     string // "movie" or "series"
+	SeasonNumber  *int
+	EpisodeNumber *int
+	Title         string
+	Year          int
+	At            time.Time
+	Rating        int // 1-10
+}
+
+// Label identifies a record in job error messages
+func (r *Record) Label() string {
+	if r.Title != "" {
+		return r.Title
+	}
+	return r.ImdbID
+}
@@ -0,0 +1,230 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"zync-stream/watchlist"
+)
+
+// ImportRepository tracks import jobs and writes imported records
+type ImportRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewImportRepository creates a new ImportRepository
+func NewImportRepository(db *pgxpool.Pool) *ImportRepository {
+	return &ImportRepository{db: db}
+}
+
+const jobColumns = `
+        id, user_id, source, status, total, processed, imported, skipped, failed,
+        errors, created_at, started_at, finished_at
+    `
+
+func scanJob(row pgx.Row) (*Job, error) {
+	var job Job
+	err := row.Scan(&job.ID, &job.UserID, &job.Source, &job.Status, &job.Total, &job.Processed,
+		&job.Imported, &job.Skipped, &job.Failed, &job.Errors, &job.CreatedAt, &job.StartedAt, &job.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *ImportRepository) CreateJob(ctx context.Context, userID int, source string, total int) (*Job, error) {
+	query := `
+        INSERT INTO import_jobs (user_id, source, total)
+        VALUES ($1, $2, $3)
+        RETURNING ` + jobColumns
+
+	job, err := scanJob(r.db.QueryRow(ctx, query, userID, source, total))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import job: %w", err)
+	}
+	return job, nil
+}
+
+func (r *ImportRepository) GetJob(ctx context.Context, id int) (*Job, error) {
+	job, err := scanJob(r.db.QueryRow(ctx, `SELECT `+jobColumns+` FROM import_jobs WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get import job: %w", err)
+	}
+	return job, nil
+}
+
+func (r *ImportRepository) ListJobs(ctx context.Context, userID, limit int) ([]*Job, error) {
+	query := `SELECT ` + jobColumns + `
+        FROM import_jobs
+        WHERE user_id = $1
+        ORDER BY created_at DESC
+        LIMIT $2
+    `
+
+	rows, err := r.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query import jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan import job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// HasActiveJob reports whether the user already has an import queued or running
+func (r *ImportRepository) HasActiveJob(ctx context.Context, userID int) (bool, error) {
+	var active bool
+	err := r.db.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1 FROM import_jobs
+            WHERE user_id = $1 AND status IN ('pending', 'running')
+        )
+    `, userID).Scan(&active)
+	if err != nil {
+		return false, fmt.Errorf("failed to check import jobs: %w", err)
+	}
+	return active, nil
+}
+
+func (r *ImportRepository) MarkRunning(ctx context.Context, id int) error {
+	_, err := r.db.Exec(ctx, `UPDATE import_jobs SET status = 'running', started_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+func (r *ImportRepository) UpdateProgress(ctx context.Context, job *Job) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE import_jobs
+        SET processed = $2, imported = $3, skipped = $4, failed = $5, errors = $6
+        WHERE id = $1
+    `, job.ID, job.Processed, job.Imported, job.Skipped, job.Failed, job.Errors)
+	return err
+}
+
+func (r *ImportRepository) Finish(ctx context.Context, job *Job, status string) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE import_jobs
+        SET status = $2, processed = $3, imported = $4, skipped = $5, failed = $6,
+            errors = $7, finished_at = NOW()
+        WHERE id = $1
+    `, job.ID, status, job.Processed, job.Imported, job.Skipped, job.Failed, job.Errors)
+	return err
+}
+
+// FailInterrupted closes jobs left behind by a previous process
+func (r *ImportRepository) FailInterrupted(ctx context.Context) (int64, error) {
+	tag, err := r.db.Exec(ctx, `
+        UPDATE import_jobs
+        SET status = 'failed', finished_at = NOW(),
+            errors = array_append(errors, 'interrupted by a server restart')
+        WHERE status IN ('pending', 'running')
+    `)
+	if err != nil {
+		return 0, fmt.Errorf("failed to close interrupted import jobs: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ImportHistory records a finished viewing. Existing entries are only
+// touched when the imported viewing is more recent; returns false for duplicates.
+func (r *ImportRepository) ImportHistory(ctx context.Context, userID int, record *Record) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+        INSERT INTO watch_history
+        (user_id, imdb_id, media_type, season_number, episode_number,
+        timestamp_seconds, percentage_watched, last_watched)
+        VALUES ($1, $2, $3, COALESCE($4, 0), COALESCE($5, 0), 0, 100, $6)
+        ON CONFLICT (user_id, imdb_id, season_number, episode_number)
+        DO UPDATE SET
+            percentage_watched = 100,
+            last_watched = EXCLUDED.last_watched
+        WHERE watch_history.last_watched < EXCLUDED.last_watched
+    `, userID, record.ImdbID, record.MediaType, record.SeasonNumber, record.EpisodeNumber, record.At)
+	if err != nil {
+		return false, fmt.Errorf("failed to import history entry: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ImportRating keeps any rating the user already gave on Zync
+func (r *ImportRepository) ImportRating(ctx context.Context, userID int, record *Record) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+        INSERT INTO title_reviews (user_id, imdb_id, media_type, rating, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $5)
+        ON CONFLICT (user_id, imdb_id) DO NOTHING
+    `, userID, record.ImdbID, record.MediaType, record.Rating, record.At)
+	if err != nil {
+		return false, fmt.Errorf("failed to import rating: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DefaultWatchlistID returns the user's default list, creating it if needed
+func (r *ImportRepository) DefaultWatchlistID(ctx context.Context, userID int) (int, error) {
+	if _, err := r.db.Exec(ctx, `
+        INSERT INTO watchlists (user_id, name, is_default)
+        VALUES ($1, $2, true)
+        ON CONFLICT DO NOTHING
+    `, userID, watchlist.DefaultListName); err != nil {
+		return 0, fmt.Errorf("failed to create default watchlist: %w", err)
+	}
+
+	var listID int
+	err := r.db.QueryRow(ctx, `
+        SELECT id FROM watchlists
+        WHERE user_id = $1 AND (is_default OR name = $2)
+        ORDER BY is_default DESC
+        LIMIT 1
+    `, userID, watchlist.DefaultListName).Scan(&listID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find default watchlist: %w", err)
+	}
+	return listID, nil
+}
+
+func (r *ImportRepository) ImportWatchlistItem(ctx context.Context, userID, listID int, record *Record) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+        INSERT INTO watchlist_items (list_id, user_id, imdb_id, media_type, added_at, position)
+        VALUES ($1, $2, $3, $4, $5,
+            (SELECT COALESCE(MAX(position), -1) + 1 FROM watchlist_items WHERE list_id = $1))
+        ON CONFLICT (list_id, imdb_id, media_type) DO NOTHING
+    `, listID, userID, record.ImdbID, record.MediaType, record.At)
+	if err != nil {
+		return false, fmt.Errorf("failed to import watchlist item: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// UserRatings maps imdb ids to the user's rating, for exports
+func (r *ImportRepository) UserRatings(ctx context.Context, userID int) (map[string]int, error) {
+	rows, err := r.db.Query(ctx, `SELECT imdb_id, rating FROM title_reviews WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ratings: %w", err)
+	}
+	defer rows.Close()
+
+	ratings := make(map[string]int)
+	for rows.Next() {
+		var imdbID string
+		var rating int
+		if err := rows.Scan(&imdbID, &rating); err != nil {
+			return nil, fmt.Errorf("failed to scan rating: %w", err)
+		}
+		ratings[imdbID] = rating
+	}
+
+	return ratings, rows.Err()
+}
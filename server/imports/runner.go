@@ -0,0 +1,146 @@
+package imports
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"zync-stream/metadata"
+)
+
+// Runner processes import jobs in the background, a few at a time
+type Runner struct {
+	ctx      context.Context
+	repo     *ImportRepository
+	metadata metadata.Provider
+	slots    chan struct{}
+}
+
+func NewRunner(ctx context.Context, repo *ImportRepository, meta metadata.Provider) *Runner {
+	return &Runner{
+		ctx:      ctx,
+		repo:     repo,
+		metadata: meta,
+		slots:    make(chan struct{}, MaxConcurrentJobs),
+	}
+}
+
+// Start queues a job; it runs once a slot is free
+func (r *Runner) Start(job *Job, records []Record) {
+	go func() {
+		select {
+		case r.slots <- struct{}{}:
+		case <-r.ctx.Done():
+			return
+		}
+		defer func() { <-r.slots }()
+
+		r.run(job, records)
+	}()
+}
+
+func (r *Runner) run(job *Job, records []Record) {
+	ctx := r.ctx
+	if job.Errors == nil {
+		job.Errors = []string{}
+	}
+
+	if err := r.repo.MarkRunning(ctx, job.ID); err != nil {
+		log.Printf("Failed to start import job %d: %v", job.ID, err)
+		return
+	}
+
+	log.Printf("📥 Import job %d (%s) started for user %d: %d records", job.ID, job.Source, job.UserID, len(records))
+
+	listID := 0
+	for i := range records {
+		if ctx.Err() != nil {
+			break
+		}
+
+		record := &records[i]
+		imported, err := r.apply(ctx, job.UserID, record, &listID)
+		job.Processed++
+		switch {
+		case err != nil:
+			job.Failed++
+			if len(job.Errors) < MaxJobErrors {
+				job.Errors = append(job.Errors, fmt.Sprintf("%s: %v", record.Label(), err))
+			}
+		case imported:
+			job.Imported++
+		default:
+			job.Skipped++
+		}
+
+		if job.Processed%progressInterval == 0 {
+			if err := r.repo.UpdateProgress(ctx, job); err != nil {
+				log.Printf("Failed to update import job %d progress: %v", job.ID, err)
+			}
+		}
+	}
+
+	status := JobCompleted
+	if ctx.Err() != nil {
+		status = JobFailed
+		job.Errors = append(job.Errors, "interrupted by server shutdown")
+	}
+
+	// the job context may already be cancelled, record the outcome regardless
+	finishCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.repo.Finish(finishCtx, job, status); err != nil {
+		log.Printf("Failed to finish import job %d: %v", job.ID, err)
+	}
+
+	log.Printf("📥 Import job %d %s: %d imported, %d skipped, %d failed",
+		job.ID, status, job.Imported, job.Skipped, job.Failed)
+}
+
+func (r *Runner) apply(ctx context.Context, userID int, record *Record, listID *int) (bool, error) {
+	if record.ImdbID == "" {
+		if err := r.resolve(ctx, record); err != nil {
+			return false, err
+		}
+	}
+
+	switch record.Kind {
+	case KindHistory:
+		return r.repo.ImportHistory(ctx, userID, record)
+	case KindRating:
+		return r.repo.ImportRating(ctx, userID, record)
+	case KindWatchlist:
+		if *listID == 0 {
+			id, err := r.repo.DefaultWatchlistID(ctx, userID)
+			if err != nil {
+				return false, err
+			}
+			*listID = id
+		}
+		return r.repo.ImportWatchlistItem(ctx, userID, *listID, record)
+	}
+
+	return false, fmt.Errorf("unknown record kind %q", record.Kind)
+}
+
+// resolve finds the imdb id of a record that only carries a title and year
+func (r *Runner) resolve(ctx context.Context, record *Record) error {
+	if r.metadata == nil || strings.TrimSpace(record.Title) == "" {
+		return fmt.Errorf("no imdb id")
+	}
+
+	results, err := r.metadata.Search(ctx, record.MediaType, record.Title)
+	if err != nil {
+		return fmt.Errorf("title lookup failed: %w", err)
+	}
+
+	match := metadata.MatchYear(results, record.Year)
+	if match == nil {
+		return fmt.Errorf("title not found")
+	}
+
+	record.ImdbID = match.ID
+	return nil
+}
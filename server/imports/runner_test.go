@@ -0,0 +1,71 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"zync-stream/metadata"
+)
+
+type fakeProvider map[string][]metadata.Meta
+
+func (p fakeProvider) Meta(ctx context.Context, mediaType, imdbID string) (*metadata.Meta, error) {
+	return nil, metadata.ErrNotFound
+}
+
+func (p fakeProvider) Search(ctx context.Context, mediaType, query string) ([]metadata.Meta, error) {
+	if query == "outage" {
+		return nil, errors.New("cinemeta is down")
+	}
+	return p[mediaType+":"+strings.ToLower(query)], nil
+}
+
+func TestResolveTitle(t *testing.T) {
+	runner := &Runner{metadata: fakeProvider{
+		"movie:heat": {
+			{ID: "tt0113277", Name: "Heat", ReleaseInfo: "1995"},
+			{ID: "tt0096258", Name: "Heat", ReleaseInfo: "1986"},
+		},
+		"series:the office": {
+			{ID: "tt0386676", Name: "The Office", ReleaseInfo: "2005-2013"},
+			{ID: "tt0290978", Name: "The Office", ReleaseInfo: "2001-2003"},
+		},
+	}}
+
+	tests := []struct {
+		name    string
+		record  Record
+		want    string
+		wantErr string
+	}{
+		{"year picks the remake", Record{MediaType: "movie", Title: "Heat", Year: 1986}, "tt0096258", ""},
+		{"unknown year takes the first result", Record{MediaType: "movie", Title: "Heat"}, "tt0113277", ""},
+		{"unmatched year takes the first result", Record{MediaType: "movie", Title: "Heat", Year: 2020}, "tt0113277", ""},
+		{"series years are ranges", Record{MediaType: "series", Title: "The Office", Year: 2001}, "tt0290978", ""},
+		{"type must match", Record{MediaType: "series", Title: "Heat"}, "", "title not found"},
+		{"blank title", Record{MediaType: "movie", Title: "  "}, "", "no imdb id"},
+		{"lookup failure", Record{MediaType: "movie", Title: "outage"}, "", "title lookup failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := tt.record
+			err := runner.resolve(context.Background(), &record)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("resolve() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || record.ImdbID != tt.want {
+				t.Errorf("resolve() = %q, %v, want %q", record.ImdbID, err, tt.want)
+			}
+		})
+	}
+
+	if err := (&Runner{}).resolve(context.Background(), &Record{Title: "Heat"}); err == nil {
+		t.Error("resolve() without a metadata provider succeeded")
+	}
+}
@@ -0,0 +1,203 @@
+package imports
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+type traktIDs struct {
+	Imdb string `json:"imdb"`
+}
+
+type traktMedia struct {
+	Title string   `json:"title"`
+	Year  int      `json:"year"`
+	IDs   traktIDs `json:"ids"`
+}
+
+type traktEpisode struct {
+	Season int      `json:"season"`
+	Number int      `json:"number"`
+	Title  string   `json:"title"`
+	IDs    traktIDs `json:"ids"`
+}
+
+// traktItem covers the entries of every Trakt export file we read:
+// watched-history, watched-movies/shows, ratings-* and watchlist
+type traktItem struct {
+	WatchedAt     *time.Time    `json:"watched_at"`
+	LastWatchedAt *time.Time    `json:"last_watched_at"`
+	RatedAt       *time.Time    `json:"rated_at"`
+	ListedAt      *time.Time    `json:"listed_at"`
+	Rating        int           `json:"rating"`
+	Type          string        `json:"type"`
+	Movie         *traktMedia   `json:"movie"`
+	Show          *traktMedia   `json:"show"`
+	Episode       *traktEpisode `json:"episode"`
+	Seasons       []struct {
+		Number   int `json:"number"`
+		Episodes []struct {
+			Number        int        `json:"number"`
+			LastWatchedAt *time.Time `json:"last_watched_at"`
+		} `json:"episodes"`
+	} `json:"seasons"`
+}
+
+// ParseTrakt reads a Trakt export: either a single JSON file or the zip
+// produced by Trakt's data export
+func ParseTrakt(data []byte) ([]Record, error) {
+	if isZip(data) {
+		reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zip archive: %w", err)
+		}
+
+		var records []Record
+		for _, file := range reader.File {
+			if !strings.HasSuffix(file.Name, ".json") || !isTraktFile(path.Base(file.Name)) {
+				continue
+			}
+			content, err := readZipFile(file)
+			if err != nil {
+				return nil, err
+			}
+			parsed, err := parseTraktJSON(content)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file.Name, err)
+			}
+			records = append(records, parsed...)
+		}
+		return records, nil
+	}
+
+	return parseTraktJSON(data)
+}
+
+func isTraktFile(name string) bool {
+	for _, prefix := range []string{"watched-", "history", "ratings-", "watchlist"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTraktJSON(data []byte) ([]Record, error) {
+	// files re-saved on Windows often gain a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var items []traktItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid Trakt JSON: %w", err)
+	}
+
+	var records []Record
+	for _, item := range items {
+		records = append(records, item.records()...)
+	}
+	return records, nil
+}
+
+func (item *traktItem) records() []Record {
+	switch {
+	case item.Rating > 0 && item.RatedAt != nil:
+		// episode and season ratings have no equivalent here
+		if media, mediaType := item.title(); media != nil && item.Episode == nil && item.Type != "season" {
+			return []Record{media.record(KindRating, mediaType, *item.RatedAt, item.Rating)}
+		}
+
+	case item.ListedAt != nil:
+		if media, mediaType := item.title(); media != nil && item.Episode == nil {
+			return []Record{media.record(KindWatchlist, mediaType, *item.ListedAt, 0)}
+		}
+
+	case len(item.Seasons) > 0 && item.Show != nil:
+		var records []Record
+		for _, season := range item.Seasons {
+			for _, episode := range season.Episodes {
+				at := firstTime(episode.LastWatchedAt, item.LastWatchedAt)
+				record := item.Show.record(KindHistory, "series", at, 0)
+				record.SeasonNumber = intPtr(season.Number)
+				record.EpisodeNumber = intPtr(episode.Number)
+				records = append(records, record)
+			}
+		}
+		return records
+
+	case item.WatchedAt != nil || item.LastWatchedAt != nil:
+		at := firstTime(item.WatchedAt, item.LastWatchedAt)
+		if item.Episode != nil && item.Show != nil {
+			record := item.Show.record(KindHistory, "series", at, 0)
+			record.SeasonNumber = intPtr(item.Episode.Season)
+			record.EpisodeNumber = intPtr(item.Episode.Number)
+			return []Record{record}
+		}
+		if media, mediaType := item.title(); media != nil {
+			return []Record{media.record(KindHistory, mediaType, at, 0)}
+		}
+	}
+
+	return nil
+}
+
+func (item *traktItem) title() (*traktMedia, string) {
+	if item.Movie != nil {
+		return item.Movie, "movie"
+	}
+	if item.Show != nil {
+		return item.Show, "series"
+	}
+	return nil, ""
+}
+
+func (m *traktMedia) record(kind, mediaType string, at time.Time, rating int) Record {
+	return Record{
+		Kind:      kind,
+		ImdbID:    m.IDs.Imdb,
+		MediaType: mediaType,
+		Title:     m.Title,
+		Year:      m.Year,
+		At:        at,
+		Rating:    rating,
+	}
+}
+
+func firstTime(times ...*time.Time) time.Time {
+	for _, t := range times {
+		if t != nil {
+			return *t
+		}
+	}
+	return time.Now()
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func isZip(data []byte) bool {
+	return len(data) >= 4 && bytes.Equal(data[:4], []byte("PK\x03\x04"))
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, MaxUploadBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+	}
+	if len(content) > MaxUploadBytes {
+		return nil, fmt.Errorf("%s is too large", file.Name)
+	}
+	return content, nil
+}
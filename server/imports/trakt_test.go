@@ -0,0 +1,197 @@
+package imports
+
+import (
+	"archive/zip"
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixtures follow the shape of Trakt's data export
+const (
+	traktHistory = `[
+  {"id": 9001, "watched_at": "2024-01-05T20:00:00.000Z", "action": "watch", "type": "episode",
+   "episode": {"season": 1, "number": 2, "title": "Cat's in the Bag...", "ids": {"trakt": 62086, "tvdb": 438901, "imdb": "tt1054724", "tmdb": 62086}},
+   "show": {"title": "Breaking Bad", "year": 2008, "ids": {"trakt": 1388, "slug": "breaking-bad", "tvdb": 81189, "imdb": "tt0903747", "tmdb": 1396}}},
+  {"id": 9002, "watched_at": "2024-01-06T21:30:00.000Z", "action": "checkin", "type": "movie",
+   "movie": {"title": "Heat", "year": 1995, "ids": {"trakt": 1, "slug": "heat-1995", "imdb": "tt0113277", "tmdb": 949}}},
+  {"id": 9003, "action": "watch", "type": "movie"}
+]`
+
+	traktWatchedShows = `[
+  {"plays": 3, "last_watched_at": "2024-02-01T10:00:00.000Z", "last_updated_at": "2024-02-01T10:00:00.000Z",
+   "show": {"title": "The Wire", "year": 2002, "ids": {"trakt": 1, "imdb": "tt0306414"}},
+   "seasons": [
+     {"number": 1, "episodes": [
+       {"number": 1, "plays": 1, "last_watched_at": "2024-01-30T10:00:00.000Z"},
+       {"number": 2, "plays": 2, "last_watched_at": null}
+     ]}
+   ]}
+]`
+
+	traktRatings = `[
+  {"rated_at": "2024-03-01T12:00:00.000Z", "rating": 8, "type": "movie",
+   "movie": {"title": "Heat", "year": 1995, "ids": {"imdb": "tt0113277"}}},
+  {"rated_at": "2024-03-02T12:00:00.000Z", "rating": 10, "type": "show",
+   "show": {"title": "Breaking Bad", "year": 2008, "ids": {"imdb": "tt0903747"}}},
+  {"rated_at": "2024-03-03T12:00:00.000Z", "rating": 9, "type": "episode",
+   "episode": {"season": 5, "number": 14, "ids": {"imdb": "tt2301451"}},
+   "show": {"title": "Breaking Bad", "year": 2008, "ids": {"imdb": "tt0903747"}}},
+  {"rated_at": "2024-03-04T12:00:00.000Z", "rating": 7, "type": "season",
+   "season": {"number": 2},
+   "show": {"title": "Breaking Bad", "year": 2008, "ids": {"imdb": "tt0903747"}}}
+]`
+
+	traktWatchlist = `[
+  {"rank": 1, "id": 1, "listed_at": "2024-04-01T08:00:00.000Z", "notes": null, "type": "movie",
+   "movie": {"title": "Ran", "year": 1985, "ids": {"imdb": ""}}},
+  {"rank": 2, "id": 2, "listed_at": "2024-04-02T08:00:00.000Z", "notes": null, "type": "episode",
+   "episode": {"season": 1, "number": 1},
+   "show": {"title": "Severance", "year": 2022, "ids": {"imdb": "tt11280740"}}}
+]`
+)
+
+func recordSummary(records []Record) string {
+	lines := make([]string, len(records))
+	for i, r := range records {
+		line := r.Kind + " " + r.MediaType + " " + r.ImdbID + " " + r.Title
+		if r.SeasonNumber != nil && r.EpisodeNumber != nil {
+			line += " S" + strconv.Itoa(*r.SeasonNumber) + "E" + strconv.Itoa(*r.EpisodeNumber)
+		}
+		if r.Rating > 0 {
+			line += " rated " + strconv.Itoa(r.Rating)
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestParseTrakt(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "history skips entries without a title",
+			input: traktHistory,
+			want:  "history series tt0903747 Breaking Bad S1E2\nhistory movie tt0113277 Heat",
+		},
+		{
+			name:  "watched shows expand to episodes",
+			input: traktWatchedShows,
+			want:  "history series tt0306414 The Wire S1E1\nhistory series tt0306414 The Wire S1E2",
+		},
+		{
+			name:  "episode and season ratings are dropped",
+			input: traktRatings,
+			want:  "rating movie tt0113277 Heat rated 8\nrating series tt0903747 Breaking Bad rated 10",
+		},
+		{
+			name:  "watchlisted episodes are dropped",
+			input: traktWatchlist,
+			want:  "watchlist movie  Ran",
+		},
+		{
+			name:  "byte order mark",
+			input: "\ufeff" + traktRatings,
+			want:  "rating movie tt0113277 Heat rated 8\nrating series tt0903747 Breaking Bad rated 10",
+		},
+		{
+			name:  "empty export",
+			input: "[]",
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ParseTrakt([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("ParseTrakt() error = %v", err)
+			}
+			if got := recordSummary(records); got != tt.want {
+				t.Errorf("ParseTrakt() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTraktTimes(t *testing.T) {
+	records, err := ParseTrakt([]byte(traktWatchedShows))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an episode without its own time falls back to the show's
+	if want := time.Date(2024, 1, 30, 10, 0, 0, 0, time.UTC); !records[0].At.Equal(want) {
+		t.Errorf("episode 1 watched at %v, want %v", records[0].At, want)
+	}
+	if want := time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC); !records[1].At.Equal(want) {
+		t.Errorf("episode 2 watched at %v, want %v", records[1].At, want)
+	}
+}
+
+func zipOf(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseTraktZip(t *testing.T) {
+	archive := zipOf(t, map[string]string{
+		"trakt-export/watched-history.json": traktHistory,
+		"trakt-export/ratings-movies.json":  traktRatings,
+		"trakt-export/watchlist.json":       traktWatchlist,
+		"trakt-export/user-profile.json":    `{"username": "someone"}`,
+		"trakt-export/lists-lists.json":     `not json, but never read`,
+	})
+
+	records, err := ParseTrakt(archive)
+	if err != nil {
+		t.Fatalf("ParseTrakt() error = %v", err)
+	}
+
+	counts := map[string]int{}
+	for _, record := range records {
+		counts[record.Kind]++
+	}
+	if counts[KindHistory] != 2 || counts[KindRating] != 2 || counts[KindWatchlist] != 1 {
+		t.Errorf("ParseTrakt() kinds = %v", counts)
+	}
+}
+
+func TestParseTraktRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+	}{
+		{"not json", []byte("Date,Name,Year\n"), "invalid Trakt JSON"},
+		{"object instead of list", []byte(`{"movie": {}}`), "invalid Trakt JSON"},
+		{"truncated", []byte(traktHistory[:200]), "invalid Trakt JSON"},
+		{"broken file in zip", zipOf(t, map[string]string{"watched-movies.json": "[{"}), "watched-movies.json"},
+		{"corrupt zip", []byte("PK\x03\x04 not really a zip"), "invalid zip archive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTrakt(tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseTrakt() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -14,7 +14,11 @@ import (
 	"github.com/joho/godotenv"
 
 	"zync-stream/db"
+	"zync-stream/httpcache"
+	"zync-stream/media"
+	"zync-stream/metadata"
 	"zync-stream/routes"
+	"zync-stream/users"
 	"zync-stream/ws"
 )
 
@@ -73,9 +77,35 @@ func main() {
 		MaxAge:           12 * time.Hour,
 	}))
 
-	userRepo := routes.SetupUserRoutes(router, dbPool, redisClient)
+	avatarStorage, err := media.NewStorageFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize media storage: %v", err)
+	}
+
+	responseCache := httpcache.NewCacheFromEnv(redisClient)
+	metaProvider := metadata.NewProviderFromEnv(responseCache)
+
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	userRepo := routes.SetupUserRoutes(bgCtx, router, dbPool, redisClient, avatarStorage, metaProvider)
+
+	users.StartDeletionPurger(bgCtx, userRepo, avatarStorage, time.Hour)
 	ws.InitPresenceManager(userRepo)
 	routes.SetupRoomRoutes(router, dbPool, redisClient)
+	routes.SetupMediaRoutes(router, avatarStorage)
+	routes.SetupDMRoutes(router, dbPool)
+	routes.SetupNotificationRoutes(router, dbPool)
+	routes.SetupWebhookRoutes(bgCtx, router, dbPool)
+	routes.SetupPushRoutes(bgCtx, router, dbPool)
+	routes.SetupWatchlistRoutes(router, dbPool)
+	routes.SetupReviewRoutes(router, dbPool, metaProvider)
+	routes.SetupImportRoutes(bgCtx, router, dbPool, userRepo, metaProvider)
+	routes.SetupSessionRoutes(router, dbPool, userRepo)
+	routes.SetupAddonRoutes(bgCtx, router, dbPool, redisClient, responseCache)
+	routes.SetupSubtitleRoutes(router, dbPool, redisClient, responseCache)
+	routes.SetupTorrentRoutes(bgCtx, router)
+	routes.SetupHLSRoutes(bgCtx, router, dbPool)
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -0,0 +1,82 @@
+package media
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const AvatarURLPrefix = "/media/avatars/"
+
+type Handlers struct {
+	storage Storage
+}
+
+func NewHandlers(storage Storage) *Handlers {
+	return &Handlers{storage: storage}
+}
+
+// AvatarKey maps a public avatar URL back to its storage key. It returns
+// false for URLs that were not produced by the upload endpoint.
+func AvatarKey(publicURL string) (string, bool) {
+	if !strings.HasPrefix(publicURL, AvatarURLPrefix) {
+		return "", false
+	}
+	return "avatars/" + strings.TrimPrefix(publicURL, AvatarURLPrefix), true
+}
+
+func (h *Handlers) ServeAvatar(c *gin.Context) {
+	path := strings.TrimPrefix(c.Param("path"), "/")
+	if path == "" || strings.Contains(path, "..") {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	body, info, err := h.storage.Get(c.Request.Context(), "avatars/"+path)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		log.Printf("Error reading avatar %s: %v", path, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	// avatar keys embed a content hash, so a given URL never changes
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	if info.ETag != "" {
+		c.Header("ETag", info.ETag)
+		if match := c.GetHeader("If-None-Match"); match != "" && match == info.ETag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+	if !info.LastModified.IsZero() {
+		c.Header("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "image/jpeg"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	if info.Size > 0 {
+		c.Header("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	c.Status(http.StatusOK)
+
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(c.Writer, body); err != nil {
+		log.Printf("Error streaming avatar %s: %v", path, err)
+	}
+}
@@ -0,0 +1,243 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "us-east-1"
+	testBucket    = "avatars"
+)
+
+type s3Object struct {
+	body        []byte
+	contentType string
+}
+
+// fakeS3 is a path-style S3 endpoint that stores objects in memory and
+// rejects requests whose SigV4 signature it cannot reproduce
+type fakeS3 struct {
+	t       *testing.T
+	mu      sync.Mutex
+	objects map[string]s3Object
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	fake := &fakeS3{t: t, objects: make(map[string]s3Object)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := f.verify(r, body); err != nil {
+		f.t.Logf("rejected %s %s: %v", r.Method, r.URL.Path, err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/"+testBucket+"/")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key] = s3Object{body: body, contentType: r.Header.Get("Content-Type")}
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Format(http.TimeFormat))
+		w.Write(obj.body)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// verify recomputes the signature from what arrived on the wire
+func (f *fakeS3) verify(r *http.Request, body []byte) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") {
+		return errors.New("missing SigV4 authorization")
+	}
+
+	fields := map[string]string{}
+	for _, part := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ", ") {
+		name, value, _ := strings.Cut(part, "=")
+		fields[name] = value
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) != len("20060102T150405Z") {
+		return errors.New("missing X-Amz-Date")
+	}
+	scope := amzDate[:8] + "/" + testRegion + "/s3/aws4_request"
+	if fields["Credential"] != testAccessKey+"/"+scope {
+		return errors.New("bad credential scope " + fields["Credential"])
+	}
+
+	payloadHash := sha256Hex(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != payloadHash {
+		return errors.New("payload hash does not match the body")
+	}
+
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(signedHeaders) {
+		return errors.New("signed headers are not sorted")
+	}
+	for _, required := range []string{"host", "x-amz-content-sha256", "x-amz-date"} {
+		if !strings.Contains(";"+fields["SignedHeaders"]+";", ";"+required+";") {
+			return errors.New(required + " is not signed")
+		}
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, h := range signedHeaders {
+		value := r.Header.Get(h)
+		if h == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		fields["SignedHeaders"],
+		payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+testSecretKey), amzDate[:8])
+	key = hmacSHA256(key, testRegion)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	want := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	if !hmac.Equal([]byte(fields["Signature"]), []byte(want)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func newTestS3Storage(t *testing.T, endpoint, secret string) *S3Storage {
+	t.Helper()
+	storage, err := NewS3Storage(S3Config{
+		Endpoint:        endpoint,
+		Region:          testRegion,
+		Bucket:          testBucket,
+		AccessKeyID:     testAccessKey,
+		SecretAccessKey: secret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage
+}
+
+func TestS3StorageRoundTrip(t *testing.T) {
+	fake, server := newFakeS3(t)
+	storage := newTestS3Storage(t, server.URL, testSecretKey)
+	ctx := context.Background()
+
+	key := "avatars/7/1700000000-abc123.webp"
+	content := []byte("RIFF....WEBPVP8 image bytes")
+
+	if err := storage.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "image/webp"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if obj := fake.objects[key]; !bytes.Equal(obj.body, content) || obj.contentType != "image/webp" {
+		t.Fatalf("stored object = %q (%s)", obj.body, obj.contentType)
+	}
+
+	body, info, err := storage.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	got, _ := io.ReadAll(body)
+	body.Close()
+	if !bytes.Equal(got, content) {
+		t.Errorf("Get() body = %q", got)
+	}
+	if info.ContentType != "image/webp" || info.Size != int64(len(content)) || info.ETag != `"etag"` || info.LastModified.IsZero() {
+		t.Errorf("Get() info = %+v", info)
+	}
+
+	if err := storage.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, _, err := storage.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+
+	// deleting a missing object is not an error
+	if err := storage.Delete(ctx, key); err != nil {
+		t.Errorf("Delete() of a missing object error = %v", err)
+	}
+}
+
+func TestS3StorageEndpointPath(t *testing.T) {
+	_, server := newFakeS3(t)
+	storage := newTestS3Storage(t, server.URL+"/", testSecretKey)
+
+	if got := storage.objectURL("/avatars/1/x.webp").Path; got != "/"+testBucket+"/avatars/1/x.webp" {
+		t.Errorf("objectURL() path = %q", got)
+	}
+
+	content := []byte("x")
+	if err := storage.Put(context.Background(), "avatars/1/x.webp", bytes.NewReader(content), 1, "image/webp"); err != nil {
+		t.Errorf("Put() with a trailing slash endpoint error = %v", err)
+	}
+}
+
+func TestS3StorageRejectedSignature(t *testing.T) {
+	fake, server := newFakeS3(t)
+	storage := newTestS3Storage(t, server.URL, "wrong secret")
+	ctx := context.Background()
+
+	if err := storage.Put(ctx, "avatars/1/x.webp", strings.NewReader("x"), 1, "image/webp"); err == nil {
+		t.Error("Put() with a bad signature succeeded")
+	}
+	if len(fake.objects) != 0 {
+		t.Error("object stored despite the bad signature")
+	}
+	if _, _, err := storage.Get(ctx, "avatars/1/x.webp"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() with a bad signature error = %v", err)
+	}
+	if err := storage.Delete(ctx, "avatars/1/x.webp"); err == nil {
+		t.Error("Delete() with a bad signature succeeded")
+	}
+}
+
+func TestNewS3StorageValidatesConfig(t *testing.T) {
+	if _, err := NewS3Storage(S3Config{Bucket: testBucket, AccessKeyID: "a", SecretAccessKey: "b"}); err == nil {
+		t.Error("NewS3Storage() without an endpoint succeeded")
+	}
+	if _, err := NewS3Storage(S3Config{Endpoint: "http://localhost", Bucket: testBucket}); err == nil {
+		t.Error("NewS3Storage() without credentials succeeded")
+	}
+}
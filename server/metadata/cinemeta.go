@@ -0,0 +1,169 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"zync-stream/httpcache"
+)
+
+const DefaultCinemetaURL = "https://v3-cinemeta.strem.io"
+
+// CinemetaProvider reads Stremio-style addon meta endpoints:
+// GET {base}/meta/{type}/{imdb_id}.json
+type CinemetaProvider struct {
+	baseURL string
+	cache   *httpcache.Cache
+}
+
+// NewCinemetaProvider fetches through the shared response cache so every
+// server instance reuses the same lookups
+func NewCinemetaProvider(baseURL string, cache *httpcache.Cache) *CinemetaProvider {
+	return &CinemetaProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		cache:   cache,
+	}
+}
+
+type cinemetaResponse struct {
+	Meta *struct {
+		ID          string          `json:"id"`
+		Type        string          `json:"type"`
+		Name        string          `json:"name"`
+		Poster      string          `json:"poster"`
+		Background  string          `json:"background"`
+		ReleaseInfo string          `json:"releaseInfo"`
+		Released    string          `json:"released"`
+		Videos      []cinemetaVideo `json:"videos"`
+	} `json:"meta"`
+}
+
+type cinemetaVideo struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Title     string `json:"title"`
+	Season    int    `json:"season"`
+	Episode   int    `json:"episode"`
+	Number    int    `json:"number"`
+	Thumbnail string `json:"thumbnail"`
+	Released  string `json:"released"`
+}
+
+func (p *CinemetaProvider) Meta(ctx context.Context, mediaType, imdbID string) (*Meta, error) {
+	endpoint := fmt.Sprintf("%s/meta/%s/%s.json", p.baseURL, url.PathEscape(mediaType), url.PathEscape(imdbID))
+
+	data, err := p.cache.Get(ctx, endpoint)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+
+	var body cinemetaResponse
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	if body.Meta == nil || body.Meta.ID == "" {
+		return nil, ErrNotFound
+	}
+
+	meta := &Meta{
+		ID:          body.Meta.ID,
+		Type:        body.Meta.Type,
+		Name:        body.Meta.Name,
+		Poster:      body.Meta.Poster,
+		Background:  body.Meta.Background,
+		ReleaseInfo: body.Meta.ReleaseInfo,
+		Released:    parseReleased(body.Meta.Released),
+	}
+
+	for _, v := range body.Meta.Videos {
+		episode := v.Episode
+		if episode == 0 {
+			episode = v.Number
+		}
+		title := v.Title
+		if title == "" {
+			title = v.Name
+		}
+		meta.Videos = append(meta.Videos, Video{
+			ID:        v.ID,
+			Title:     title,
+			Season:    v.Season,
+			Episode:   episode,
+			Thumbnail: v.Thumbnail,
+			Released:  parseReleased(v.Released),
+		})
+	}
+
+	return meta, nil
+}
+
+type cinemetaSearchResponse struct {
+	Metas []struct {
+		ID          string `json:"id"`
+		Type        string `json:"type"`
+		Name        string `json:"name"`
+		Poster      string `json:"poster"`
+		ReleaseInfo string `json:"releaseInfo"`
+	} `json:"metas"`
+}
+
+// Search queries the addon's search catalog:
+// GET {base}/catalog/{type}/top/search={query}.json
+func (p *CinemetaProvider) Search(ctx context.Context, mediaType, query string) ([]Meta, error) {
+	endpoint := fmt.Sprintf("%s/catalog/%s/top/search=%s.json", p.baseURL, url.PathEscape(mediaType), url.PathEscape(query))
+
+	data, err := p.cache.Get(ctx, endpoint)
+	if err != nil {
+		if isNotFound(err) {
+			return []Meta{}, nil
+		}
+		return nil, fmt.Errorf("failed to search metadata: %w", err)
+	}
+
+	var body cinemetaSearchResponse
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("failed to decode search results: %w", err)
+	}
+
+	results := make([]Meta, 0, len(body.Metas))
+	for _, m := range body.Metas {
+		if !strings.HasPrefix(m.ID, "tt") {
+			continue
+		}
+		results = append(results, Meta{
+			ID:          m.ID,
+			Type:        m.Type,
+			Name:        m.Name,
+			Poster:      m.Poster,
+			ReleaseInfo: m.ReleaseInfo,
+		})
+	}
+
+	return results, nil
+}
+
+func isNotFound(err error) bool {
+	var statusErr *httpcache.StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// parseReleased tolerates the empty and malformed dates some catalogs return
+func parseReleased(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
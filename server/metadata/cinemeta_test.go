@@ -0,0 +1,161 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"zync-stream/httpcache"
+)
+
+// fakeCinemeta serves a single series, a movie and a search catalog
+func fakeCinemeta(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/meta/series/tt0903747.json":
+			w.Write([]byte(`{"meta":{"id":"tt0903747","type":"series","name":"Breaking Bad","releaseInfo":"2008-2013","videos":[
+				{"id":"tt0903747:1:2","name":"Cat's in the Bag...","season":1,"number":2,"released":"2008-01-27T00:00:00.000Z"},
+				{"id":"tt0903747:1:1","title":"Pilot","season":1,"episode":1,"released":"2008-01-20T00:00:00.000Z"},
+				{"id":"tt0903747:0:1","title":"Special","season":0,"episode":1},
+				{"id":"tt0903747:2:1","title":"Seven Thirty-Seven","season":2,"episode":1,"released":"not a date"}
+			]}}`))
+		case "/meta/movie/tt0111161.json":
+			w.Write([]byte(`{"meta":{"id":"tt0111161","type":"movie","name":"The Shawshank Redemption","released":"1994-09-23T00:00:00.000Z"}}`))
+		case "/meta/movie/tt0000000.json":
+			w.Write([]byte(`{"meta":null}`))
+		case "/catalog/movie/top/search=heat.json":
+			w.Write([]byte(`{"metas":[
+				{"id":"tt0113277","type":"movie","name":"Heat","releaseInfo":"1995"},
+				{"id":"kitsu:1","type":"movie","name":"Heat (anime)"},
+				{"id":"tt0096258","type":"movie","name":"Heat","releaseInfo":"1986"}
+			]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestProvider(t *testing.T) *CinemetaProvider {
+	return NewCinemetaProvider(fakeCinemeta(t).URL+"/", httpcache.NewCache(nil, http.DefaultClient, time.Minute, time.Minute))
+}
+
+func TestCinemetaMeta(t *testing.T) {
+	provider := newTestProvider(t)
+	ctx := context.Background()
+
+	meta, err := provider.Meta(ctx, "series", "tt0903747")
+	if err != nil {
+		t.Fatalf("Meta() error = %v", err)
+	}
+	if meta.Name != "Breaking Bad" || len(meta.Videos) != 4 {
+		t.Fatalf("Meta() = %+v", meta)
+	}
+
+	// "number" and "name" stand in for episode and title
+	second := meta.Videos[0]
+	if second.Episode != 2 || second.Title != "Cat's in the Bag..." || second.Released == nil {
+		t.Errorf("video = %+v", second)
+	}
+	if meta.Videos[3].Released != nil {
+		t.Errorf("malformed release date parsed as %v", meta.Videos[3].Released)
+	}
+
+	movie, err := provider.Meta(ctx, "movie", "tt0111161")
+	if err != nil {
+		t.Fatalf("Meta(movie) error = %v", err)
+	}
+	if movie.Released == nil || movie.Released.Year() != 1994 {
+		t.Errorf("movie released = %v", movie.Released)
+	}
+
+	for _, id := range []string{"tt0000000", "tt9999999"} {
+		if _, err := provider.Meta(ctx, "movie", id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Meta(%s) error = %v, want ErrNotFound", id, err)
+		}
+	}
+}
+
+func TestCinemetaSearch(t *testing.T) {
+	provider := newTestProvider(t)
+	ctx := context.Background()
+
+	results, err := provider.Search(ctx, "movie", "heat")
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Search() = %+v, want only imdb titles", results)
+	}
+	if match := MatchYear(results, 1986); match.ID != "tt0096258" {
+		t.Errorf("MatchYear(1986) = %s", match.ID)
+	}
+	if match := MatchYear(results, 2001); match.ID != "tt0113277" {
+		t.Errorf("MatchYear(2001) = %s, want the first result", match.ID)
+	}
+
+	results, err = provider.Search(ctx, "movie", "nothing")
+	if err != nil || len(results) != 0 {
+		t.Errorf("Search() for an unknown title = %v, %v", results, err)
+	}
+}
+
+func TestCinemetaUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	provider := NewCinemetaProvider(server.URL, httpcache.NewCache(nil, http.DefaultClient, time.Minute, time.Minute))
+
+	if _, err := provider.Meta(context.Background(), "movie", "tt0111161"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Meta() error = %v, want a fetch error", err)
+	}
+}
+
+func TestNextEpisode(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	aired := now.Add(-24 * time.Hour)
+	upcoming := now.Add(24 * time.Hour)
+
+	meta := &Meta{Videos: []Video{
+		{ID: "s2e1", Season: 2, Episode: 1, Released: &upcoming},
+		{ID: "s1e2", Season: 1, Episode: 2, Released: &aired},
+		{ID: "s0e1", Season: 0, Episode: 1, Released: &aired},
+		{ID: "s1e1", Season: 1, Episode: 1, Released: &aired},
+		{ID: "s1e3", Season: 1, Episode: 3},
+	}}
+
+	tests := []struct {
+		name            string
+		season, episode int
+		want            string
+	}{
+		{"next in season", 1, 1, "s1e2"},
+		{"unknown release date counts as aired", 1, 2, "s1e3"},
+		{"next season not aired yet", 1, 3, ""},
+		{"specials are skipped", 0, 1, "s1e1"},
+		{"last episode", 2, 1, ""},
+		{"episode missing from the list", 1, 10, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := NextEpisode(meta, tt.season, tt.episode, now)
+			got := ""
+			if next != nil {
+				got = next.ID
+			}
+			if got != tt.want {
+				t.Errorf("NextEpisode(%d, %d) = %q, want %q", tt.season, tt.episode, got, tt.want)
+			}
+		})
+	}
+
+	if len(meta.Videos) != 5 || meta.Videos[0].ID != "s2e1" {
+		t.Error("NextEpisode() reordered the meta's videos")
+	}
+}
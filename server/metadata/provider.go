@@ -0,0 +1,102 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"zync-stream/httpcache"
+)
+
+var ErrNotFound = errors.New("title not found")
+
+// Provider looks up title metadata by imdb id and searches titles by name
+type Provider interface {
+	Meta(ctx context.Context, mediaType, imdbID string) (*Meta, error)
+	Search(ctx context.Context, mediaType, query string) ([]Meta, error)
+}
+
+type Meta struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	Name        string     `json:"name"`
+	Poster      string     `json:"poster,omitempty"`
+	Background  string     `json:"background,omitempty"`
+	ReleaseInfo string     `json:"releaseInfo,omitempty"`
+	Videos      []Video    `json:"videos,omitempty"`
+	Released    *time.Time `json:"released,omitempty"`
+}
+
+// Video is a single episode of a series
+type Video struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title,omitempty"`
+	Season    int        `json:"season"`
+	Episode   int        `json:"episode"`
+	Thumbnail string     `json:"thumbnail,omitempty"`
+	Released  *time.Time `json:"released,omitempty"`
+}
+
+// NewProviderFromEnv returns a Cinemeta client (CINEMETA_URL). Lookups are
+// cached by the shared response cache only, so every instance sees the same
+// entries and the addon's cacheMaxAge decides how long they live.
+func NewProviderFromEnv(cache *httpcache.Cache) Provider {
+	baseURL := os.Getenv("CINEMETA_URL")
+	if baseURL == "" {
+		baseURL = DefaultCinemetaURL
+	}
+
+	return NewCinemetaProvider(baseURL, cache)
+}
+
+// MatchYear picks the search result released in year, or the first result
+// when no year is known or nothing matches it
+func MatchYear(results []Meta, year int) *Meta {
+	if len(results) == 0 {
+		return nil
+	}
+	if year > 0 {
+		prefix := strconv.Itoa(year)
+		for i := range results {
+			if strings.HasPrefix(results[i].ReleaseInfo, prefix) {
+				return &results[i]
+			}
+		}
+	}
+	return &results[0]
+}
+
+// NextEpisode returns the episode that follows season/episode, skipping
+// specials (season 0) and episodes that have not aired by now
+func NextEpisode(meta *Meta, season, episode int, now time.Time) *Video {
+	videos := make([]Video, 0, len(meta.Videos))
+	for _, video := range meta.Videos {
+		if video.Season > 0 {
+			videos = append(videos, video)
+		}
+	}
+
+	sort.Slice(videos, func(i, j int) bool {
+		if videos[i].Season != videos[j].Season {
+			return videos[i].Season < videos[j].Season
+		}
+		return videos[i].Episode < videos[j].Episode
+	})
+
+	for _, video := range videos {
+		if video.Season < season || (video.Season == season && video.Episode <= episode) {
+			continue
+		}
+		if video.Released != nil && video.Released.After(now) {
+			return nil
+		}
+		next := video
+		return &next
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// accountCacheTTL bounds how long another instance may keep accepting a
+// token after an account is scheduled for deletion
+const accountCacheTTL = 30 * time.Second
+
+// expired entries are swept once the cache holds this many users
+const maxCachedAccounts = 10000
+
+var ErrAccountInactive = errors.New("account is scheduled for deletion")
+
+var accountDB *pgxpool.Pool
+
+// accountState is what CheckAccountActive needs to know about a user
+type accountState struct {
+	exists          bool
+	pending         bool
+	tokensNotBefore *time.Time
+	loadedAt        time.Time
+}
+
+var accounts = struct {
+	sync.Mutex
+	states map[int]accountState
+}{states: make(map[int]accountState)}
+
+// loadAccount is replaced in tests
+var loadAccount = func(ctx context.Context, userID int) (accountState, error) {
+	var state accountState
+	err := accountDB.QueryRow(ctx, `
+        SELECT deletion_scheduled_at IS NOT NULL, tokens_valid_after
+        FROM users WHERE id = $1
+    `, userID).Scan(&state.pending, &state.tokensNotBefore)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to check account status: %w", err)
+	}
+	state.exists = true
+	return state, nil
+}
+
+// SetAccountDB makes AuthMiddleware and CheckAccountActive reject tokens of
+// accounts that are pending deletion or already purged, and tokens issued
+// before the account last asked to be signed out everywhere. Logging in
+// again cancels a deletion but does not revive those older tokens.
+func SetAccountDB(db *pgxpool.Pool) {
+	accountDB = db
+}
+
+// CheckAccountActive returns ErrAccountInactive when the user has scheduled
+// their account for deletion, no longer exists, or the token was issued
+// before tokens_valid_after. Lookups are cached for accountCacheTTL.
+func CheckAccountActive(ctx context.Context, userID int, issuedAt time.Time) error {
+	if accountDB == nil {
+		return nil
+	}
+
+	now := time.Now()
+	accounts.Lock()
+	state, ok := accounts.states[userID]
+	accounts.Unlock()
+
+	if !ok || now.Sub(state.loadedAt) > accountCacheTTL {
+		loaded, err := loadAccount(ctx, userID)
+		if err != nil {
+			return err
+		}
+		loaded.loadedAt = now
+		state = loaded
+
+		accounts.Lock()
+		if len(accounts.states) >= maxCachedAccounts {
+			for id, cached := range accounts.states {
+				if now.Sub(cached.loadedAt) > accountCacheTTL {
+					delete(accounts.states, id)
+				}
+			}
+		}
+		accounts.states[userID] = state
+		accounts.Unlock()
+	}
+
+	if !state.exists || state.pending {
+		return ErrAccountInactive
+	}
+	if state.tokensNotBefore != nil && issuedAt.Before(*state.tokensNotBefore) {
+		return ErrAccountInactive
+	}
+	return nil
+}
+
+// ForgetAccount drops the cached status of a user whose deletion was just
+// scheduled or cancelled, so this instance sees the change immediately
+func ForgetAccount(userID int) {
+	accounts.Lock()
+	delete(accounts.states, userID)
+	accounts.Unlock()
+}
+
+// TokenIssuedAt returns the iat claim, or the zero time for tokens without one
+func TokenIssuedAt(claims jwt.MapClaims) time.Time {
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return time.Time{}
+	}
+	return issuedAt.Time
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestCheckAccountActive(t *testing.T) {
+	cutoff := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	states := map[int]accountState{
+		1: {exists: true},
+		2: {exists: true, pending: true},
+		3: {exists: true, tokensNotBefore: &cutoff},
+	}
+	loads := 0
+
+	defer func(db *pgxpool.Pool, load func(context.Context, int) (accountState, error)) {
+		accountDB, loadAccount = db, load
+		ForgetAccount(1)
+		ForgetAccount(2)
+		ForgetAccount(3)
+		ForgetAccount(4)
+	}(accountDB, loadAccount)
+	accountDB = &pgxpool.Pool{}
+	loadAccount = func(ctx context.Context, userID int) (accountState, error) {
+		loads++
+		return states[userID], nil
+	}
+
+	tests := []struct {
+		name     string
+		userID   int
+		issuedAt time.Time
+		wantErr  error
+	}{
+		{"active", 1, cutoff, nil},
+		{"deletion pending", 2, cutoff.Add(time.Hour), ErrAccountInactive},
+		{"token from before the sign out", 3, cutoff.Add(-time.Second), ErrAccountInactive},
+		{"token without iat", 3, time.Time{}, ErrAccountInactive},
+		{"token from the cut-off second", 3, cutoff, nil},
+		{"token from after logging back in", 3, cutoff.Add(time.Minute), nil},
+		{"purged", 4, cutoff, ErrAccountInactive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckAccountActive(context.Background(), tt.userID, tt.issuedAt); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckAccountActive(%d) error = %v, want %v", tt.userID, err, tt.wantErr)
+			}
+		})
+	}
+
+	// one lookup per user until it expires or is forgotten
+	if loads != 4 {
+		t.Errorf("loaded accounts %d times, want 4", loads)
+	}
+
+	states[2] = accountState{exists: true}
+	if err := CheckAccountActive(context.Background(), 2, cutoff); err == nil {
+		t.Error("cached pending state was not used")
+	}
+	ForgetAccount(2)
+	if err := CheckAccountActive(context.Background(), 2, cutoff); err != nil {
+		t.Errorf("CheckAccountActive() after ForgetAccount() error = %v", err)
+	}
+}
+
+func TestCheckAccountActiveLookupFailure(t *testing.T) {
+	defer func(db *pgxpool.Pool, load func(context.Context, int) (accountState, error)) {
+		accountDB, loadAccount = db, load
+	}(accountDB, loadAccount)
+	accountDB = &pgxpool.Pool{}
+	loadAccount = func(ctx context.Context, userID int) (accountState, error) {
+		return accountState{}, errors.New("connection refused")
+	}
+
+	err := CheckAccountActive(context.Background(), 99, time.Now())
+	if err == nil || errors.Is(err, ErrAccountInactive) {
+		t.Errorf("CheckAccountActive() error = %v, want the lookup error", err)
+	}
+
+	// failures are not cached
+	loadAccount = func(ctx context.Context, userID int) (accountState, error) {
+		return accountState{exists: true}, nil
+	}
+	if err := CheckAccountActive(context.Background(), 99, time.Now()); err != nil {
+		t.Errorf("CheckAccountActive() after recovery error = %v", err)
+	}
+	ForgetAccount(99)
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AdminMiddleware only lets users flagged is_admin through. It must run
+// after AuthMiddleware, and reads the flag from the database so revoking
+// it takes effect without waiting for tokens to expire.
+func AdminMiddleware(db *pgxpool.Pool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		var isAdmin bool
+		err := db.QueryRow(c.Request.Context(), `SELECT is_admin FROM users WHERE id = $1`, userID).Scan(&isAdmin)
+		if err != nil || !isAdmin {
+			if err != nil {
+				log.Printf("Admin check failed for user %v: %v", userID, err)
+			}
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -99,6 +99,17 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if err := CheckAccountActive(c.Request.Context(), c.GetInt("user_id"), TokenIssuedAt(claims)); err != nil {
+			if errors.Is(err, ErrAccountInactive) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is scheduled for deletion, log in again to restore it"})
+			} else {
+				log.Printf("Account check failed: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify account"})
+			}
+			c.Abort()
+			return
+		}
+
 		if username, ok := claims["username"].(string); ok {
 			c.Set("username", username)
 		}
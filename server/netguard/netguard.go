@@ -0,0 +1,133 @@
+// Package netguard keeps requests the server makes on behalf of users, to
+// webhook, addon, subtitle and push URLs, away from loopback, private and
+// other internal networks.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const maxRedirects = 5
+
+var (
+	ErrInvalidURL     = errors.New("URL must be an absolute http or https URL")
+	ErrBlockedAddress = errors.New("URL points to a private or internal address")
+)
+
+// reserved ranges that IsPrivate and friends do not cover
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 can reach any IPv4 address
+}
+
+// IsPublic reports whether ip may be contacted on a user's behalf
+func IsPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL validates that raw is an http(s) URL whose host only resolves to
+// public addresses. The dialer repeats the check on connect, so a host that
+// later resolves elsewhere is still refused.
+func CheckURL(ctx context.Context, raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Hostname() == "" {
+		return ErrInvalidURL
+	}
+	if scheme := strings.ToLower(parsed.Scheme); scheme != "http" && scheme != "https" {
+		return ErrInvalidURL
+	}
+	return checkHost(ctx, parsed.Hostname())
+}
+
+func checkHost(ctx context.Context, host string) error {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !IsPublic(ip) {
+			return ErrBlockedAddress
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !IsPublic(addr) {
+			return ErrBlockedAddress
+		}
+	}
+	return nil
+}
+
+// control runs on the address actually being dialled, after DNS resolution
+func control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !IsPublic(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+	}
+	return nil
+}
+
+// NewDialer returns a dialer that refuses non-public addresses
+func NewDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   control,
+	}
+}
+
+// NewClient returns a client for user supplied URLs. It only dials public
+// addresses, never goes through a proxy (which would hide the real target)
+// and follows at most maxRedirects http(s) redirects.
+func NewClient(timeout time.Duration) *http.Client {
+	transport := &http.Transport{
+		DialContext:           NewDialer().DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	return &http.Client{
+		Timeout:       timeout,
+		Transport:     transport,
+		CheckRedirect: CheckRedirect,
+	}
+}
+
+// CheckRedirect is an http.Client CheckRedirect that applies CheckURL to
+// every hop
+func CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	return CheckURL(req.Context(), req.URL.String())
+}
@@ -0,0 +1,108 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsPublic(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("IsPublic(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr error
+	}{
+		{"https://93.184.216.34/hook", nil},
+		{"http://[2606:4700:4700::1111]:8080/", nil},
+		{"http://127.0.0.1:6379/", ErrBlockedAddress},
+		{"http://169.254.169.254/latest/meta-data/", ErrBlockedAddress},
+		{"http://[::1]/", ErrBlockedAddress},
+		{"http://localhost:8080/", ErrBlockedAddress},
+		{"http://0x7f000001/", nil}, // not an IP literal to Go, so it must resolve
+		{"ftp://93.184.216.34/", ErrInvalidURL},
+		{"file:///etc/passwd", ErrInvalidURL},
+		{"/relative", ErrInvalidURL},
+		{"http://", ErrInvalidURL},
+	}
+
+	for _, tt := range tests {
+		err := CheckURL(context.Background(), tt.url)
+		if tt.url == "http://0x7f000001/" {
+			// resolvers either fail or answer with loopback, both are refusals
+			if err == nil {
+				t.Errorf("CheckURL(%s) succeeded", tt.url)
+			}
+			continue
+		}
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("CheckURL(%s) error = %v, want %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestClientRefusesInternalTargets(t *testing.T) {
+	hit := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second).Get(server.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Get(%s) error = %v, want ErrBlockedAddress", server.URL, err)
+	}
+	if hit {
+		t.Error("the guarded client reached a loopback server")
+	}
+}
+
+func TestCheckRedirect(t *testing.T) {
+	redirect := func(target string, hops int) error {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		return CheckRedirect(req, make([]*http.Request, hops))
+	}
+
+	if err := redirect("https://93.184.216.34/next", 1); err != nil {
+		t.Errorf("redirect to a public address error = %v", err)
+	}
+	if err := redirect("http://10.0.0.5/admin", 1); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("redirect to a private address error = %v", err)
+	}
+	if err := redirect("https://93.184.216.34/next", maxRedirects); err == nil {
+		t.Error("redirect chain longer than the limit was followed")
+	}
+}
@@ -0,0 +1,224 @@
+package notifications
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Notifier pushes a realtime event to a user's notification channel
+type Notifier func(userID int, eventType string, data map[string]interface{}) error
+
+type NotificationHandlers struct {
+	repo   *NotificationRepository
+	notify Notifier
+}
+
+func NewNotificationHandlers(repo *NotificationRepository, notify Notifier) *NotificationHandlers {
+	return &NotificationHandlers{
+		repo:   repo,
+		notify: notify,
+	}
+}
+
+func (h *NotificationHandlers) GetNotifications(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var err error
+	limit := DefaultPageSize
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = min(limit, MaxPageSize)
+	}
+
+	beforeID := 0
+	if beforeStr := c.Query("before"); beforeStr != "" {
+		beforeID, err = strconv.Atoi(beforeStr)
+		if err != nil || beforeID < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+	}
+
+	unreadOnly := c.Query("unread") == "true"
+
+	notifications, err := h.repo.List(c.Request.Context(), userID.(int), unreadOnly, beforeID, limit+1)
+	if err != nil {
+		log.Printf("Error loading notifications for user %d: %v", userID.(int), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notifications"})
+		return
+	}
+
+	hasMore := len(notifications) > limit
+	if hasMore {
+		notifications = notifications[:limit]
+	}
+
+	unread, err := h.repo.UnreadCount(c.Request.Context(), userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notifications"})
+		return
+	}
+
+	response := gin.H{
+		"notifications": notifications,
+		"unread_count":  unread,
+		"has_more":      hasMore,
+	}
+	if hasMore {
+		response["next_before"] = notifications[len(notifications)-1].ID
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *NotificationHandlers) GetUnreadCount(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	unread, err := h.repo.UnreadCount(c.Request.Context(), userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve unread count"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread_count": unread})
+}
+
+func (h *NotificationHandlers) MarkRead(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	notificationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	found, err := h.repo.MarkRead(c.Request.Context(), userID.(int), notificationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notification read"})
+		return
+	}
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	h.pushUnreadCount(c, userID.(int))
+}
+
+func (h *NotificationHandlers) MarkAllRead(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if _, err := h.repo.MarkAllRead(c.Request.Context(), userID.(int)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notifications read"})
+		return
+	}
+
+	h.pushUnreadCount(c, userID.(int))
+}
+
+// pushUnreadCount responds with the new unread count and syncs it to the
+// user's other open sessions
+func (h *NotificationHandlers) pushUnreadCount(c *gin.Context, userID int) {
+	unread, err := h.repo.UnreadCount(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve unread count"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread_count": unread})
+
+	if h.notify != nil {
+		if err := h.notify(userID, "notification_unread_count", map[string]interface{}{"count": unread}); err != nil {
+			log.Printf("Failed to push unread count to user %d: %v", userID, err)
+		}
+	}
+}
+
+func (h *NotificationHandlers) GetPreferences(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	prefs, err := h.repo.GetPreferences(c.Request.Context(), userID.(int))
+	if err != nil {
+		log.Printf("Error loading notification preferences for user %d: %v", userID.(int), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+func (h *NotificationHandlers) UpdatePreferences(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req struct {
+		Channels   map[string]string `json:"channels"`
+		Timezone   *string           `json:"timezone"`
+		QuietHours *QuietHours       `json:"quiet_hours"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefs, err := h.repo.GetPreferences(c.Request.Context(), userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve preferences"})
+		return
+	}
+
+	for notificationType, channel := range req.Channels {
+		prefs.Channels[notificationType] = channel
+	}
+	if req.Timezone != nil {
+		prefs.Timezone = *req.Timezone
+	}
+	if req.QuietHours != nil {
+		prefs.QuietHours = *req.QuietHours
+	}
+
+	if err := prefs.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.repo.UpdatePreferences(c.Request.Context(), userID.(int), prefs); err != nil {
+		log.Printf("Error saving notification preferences for user %d: %v", userID.(int), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
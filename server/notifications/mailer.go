@@ -0,0 +1,178 @@
+package notifications
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Mailer delivers notifications for users who chose the email channel
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// NewMailerFromEnv returns an SMTP mailer when SMTP_HOST is configured and a
+// logging mailer otherwise.
+func NewMailerFromEnv() Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		log.Println("warning: SMTP_HOST not set, notification emails will only be logged")
+		return LogMailer{}
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = "no-reply@zync.local"
+	}
+
+	return &SMTPMailer{
+		addr:     host + ":" + port,
+		host:     host,
+		from:     from,
+		username: os.Getenv("SMTP_USERNAME"),
+		password: os.Getenv("SMTP_PASSWORD"),
+	}
+}
+
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, to, subject, body string) error {
+	log.Printf("📧 Email to %s: %s", to, subject)
+	return nil
+}
+
+// smtpTimeout bounds a delivery when the caller's context has no deadline
+const smtpTimeout = 30 * time.Second
+
+type SMTPMailer struct {
+	addr     string
+	host     string
+	from     string
+	username string
+	password string
+}
+
+// Send delivers the email like smtp.SendMail, but gives up when ctx is done
+// or smtpTimeout passes, so a hung server cannot block the caller
+func (m *SMTPMailer) Send(ctx context.Context, to, subject, body string) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, smtpTimeout)
+		defer cancel()
+	}
+
+	msg := strings.Join([]string{
+		"From: " + m.from,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	if err := m.send(ctx, to, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+func (m *SMTPMailer) send(ctx context.Context, to string, msg []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Hello("localhost"); err != nil {
+		return err
+	}
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(m.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// EmailContent renders a plain text email for a notification
+func EmailContent(notificationType string, data map[string]interface{}) (string, string) {
+	name := func(keys ...string) string {
+		for _, key := range keys {
+			if value, ok := data[key].(string); ok && value != "" {
+				return value
+			}
+		}
+		return "Someone"
+	}
+
+	switch notificationType {
+	case "friend_request_received":
+		who := name("display_name", "username")
+		return "New friend request", fmt.Sprintf("%s sent you a friend request on Zync.", who)
+	case "friend_request_accepted":
+		who := name("display_name", "username")
+		return "Friend request accepted", fmt.Sprintf("%s accepted your friend request.", who)
+	case "friend_request_rejected":
+		who := name("display_name", "username")
+		return "Friend request declined", fmt.Sprintf("%s declined your friend request.", who)
+	case "room_invitation":
+		who := name("inviter_name")
+		return "Room invitation", fmt.Sprintf("%s invited you to watch together in %v.", who, data["room_name"])
+	case "invitation_accepted":
+		who := name("accepter_name")
+		return "Invitation accepted", fmt.Sprintf("%s joined %v.", who, data["room_name"])
+	case "invitation_rejected":
+		who := name("rejecter_name")
+		return "Invitation declined", fmt.Sprintf("%s declined your invitation to %v.", who, data["room_name"])
+	default:
+		return "New notification", "You have a new notification on Zync."
+	}
+}
@@ -0,0 +1,20 @@
+package notifications
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	DefaultPageSize = 30
+	MaxPageSize     = 100
+)
+
+type Notification struct {
+	ID        int             `json:"id"`
+	UserID    int             `json:"user_id"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	ReadAt    *time.Time      `json:"read_at,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
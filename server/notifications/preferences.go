@@ -0,0 +1,173 @@
+package notifications
+
+import (
+	"fmt"
+	"time"
+
+	// the server image ships without a system zoneinfo database
+	_ "time/tzdata"
+)
+
+const (
+	ChannelInApp = "in_app"
+	ChannelEmail = "email"
+	ChannelOff   = "off"
+
+	TypeStatusUpdate = "status_update"
+)
+
+// KnownTypes lists every notification type a preference can be set for
+var KnownTypes = []string{
+	"friend_request_received",
+	"friend_request_accepted",
+	"friend_request_rejected",
+	"room_invitation",
+	"invitation_accepted",
+	"invitation_rejected",
+	TypeStatusUpdate,
+}
+
+type QuietHours struct {
+	Enabled bool   `json:"enabled"`
+	Start   string `json:"start"` // "HH:MM" in the user's timezone
+	End     string `json:"end"`
+}
+
+type Preferences struct {
+	Channels   map[string]string `json:"channels"`
+	Timezone   string            `json:"timezone"`
+	QuietHours QuietHours        `json:"quiet_hours"`
+}
+
+// Delivery describes how a single notification reaches a user
+type Delivery struct {
+	Store  bool // keep it in the inbox
+	Live   bool // publish it to open sessions
+	Silent bool // publish without a toast (inbox badge only)
+	Quiet  bool // quiet hours or do-not-disturb: nothing may interrupt the user
+	Email  bool
+}
+
+func DefaultPreferences() *Preferences {
+	channels := make(map[string]string, len(KnownTypes))
+	for _, t := range KnownTypes {
+		channels[t] = ChannelInApp
+	}
+
+	return &Preferences{
+		Channels: channels,
+		Timezone: "UTC",
+		QuietHours: QuietHours{
+			Start: "22:00",
+			End:   "08:00",
+		},
+	}
+}
+
+func (p *Preferences) Channel(notificationType string) string {
+	if channel, ok := p.Channels[notificationType]; ok {
+		return channel
+	}
+	return ChannelInApp
+}
+
+// Route decides how a notification is delivered given the user's
+// preferences, the current time and whether they are in do-not-disturb.
+func (p *Preferences) Route(notificationType string, now time.Time, dnd bool) Delivery {
+	quiet := dnd || p.InQuietHours(now)
+
+	switch p.Channel(notificationType) {
+	case ChannelOff:
+		return Delivery{Quiet: quiet}
+	case ChannelEmail:
+		return Delivery{Store: true, Live: true, Silent: true, Quiet: quiet, Email: true}
+	}
+
+	return Delivery{
+		Store:  true,
+		Live:   true,
+		Silent: quiet,
+		Quiet:  quiet,
+	}
+}
+
+func (p *Preferences) InQuietHours(now time.Time) bool {
+	if !p.QuietHours.Enabled {
+		return false
+	}
+
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	start, err := parseClock(p.QuietHours.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(p.QuietHours.End)
+	if err != nil {
+		return false
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	if start == end {
+		return false
+	}
+	if start < end {
+		return minute >= start && minute < end
+	}
+	// window wraps past midnight, e.g. 22:00-08:00
+	return minute >= start || minute < end
+}
+
+// Validate checks channels, timezone and quiet hour formats
+func (p *Preferences) Validate() error {
+	for notificationType, channel := range p.Channels {
+		if !isKnownType(notificationType) {
+			return fmt.Errorf("unknown notification type: %s", notificationType)
+		}
+		switch channel {
+		case ChannelInApp, ChannelOff:
+		case ChannelEmail:
+			if notificationType == TypeStatusUpdate {
+				return fmt.Errorf("status updates cannot be delivered by email")
+			}
+		default:
+			return fmt.Errorf("invalid channel %q for %s", channel, notificationType)
+		}
+	}
+
+	if _, err := time.LoadLocation(p.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone: %s", p.Timezone)
+	}
+
+	if _, err := parseClock(p.QuietHours.Start); err != nil {
+		return fmt.Errorf("invalid quiet hours start: %s", p.QuietHours.Start)
+	}
+	if _, err := parseClock(p.QuietHours.End); err != nil {
+		return fmt.Errorf("invalid quiet hours end: %s", p.QuietHours.End)
+	}
+
+	return nil
+}
+
+func isKnownType(notificationType string) bool {
+	for _, t := range KnownTypes {
+		if t == notificationType {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock turns "HH:MM" into minutes since midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,74 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoute(t *testing.T) {
+	prefs := DefaultPreferences()
+	prefs.Timezone = "Europe/Berlin"
+	prefs.QuietHours.Enabled = true
+	prefs.Channels["room_invitation"] = ChannelEmail
+	prefs.Channels[TypeStatusUpdate] = ChannelOff
+
+	// 21:30 UTC is 23:30 in Berlin, inside the 22:00-08:00 window
+	night := time.Date(2024, 6, 1, 21, 30, 0, 0, time.UTC)
+	day := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		notificationType string
+		now              time.Time
+		dnd              bool
+		want             Delivery
+	}{
+		{"in app", "friend_request_received", day, false, Delivery{Store: true, Live: true}},
+		{"quiet hours", "friend_request_received", night, false, Delivery{Store: true, Live: true, Silent: true, Quiet: true}},
+		{"do not disturb", "friend_request_received", day, true, Delivery{Store: true, Live: true, Silent: true, Quiet: true}},
+		{"email", "room_invitation", day, false, Delivery{Store: true, Live: true, Silent: true, Email: true}},
+		{"email in quiet hours", "room_invitation", night, false, Delivery{Store: true, Live: true, Silent: true, Quiet: true, Email: true}},
+		{"off", TypeStatusUpdate, day, false, Delivery{}},
+		{"off in do not disturb", TypeStatusUpdate, day, true, Delivery{Quiet: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := prefs.Route(tt.notificationType, tt.now, tt.dnd); got != tt.want {
+				t.Errorf("Route() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInQuietHours(t *testing.T) {
+	prefs := DefaultPreferences()
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 6, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	if prefs.InQuietHours(at(23, 0)) {
+		t.Error("InQuietHours() is true while quiet hours are disabled")
+	}
+
+	prefs.QuietHours.Enabled = true
+	for _, tt := range []struct {
+		hour, minute int
+		want         bool
+	}{
+		{21, 59, false},
+		{22, 0, true},
+		{3, 0, true},
+		{7, 59, true},
+		{8, 0, false},
+	} {
+		if got := prefs.InQuietHours(at(tt.hour, tt.minute)); got != tt.want {
+			t.Errorf("InQuietHours(%02d:%02d) = %v, want %v", tt.hour, tt.minute, got, tt.want)
+		}
+	}
+
+	prefs.QuietHours.Start, prefs.QuietHours.End = "09:00", "17:00"
+	if !prefs.InQuietHours(at(12, 0)) || prefs.InQuietHours(at(18, 0)) {
+		t.Error("InQuietHours() mishandles a window within one day")
+	}
+}
@@ -0,0 +1,250 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NotificationRepository handles database operations for the notification inbox
+type NotificationRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewNotificationRepository creates a new NotificationRepository
+func NewNotificationRepository(db *pgxpool.Pool) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+func (r *NotificationRepository) Create(ctx context.Context, userID int, notificationType string, data map[string]interface{}) (*Notification, error) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal notification data: %w", err)
+	}
+
+	query := `
+        INSERT INTO notifications (user_id, type, data)
+        VALUES ($1, $2, $3::jsonb)
+        RETURNING id, created_at
+    `
+
+	notification := &Notification{
+		UserID: userID,
+		Type:   notificationType,
+		Data:   dataJSON,
+	}
+
+	err = r.db.QueryRow(ctx, query, userID, notificationType, string(dataJSON)).
+		Scan(&notification.ID, &notification.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return notification, nil
+}
+
+// List returns a page of notifications, newest first
+func (r *NotificationRepository) List(ctx context.Context, userID int, unreadOnly bool, beforeID, limit int) ([]Notification, error) {
+	query := `
+        SELECT id, user_id, type, data, read_at, created_at
+        FROM notifications
+        WHERE user_id = $1
+            AND ($2 = false OR read_at IS NULL)
+            AND ($3 = 0 OR id < $3)
+        ORDER BY id DESC
+        LIMIT $4
+    `
+
+	rows, err := r.db.Query(ctx, query, userID, unreadOnly, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		var notification Notification
+		var data []byte
+
+		if err := rows.Scan(&notification.ID, &notification.UserID, &notification.Type,
+			&data, &notification.ReadAt, &notification.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+
+		notification.Data = data
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+func (r *NotificationRepository) UnreadCount(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `
+        SELECT COUNT(*) FROM notifications
+        WHERE user_id = $1 AND read_at IS NULL
+    `, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+
+	return count, nil
+}
+
+// MarkRead returns false if the notification doesn't exist or belongs to someone else
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID, notificationID int) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+        UPDATE notifications
+        SET read_at = COALESCE(read_at, NOW())
+        WHERE id = $1 AND user_id = $2
+    `, notificationID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark notification read: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID int) (int64, error) {
+	tag, err := r.db.Exec(ctx, `
+        UPDATE notifications
+        SET read_at = NOW()
+        WHERE user_id = $1 AND read_at IS NULL
+    `, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (r *NotificationRepository) GetPreferences(ctx context.Context, userID int) (*Preferences, error) {
+	prefs := DefaultPreferences()
+
+	err := r.db.QueryRow(ctx, `
+        SELECT timezone, quiet_hours_enabled, quiet_hours_start, quiet_hours_end
+        FROM notification_settings
+        WHERE user_id = $1
+    `, userID).Scan(&prefs.Timezone, &prefs.QuietHours.Enabled, &prefs.QuietHours.Start, &prefs.QuietHours.End)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to load notification settings: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, `
+        SELECT notification_type, channel
+        FROM notification_preferences
+        WHERE user_id = $1
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var notificationType, channel string
+		if err := rows.Scan(&notificationType, &channel); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference: %w", err)
+		}
+		prefs.Channels[notificationType] = channel
+	}
+
+	return prefs, rows.Err()
+}
+
+func (r *NotificationRepository) UpdatePreferences(ctx context.Context, userID int, prefs *Preferences) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+        INSERT INTO notification_settings (user_id, timezone, quiet_hours_enabled, quiet_hours_start, quiet_hours_end, updated_at)
+        VALUES ($1, $2, $3, $4, $5, NOW())
+        ON CONFLICT (user_id) DO UPDATE
+        SET timezone = $2, quiet_hours_enabled = $3, quiet_hours_start = $4, quiet_hours_end = $5, updated_at = NOW()
+    `, userID, prefs.Timezone, prefs.QuietHours.Enabled, prefs.QuietHours.Start, prefs.QuietHours.End)
+	if err != nil {
+		return fmt.Errorf("failed to save notification settings: %w", err)
+	}
+
+	for notificationType, channel := range prefs.Channels {
+		_, err = tx.Exec(ctx, `
+            INSERT INTO notification_preferences (user_id, notification_type, channel)
+            VALUES ($1, $2, $3)
+            ON CONFLICT (user_id, notification_type) DO UPDATE SET channel = $3
+        `, userID, notificationType, channel)
+		if err != nil {
+			return fmt.Errorf("failed to save notification preference: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// PreferencesFor loads the settings and the channel for one notification
+// type of every user in userIDs, filling in defaults for users that never
+// saved any
+func (r *NotificationRepository) PreferencesFor(ctx context.Context, userIDs []int, notificationType string) (map[int]*Preferences, error) {
+	prefs := make(map[int]*Preferences, len(userIDs))
+	for _, userID := range userIDs {
+		prefs[userID] = DefaultPreferences()
+	}
+	if len(userIDs) == 0 {
+		return prefs, nil
+	}
+
+	rows, err := r.db.Query(ctx, `
+        SELECT u.id, s.timezone, s.quiet_hours_enabled, s.quiet_hours_start, s.quiet_hours_end, p.channel
+        FROM unnest($1::int[]) AS u(id)
+        LEFT JOIN notification_settings s ON s.user_id = u.id
+        LEFT JOIN notification_preferences p ON p.user_id = u.id AND p.notification_type = $2
+        WHERE s.user_id IS NOT NULL OR p.user_id IS NOT NULL
+    `, userIDs, notificationType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			userID               int
+			timezone, start, end *string
+			quietEnabled         *bool
+			channel              *string
+		)
+		if err := rows.Scan(&userID, &timezone, &quietEnabled, &start, &end, &channel); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference: %w", err)
+		}
+
+		p, ok := prefs[userID]
+		if !ok {
+			continue
+		}
+		if timezone != nil {
+			p.Timezone = *timezone
+			p.QuietHours = QuietHours{Enabled: *quietEnabled, Start: *start, End: *end}
+		}
+		if channel != nil {
+			p.Channels[notificationType] = *channel
+		}
+	}
+
+	return prefs, rows.Err()
+}
+
+func (r *NotificationRepository) GetUserEmail(ctx context.Context, userID int) (string, error) {
+	var email string
+	if err := r.db.QueryRow(ctx, `SELECT email FROM users WHERE id = $1`, userID).Scan(&email); err != nil {
+		return "", fmt.Errorf("failed to get user email: %w", err)
+	}
+	return email, nil
+}
@@ -0,0 +1,123 @@
+package push
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"zync-stream/netguard"
+)
+
+type PushHandlers struct {
+	repo  *PushRepository
+	vapid *VAPID
+}
+
+func NewPushHandlers(repo *PushRepository, vapid *VAPID) *PushHandlers {
+	return &PushHandlers{
+		repo:  repo,
+		vapid: vapid,
+	}
+}
+
+// GetPublicKey returns the applicationServerKey for pushManager.subscribe()
+func (h *PushHandlers) GetPublicKey(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"public_key": h.vapid.PublicKey})
+}
+
+func (h *PushHandlers) Subscribe(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req SubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription"})
+		return
+	}
+
+	if endpoint, err := url.Parse(req.Endpoint); err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Push endpoint must be an https URL"})
+		return
+	}
+	if err := netguard.CheckURL(c.Request.Context(), req.Endpoint); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Push endpoint must be on a public host"})
+		return
+	}
+
+	if key, err := decodeKey(req.Keys.P256dh); err != nil || len(key) != 65 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid p256dh key"})
+		return
+	}
+	if secret, err := decodeKey(req.Keys.Auth); err != nil || len(secret) != 16 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid auth secret"})
+		return
+	}
+
+	sub := &Subscription{
+		UserID:   userID.(int),
+		Endpoint: req.Endpoint,
+		P256dh:   req.Keys.P256dh,
+		Auth:     req.Keys.Auth,
+	}
+
+	if req.ExpirationTime != nil {
+		expiresAt := time.UnixMilli(*req.ExpirationTime)
+		if !expiresAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Subscription has already expired"})
+			return
+		}
+		sub.ExpiresAt = &expiresAt
+	}
+
+	if userAgent := c.GetHeader("User-Agent"); userAgent != "" {
+		sub.UserAgent = &userAgent
+	}
+
+	if err := h.repo.Save(c.Request.Context(), sub); err != nil {
+		if errors.Is(err, ErrEndpointTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This browser is subscribed by another account"})
+			return
+		}
+		log.Printf("Error saving push subscription for user %d: %v", userID.(int), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save subscription"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"subscription": sub})
+}
+
+func (h *PushHandlers) Unsubscribe(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req struct {
+		Endpoint string `json:"endpoint" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	deleted, err := h.repo.Delete(c.Request.Context(), userID.(int), req.Endpoint)
+	if err != nil {
+		log.Printf("Error deleting push subscription for user %d: %v", userID.(int), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove subscription"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unsubscribed"})
+}
@@ -0,0 +1,52 @@
+package push
+
+import "time"
+
+// Notification types that are pushed to users without a live connection
+const (
+	TypeRoomInvitation        = "room_invitation"
+	TypeFriendRequestReceived = "friend_request_received"
+	TypeFriendWatching        = "friend_watching"
+	TypeDirectMessage         = "direct_message"
+)
+
+// PushableTypes are delivered via Web Push when the recipient is offline
+var PushableTypes = []string{TypeRoomInvitation, TypeFriendRequestReceived, TypeFriendWatching, TypeDirectMessage}
+
+type Subscription struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	Endpoint  string     `json:"endpoint"`
+	P256dh    string     `json:"-"`
+	Auth      string     `json:"-"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	UserAgent *string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Message is the JSON body the service worker receives
+type Message struct {
+	Type  string                 `json:"type"`
+	Title string                 `json:"title"`
+	Body  string                 `json:"body"`
+	Data  map[string]interface{} `json:"data,omitempty"`
+}
+
+// SubscribeRequest mirrors PushSubscription.toJSON() from the browser
+type SubscribeRequest struct {
+	Endpoint       string `json:"endpoint" binding:"required"`
+	ExpirationTime *int64 `json:"expirationTime"` // milliseconds since epoch
+	Keys           struct {
+		P256dh string `json:"p256dh" binding:"required"`
+		Auth   string `json:"auth" binding:"required"`
+	} `json:"keys" binding:"required"`
+}
+
+func IsPushable(notificationType string) bool {
+	for _, t := range PushableTypes {
+		if t == notificationType {
+			return true
+		}
+	}
+	return false
+}
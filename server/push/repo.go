@@ -0,0 +1,106 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PushRepository stores browser push subscriptions
+type PushRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPushRepository creates a new PushRepository
+func NewPushRepository(db *pgxpool.Pool) *PushRepository {
+	return &PushRepository{db: db}
+}
+
+// ErrEndpointTaken is returned when another user owns the endpoint. Knowing an
+// endpoint proves nothing, so it is only released when its owner unsubscribes.
+var ErrEndpointTaken = errors.New("push endpoint belongs to another user")
+
+// Save registers a subscription, refreshing the keys when the user
+// re-subscribes the same endpoint
+func (r *PushRepository) Save(ctx context.Context, sub *Subscription) error {
+	query := `
+        INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth, expires_at, user_agent)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (endpoint) DO UPDATE
+        SET user_id = EXCLUDED.user_id,
+            p256dh = EXCLUDED.p256dh,
+            auth = EXCLUDED.auth,
+            expires_at = EXCLUDED.expires_at,
+            user_agent = EXCLUDED.user_agent
+        WHERE push_subscriptions.user_id = EXCLUDED.user_id
+        RETURNING id, created_at
+    `
+
+	err := r.db.QueryRow(ctx, query, sub.UserID, sub.Endpoint, sub.P256dh, sub.Auth, sub.ExpiresAt, sub.UserAgent).
+		Scan(&sub.ID, &sub.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrEndpointTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save push subscription: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes one of the user's subscriptions by endpoint
+func (r *PushRepository) Delete(ctx context.Context, userID int, endpoint string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM push_subscriptions WHERE user_id = $1 AND endpoint = $2`, userID, endpoint)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteByID removes a subscription the push service reported as gone
+func (r *PushRepository) DeleteByID(ctx context.Context, id int) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM push_subscriptions WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+	return nil
+}
+
+func (r *PushRepository) ListForUser(ctx context.Context, userID int) ([]Subscription, error) {
+	query := `
+        SELECT id, user_id, endpoint, p256dh, auth, expires_at, user_agent, created_at
+        FROM push_subscriptions
+        WHERE user_id = $1
+        ORDER BY created_at DESC
+    `
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query push subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []Subscription{}
+	for rows.Next() {
+		var sub Subscription
+		if err := rows.Scan(&sub.ID, &sub.UserID, &sub.Endpoint, &sub.P256dh, &sub.Auth,
+			&sub.ExpiresAt, &sub.UserAgent, &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan push subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+
+	return subs, rows.Err()
+}
+
+// PurgeExpired drops subscriptions whose browser-provided expiration has passed
+func (r *PushRepository) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM push_subscriptions WHERE expires_at IS NOT NULL AND expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired push subscriptions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,132 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"zync-stream/netguard"
+)
+
+const defaultTTL = 24 * time.Hour
+
+// Sender encrypts and delivers push messages to every subscription of a user
+type Sender struct {
+	repo   *PushRepository
+	vapid  *VAPID
+	client *http.Client
+}
+
+func NewSender(repo *PushRepository, vapid *VAPID) *Sender {
+	return &Sender{
+		repo:   repo,
+		vapid:  vapid,
+		client: netguard.NewClient(10 * time.Second), // endpoints come from browsers, so anyone can pick them
+	}
+}
+
+// Send pushes msg to all of the user's browsers and returns how many accepted it.
+// Expired subscriptions and those the push service reports as gone are removed.
+func (s *Sender) Send(ctx context.Context, userID int, msg Message) (int, error) {
+	subs, err := s.repo.ListForUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal push message: %w", err)
+	}
+
+	delivered := 0
+	now := time.Now()
+	for _, sub := range subs {
+		if sub.ExpiresAt != nil && !sub.ExpiresAt.After(now) {
+			s.remove(ctx, sub, "expired")
+			continue
+		}
+
+		statusCode, err := s.deliver(ctx, sub, payload)
+		switch {
+		case statusCode == http.StatusNotFound || statusCode == http.StatusGone:
+			s.remove(ctx, sub, fmt.Sprintf("push service returned %d", statusCode))
+		case err != nil:
+			log.Printf("Push to subscription %d for user %d failed: %v", sub.ID, userID, err)
+		default:
+			delivered++
+		}
+	}
+
+	return delivered, nil
+}
+
+func (s *Sender) deliver(ctx context.Context, sub Subscription, payload []byte) (int, error) {
+	body, err := encrypt(payload, sub.P256dh, sub.Auth)
+	if err != nil {
+		return 0, err
+	}
+
+	authorization, err := s.vapid.AuthorizationHeader(sub.Endpoint)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("invalid push request: %w", err)
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(defaultTTL.Seconds())))
+	req.Header.Set("Urgency", "normal")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("push service responded with %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (s *Sender) remove(ctx context.Context, sub Subscription, reason string) {
+	if err := s.repo.DeleteByID(ctx, sub.ID); err != nil {
+		log.Printf("Failed to remove push subscription %d: %v", sub.ID, err)
+		return
+	}
+	log.Printf("Removed push subscription %d for user %d (%s)", sub.ID, sub.UserID, reason)
+}
+
+// StartExpiryPurger periodically removes subscriptions past their expiration time
+func StartExpiryPurger(ctx context.Context, repo *PushRepository, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				purged, err := repo.PurgeExpired(ctx, time.Now())
+				if err != nil {
+					log.Printf("Failed to purge expired push subscriptions: %v", err)
+				} else if purged > 0 {
+					log.Printf("Purged %d expired push subscriptions", purged)
+				}
+			}
+		}
+	}()
+}
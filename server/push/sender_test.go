@@ -0,0 +1,230 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// browser is the user agent side of a subscription: it holds the keys the
+// push service would hand to the service worker
+type browser struct {
+	private *ecdh.PrivateKey
+	auth    []byte
+}
+
+func newBrowser(t *testing.T) browser {
+	t.Helper()
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	if _, err := rand.Read(auth); err != nil {
+		t.Fatal(err)
+	}
+	return browser{private: key, auth: auth}
+}
+
+func (b browser) subscription(endpoint string) Subscription {
+	return Subscription{
+		ID:       1,
+		UserID:   7,
+		Endpoint: endpoint,
+		P256dh:   base64.RawURLEncoding.EncodeToString(b.private.PublicKey().Bytes()),
+		Auth:     base64.RawURLEncoding.EncodeToString(b.auth),
+	}
+}
+
+// decrypt reverses encrypt the way a browser does (RFC 8291)
+func (b browser) decrypt(t *testing.T, body []byte) []byte {
+	t.Helper()
+	if len(body) < 21 {
+		t.Fatalf("body too short: %d bytes", len(body))
+	}
+	salt := body[:16]
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != recordSize {
+		t.Errorf("record size = %d", rs)
+	}
+	keyLen := int(body[20])
+	asPublicBytes := body[21 : 21+keyLen]
+	ciphertext := body[21+keyLen:]
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, err := b.private.ECDH(asPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyInfo := append([]byte("WebPush: info\x00"), b.private.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, asPublicBytes...)
+	ikm, _ := hkdf.Key(sha256.New, shared, b.auth, string(keyInfo), 32)
+	cek, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	record, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("failed to decrypt push body: %v", err)
+	}
+	if len(record) == 0 || record[len(record)-1] != 0x02 {
+		t.Fatalf("record lacks the last-record delimiter")
+	}
+	return record[:len(record)-1]
+}
+
+func newTestVAPID(t *testing.T) *VAPID {
+	t.Helper()
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vapid, err := newVAPID(key, "mailto:test@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vapid
+}
+
+func TestDeliverToPushService(t *testing.T) {
+	vapid := newTestVAPID(t)
+	b := newBrowser(t)
+
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	sender := NewSender(nil, vapid)
+	sender.client = server.Client()
+	payload := []byte(`{"type":"room_invitation","title":"Movie night","body":"join us"}`)
+
+	statusCode, err := sender.deliver(context.Background(), b.subscription(server.URL+"/push/abc"), payload)
+	if err != nil || statusCode != http.StatusCreated {
+		t.Fatalf("deliver() = %d, %v", statusCode, err)
+	}
+
+	if got := header.Get("Content-Encoding"); got != "aes128gcm" {
+		t.Errorf("Content-Encoding = %q", got)
+	}
+	if got := header.Get("TTL"); got != "86400" {
+		t.Errorf("TTL = %q", got)
+	}
+	if got := b.decrypt(t, body); !bytes.Equal(got, payload) {
+		t.Errorf("decrypted payload = %s", got)
+	}
+
+	// Authorization: vapid t=<jwt>, k=<public key>
+	authorization := strings.TrimPrefix(header.Get("Authorization"), "vapid ")
+	parts := strings.Split(authorization, ", ")
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "t=") || parts[1] != "k="+vapid.PublicKey {
+		t.Fatalf("Authorization = %q", header.Get("Authorization"))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(strings.TrimPrefix(parts[0], "t="), claims, func(*jwt.Token) (interface{}, error) {
+		return &vapid.private.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"ES256"}), jwt.WithAudience(server.URL))
+	if err != nil {
+		t.Fatalf("VAPID token does not verify: %v", err)
+	}
+	if claims["sub"] != vapid.Subject {
+		t.Errorf("sub = %v", claims["sub"])
+	}
+}
+
+func TestDeliverReportsGoneSubscriptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	sender := NewSender(nil, newTestVAPID(t))
+	sender.client = server.Client()
+	b := newBrowser(t)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/gone", http.StatusGone},
+		{"/missing", http.StatusNotFound},
+		{"/throttled", http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		statusCode, err := sender.deliver(context.Background(), b.subscription(server.URL+tt.path), []byte(`{}`))
+		if err == nil {
+			t.Errorf("%s: deliver() succeeded, want an error", tt.path)
+		}
+		if statusCode != tt.wantStatus {
+			t.Errorf("%s: deliver() status = %d, want %d", tt.path, statusCode, tt.wantStatus)
+		}
+	}
+}
+
+func TestEncryptRejectsBadKeys(t *testing.T) {
+	b := newBrowser(t)
+	sub := b.subscription("https://push.example")
+
+	if _, err := encrypt([]byte("hi"), "not a key", sub.Auth); err == nil {
+		t.Error("encrypt() accepted an invalid p256dh key")
+	}
+	if _, err := encrypt(make([]byte, recordSize), sub.P256dh, sub.Auth); err == nil {
+		t.Error("encrypt() accepted an oversized payload")
+	}
+
+	// browsers may hand out padded keys
+	padded := base64.URLEncoding.EncodeToString(b.auth)
+	body, err := encrypt([]byte("hi"), sub.P256dh, padded)
+	if err != nil {
+		t.Fatalf("encrypt() with a padded auth secret error = %v", err)
+	}
+	if got := b.decrypt(t, body); string(got) != "hi" {
+		t.Errorf("decrypted payload = %q", got)
+	}
+}
+
+func TestDeliverRefusesInternalEndpoints(t *testing.T) {
+	hit := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	sender := NewSender(nil, newTestVAPID(t))
+	statusCode, err := sender.deliver(context.Background(), newBrowser(t).subscription(server.URL+"/push/abc"), []byte(`{}`))
+	if err == nil || statusCode != 0 || hit {
+		t.Errorf("deliver() to a loopback endpoint = %d, %v (reached: %v)", statusCode, err, hit)
+	}
+}
@@ -0,0 +1,106 @@
+package push
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// VAPID holds the application server key pair used to identify this server
+// to browser push services (RFC 8292)
+type VAPID struct {
+	PublicKey string // base64url uncompressed P-256 point, handed to the browser
+	Subject   string
+	private   *ecdsa.PrivateKey
+}
+
+// NewVAPIDFromEnv loads VAPID_PUBLIC_KEY / VAPID_PRIVATE_KEY (base64url, as
+// produced by the web-push tooling). Without them a throwaway pair is
+// generated, which invalidates every subscription on restart.
+func NewVAPIDFromEnv() (*VAPID, error) {
+	subject := os.Getenv("VAPID_SUBJECT")
+	if subject == "" {
+		subject = "mailto:admin@localhost"
+	}
+
+	privateKey := os.Getenv("VAPID_PRIVATE_KEY")
+	if privateKey == "" {
+		log.Println("warning: VAPID_PRIVATE_KEY not set, generating a temporary key pair")
+		key, err := ecdh.P256().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate VAPID key: %w", err)
+		}
+		return newVAPID(key, subject)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID_PRIVATE_KEY: %w", err)
+	}
+
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID_PRIVATE_KEY: %w", err)
+	}
+
+	vapid, err := newVAPID(key, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	if publicKey := os.Getenv("VAPID_PUBLIC_KEY"); publicKey != "" && publicKey != vapid.PublicKey {
+		return nil, fmt.Errorf("VAPID_PUBLIC_KEY does not match VAPID_PRIVATE_KEY")
+	}
+
+	return vapid, nil
+}
+
+func newVAPID(key *ecdh.PrivateKey, subject string) (*VAPID, error) {
+	point := key.PublicKey().Bytes() // 0x04 || X || Y
+
+	private := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(point[1:33]),
+			Y:     new(big.Int).SetBytes(point[33:65]),
+		},
+		D: new(big.Int).SetBytes(key.Bytes()),
+	}
+
+	return &VAPID{
+		PublicKey: base64.RawURLEncoding.EncodeToString(point),
+		Subject:   subject,
+		private:   private,
+	}, nil
+}
+
+// AuthorizationHeader returns the "vapid t=..., k=..." header for an endpoint
+func (v *VAPID) AuthorizationHeader(endpoint string) (string, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid push endpoint: %w", err)
+	}
+
+	claims := jwt.MapClaims{
+		"aud": parsed.Scheme + "://" + parsed.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": v.Subject,
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(v.private)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign VAPID token: %w", err)
+	}
+
+	return fmt.Sprintf("vapid t=%s, k=%s", token, v.PublicKey), nil
+}
@@ -0,0 +1,234 @@
+package reviews
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"zync-stream/metadata"
+	"zync-stream/rooms"
+)
+
+// RoomPublisher posts an event into a room's realtime channel
+type RoomPublisher func(roomID int, eventType string, userID int, username string, data map[string]interface{}) error
+
+type ReviewHandlers struct {
+	repo        *ReviewRepository
+	roomRepo    *rooms.RoomRepository
+	metadata    metadata.Provider
+	publishRoom RoomPublisher
+}
+
+func NewReviewHandlers(repo *ReviewRepository, roomRepo *rooms.RoomRepository, meta metadata.Provider, publishRoom RoomPublisher) *ReviewHandlers {
+	return &ReviewHandlers{
+		repo:        repo,
+		roomRepo:    roomRepo,
+		metadata:    meta,
+		publishRoom: publishRoom,
+	}
+}
+
+func (h *ReviewHandlers) GetReviews(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	imdbID := c.Param("imdb_id")
+
+	limit := DefaultPageSize
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = min(limit, MaxPageSize)
+	}
+
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		var err error
+		offset, err = strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+
+	// fetch one extra row to know whether another page exists
+	reviews, err := h.repo.ListForTitle(ctx, userID.(int), imdbID, limit+1, offset)
+	if err != nil {
+		log.Printf("Error loading reviews for %s: %v", imdbID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reviews"})
+		return
+	}
+
+	hasMore := len(reviews) > limit
+	if hasMore {
+		reviews = reviews[:limit]
+	}
+
+	summary, err := h.repo.GetSummary(ctx, userID.(int), imdbID)
+	if err != nil {
+		log.Printf("Error summarizing reviews for %s: %v", imdbID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reviews"})
+		return
+	}
+
+	mine, err := h.repo.GetUserReview(ctx, userID.(int), imdbID)
+	if err != nil {
+		log.Printf("Error loading own review for %s: %v", imdbID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reviews"})
+		return
+	}
+
+	response := gin.H{
+		"reviews":   reviews,
+		"summary":   summary,
+		"my_review": mine,
+		"has_more":  hasMore,
+	}
+	if hasMore {
+		response["next_offset"] = offset + limit
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *ReviewHandlers) RateTitle(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req RateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	if req.Rating < MinRating || req.Rating > MaxRating {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rating must be between 1 and 10"})
+		return
+	}
+
+	if req.Review != nil {
+		text := strings.TrimSpace(*req.Review)
+		if len(text) > MaxReviewLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Review is too long"})
+			return
+		}
+		if text == "" {
+			req.Review = nil
+		} else {
+			req.Review = &text
+		}
+	}
+
+	ctx := c.Request.Context()
+
+	if req.RoomID != nil {
+		isMember, _, err := h.roomRepo.IsRoomMember(ctx, *req.RoomID, userID.(int))
+		if err != nil {
+			log.Printf("Error checking room membership: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify room access"})
+			return
+		}
+		if !isMember {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this room"})
+			return
+		}
+	}
+
+	review := &Review{
+		UserID:    userID.(int),
+		ImdbID:    c.Param("imdb_id"),
+		MediaType: req.MediaType,
+		Rating:    req.Rating,
+		Review:    req.Review,
+	}
+
+	if err := h.repo.Upsert(ctx, review); err != nil {
+		log.Printf("Error saving review: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save rating"})
+		return
+	}
+
+	if req.RoomID != nil {
+		username := c.GetString("username")
+		go h.postRoomSummary(*req.RoomID, username, review)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"review": review})
+}
+
+func (h *ReviewHandlers) DeleteReview(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	deleted, err := h.repo.Delete(c.Request.Context(), userID.(int), c.Param("imdb_id"))
+	if err != nil {
+		log.Printf("Error deleting review: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rating"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You have not rated this title"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rating deleted"})
+}
+
+// postRoomSummary announces a rating in the room chat, using the title's
+// name when the metadata provider knows it
+func (h *ReviewHandlers) postRoomSummary(roomID int, username string, review *Review) {
+	if h.publishRoom == nil {
+		return
+	}
+
+	title := review.ImdbID
+	if h.metadata != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		meta, err := h.metadata.Meta(ctx, review.MediaType, review.ImdbID)
+		cancel()
+		if err == nil && meta.Name != "" {
+			title = meta.Name
+		}
+	}
+
+	message := fmt.Sprintf("%s rated %s %d/10", username, title, review.Rating)
+	if review.Review != nil {
+		message += ": " + *review.Review
+	}
+
+	data := map[string]interface{}{
+		"message": message,
+		"kind":    "review_summary",
+		"imdb_id": review.ImdbID,
+		"title":   title,
+		"rating":  review.Rating,
+	}
+	if review.Review != nil {
+		data["review"] = *review.Review
+	}
+
+	if err := h.publishRoom(roomID, "chat_message", review.UserID, username, data); err != nil {
+		log.Printf("Failed to post review summary to room %d: %v", roomID, err)
+	}
+}
@@ -0,0 +1,42 @@
+package reviews
+
+import "time"
+
+const (
+	MinRating       = 1
+	MaxRating       = 10
+	MaxReviewLength = 1000
+
+	DefaultPageSize = 20
+	MaxPageSize     = 50
+)
+
+type Review struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name,omitempty"`
+	AvatarURL   string    `json:"avatar_url,omitempty"`
+	ImdbID      string    `json:"imdb_id"`
+	MediaType   string    `json:"media_type"`
+	Rating      int       `json:"rating"`
+	Review      *string   `json:"review,omitempty"`
+	IsFriend    bool      `json:"is_friend"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Summary aggregates every rating of a title
+type Summary struct {
+	AverageRating float64 `json:"average_rating"`
+	RatingCount   int     `json:"rating_count"`
+	FriendAverage float64 `json:"friend_average_rating"`
+	FriendCount   int     `json:"friend_rating_count"`
+}
+
+type RateRequest struct {
+	MediaType string  `json:"media_type" binding:"required,oneof=movie series"`
+	Rating    int     `json:"rating" binding:"required"`
+	Review    *string `json:"review"`
+	RoomID    *int    `json:"room_id"` // post a summary into this room's chat
+}
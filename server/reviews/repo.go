@@ -0,0 +1,150 @@
+package reviews
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReviewRepository handles title ratings and reviews
+type ReviewRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewReviewRepository creates a new ReviewRepository
+func NewReviewRepository(db *pgxpool.Pool) *ReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+// Upsert stores the user's rating of a title, replacing an earlier one
+func (r *ReviewRepository) Upsert(ctx context.Context, review *Review) error {
+	query := `
+        INSERT INTO title_reviews (user_id, imdb_id, media_type, rating, review)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (user_id, imdb_id) DO UPDATE SET
+            media_type = EXCLUDED.media_type,
+            rating = EXCLUDED.rating,
+            review = EXCLUDED.review,
+            updated_at = NOW()
+        RETURNING id, created_at, updated_at
+    `
+
+	err := r.db.QueryRow(ctx, query, review.UserID, review.ImdbID, review.MediaType, review.Rating, review.Review).
+		Scan(&review.ID, &review.CreatedAt, &review.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save review: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ReviewRepository) Delete(ctx context.Context, userID int, imdbID string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM title_reviews WHERE user_id = $1 AND imdb_id = $2`, userID, imdbID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete review: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+const reviewSelect = `
+        SELECT tr.id, tr.user_id, u.username, COALESCE(u.display_name, ''),
+               COALESCE(u.profile_picture_url, ''), tr.imdb_id, tr.media_type,
+               tr.rating, tr.review,
+               EXISTS (
+                   SELECT 1 FROM friendships f
+                   WHERE ((f.user_id = $1 AND f.friend_id = tr.user_id) OR
+                       (f.user_id = tr.user_id AND f.friend_id = $1)) AND
+                       f.status = 'accepted'
+               ) AS is_friend,
+               tr.created_at, tr.updated_at
+        FROM title_reviews tr
+        JOIN users u ON u.id = tr.user_id
+    `
+
+func scanReview(row pgx.Row) (*Review, error) {
+	var review Review
+	err := row.Scan(&review.ID, &review.UserID, &review.Username, &review.DisplayName, &review.AvatarURL,
+		&review.ImdbID, &review.MediaType, &review.Rating, &review.Review, &review.IsFriend,
+		&review.CreatedAt, &review.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *ReviewRepository) GetUserReview(ctx context.Context, userID int, imdbID string) (*Review, error) {
+	query := reviewSelect + ` WHERE tr.user_id = $1 AND tr.imdb_id = $2`
+
+	review, err := scanReview(r.db.QueryRow(ctx, query, userID, imdbID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get review: %w", err)
+	}
+
+	return review, nil
+}
+
+// ListForTitle returns other users' reviews of a title, friends first, then
+// newest. Blocked users and accounts pending deletion are left out.
+func (r *ReviewRepository) ListForTitle(ctx context.Context, viewerID int, imdbID string, limit, offset int) ([]*Review, error) {
+	query := reviewSelect + `
+        WHERE tr.imdb_id = $2 AND tr.user_id <> $1 AND
+            u.deletion_scheduled_at IS NULL AND
+            NOT EXISTS (
+                SELECT 1 FROM user_blocks b
+                WHERE (b.blocker_id = $1 AND b.blocked_id = tr.user_id) OR
+                    (b.blocker_id = tr.user_id AND b.blocked_id = $1)
+            )
+        ORDER BY is_friend DESC, tr.updated_at DESC, tr.id DESC
+        LIMIT $3 OFFSET $4
+    `
+
+	rows, err := r.db.Query(ctx, query, viewerID, imdbID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []*Review{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, rows.Err()
+}
+
+func (r *ReviewRepository) GetSummary(ctx context.Context, viewerID int, imdbID string) (*Summary, error) {
+	query := `
+        SELECT COALESCE(AVG(rating), 0), COUNT(*),
+               COALESCE(AVG(rating) FILTER (WHERE is_friend), 0),
+               COUNT(*) FILTER (WHERE is_friend)
+        FROM (
+            SELECT tr.rating, EXISTS (
+                SELECT 1 FROM friendships f
+                WHERE ((f.user_id = $1 AND f.friend_id = tr.user_id) OR
+                    (f.user_id = tr.user_id AND f.friend_id = $1)) AND
+                    f.status = 'accepted'
+            ) AS is_friend
+            FROM title_reviews tr
+            WHERE tr.imdb_id = $2
+        ) ratings
+    `
+
+	var summary Summary
+	err := r.db.QueryRow(ctx, query, viewerID, imdbID).
+		Scan(&summary.AverageRating, &summary.RatingCount, &summary.FriendAverage, &summary.FriendCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize ratings: %w", err)
+	}
+
+	return &summary, nil
+}
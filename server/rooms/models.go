@@ -1,6 +1,7 @@
 package rooms
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -40,13 +41,18 @@ type RoomMember struct {
 }
 
 type PlaybackState struct {
-	RoomID          int       `json:"room_id"`
-	MediaURL        string    `json:"media_url"`
-	CurrentPosition float64   `json:"position"`
-	IsPlaying       bool      `json:"is_playing"`
-	PlaybackRate    float64   `json:"playback_rate"`
-	UpdatedBy       int       `json:"updated_by"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	RoomID          int             `json:"room_id"`
+	MediaURL        string          `json:"media_url"`
+	MediaType       string          `json:"media_type,omitempty"`
+	MediaID         string          `json:"media_id,omitempty"`
+	Stream          json.RawMessage `json:"stream,omitempty"`
+	SubtitleTrack   json.RawMessage `json:"subtitle_track,omitempty"`
+	SubtitleOffset  int             `json:"subtitle_offset_ms"`
+	CurrentPosition float64         `json:"position"`
+	IsPlaying       bool            `json:"is_playing"`
+	PlaybackRate    float64         `json:"playback_rate"`
+	UpdatedBy       int             `json:"updated_by"`
+	UpdatedAt       time.Time       `json:"updated_at"`
 }
 
 type Viewer struct {
@@ -0,0 +1,155 @@
+package rooms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// playback state outlives an empty room for this long
+const playbackTTL = 24 * time.Hour
+
+// PlaybackStore keeps each room's authoritative playback state in a Redis
+// hash, so members joining late load the same stream at the same position
+type PlaybackStore struct {
+	redis *redis.Client
+}
+
+func NewPlaybackStore(redisClient *redis.Client) *PlaybackStore {
+	return &PlaybackStore{redis: redisClient}
+}
+
+func playbackKey(roomID int) string {
+	return fmt.Sprintf("room:%d:playback", roomID)
+}
+
+func subtitleOverridesKey(roomID int) string {
+	return fmt.Sprintf("room:%d:subtitle_overrides", roomID)
+}
+
+// Get returns nil when nothing has been played in the room recently
+func (s *PlaybackStore) Get(ctx context.Context, roomID int) (*PlaybackState, error) {
+	fields, err := s.redis.HGetAll(ctx, playbackKey(roomID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playback state: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	state := &PlaybackState{
+		RoomID:       roomID,
+		MediaURL:     fields["media_url"],
+		MediaType:    fields["media_type"],
+		MediaID:      fields["media_id"],
+		IsPlaying:    fields["is_playing"] == "1",
+		PlaybackRate: 1,
+	}
+	if stream := fields["stream"]; stream != "" {
+		state.Stream = []byte(stream)
+	}
+	if track := fields["subtitle_track"]; track != "" {
+		state.SubtitleTrack = []byte(track)
+	}
+	state.SubtitleOffset, _ = strconv.Atoi(fields["subtitle_offset"])
+	state.CurrentPosition, _ = strconv.ParseFloat(fields["position"], 64)
+	if rate, err := strconv.ParseFloat(fields["playback_rate"], 64); err == nil && rate > 0 {
+		state.PlaybackRate = rate
+	}
+	state.UpdatedBy, _ = strconv.Atoi(fields["updated_by"])
+	if updatedAt, err := strconv.ParseInt(fields["updated_at"], 10, 64); err == nil {
+		state.UpdatedAt = time.Unix(updatedAt, 0)
+	}
+
+	return state, nil
+}
+
+// SetStream records the stream the room will play and rewinds to the start.
+// Subtitles chosen for the previous stream are cleared along with it.
+func (s *PlaybackStore) SetStream(ctx context.Context, roomID, userID int, mediaType, mediaID, mediaURL string, stream []byte) error {
+	if err := s.redis.Del(ctx, subtitleOverridesKey(roomID)).Err(); err != nil {
+		return fmt.Errorf("failed to clear subtitle overrides: %w", err)
+	}
+
+	return s.set(ctx, roomID, map[string]interface{}{
+		"media_type":      mediaType,
+		"media_id":        mediaID,
+		"media_url":       mediaURL,
+		"stream":          string(stream),
+		"subtitle_track":  "",
+		"subtitle_offset": 0,
+		"position":        0,
+		"is_playing":      0,
+		"updated_by":      userID,
+	})
+}
+
+// SetSubtitles sets the room-wide subtitle track; an empty track turns them off
+func (s *PlaybackStore) SetSubtitles(ctx context.Context, roomID, userID int, track []byte, offsetMS int) error {
+	return s.set(ctx, roomID, map[string]interface{}{
+		"subtitle_track":  string(track),
+		"subtitle_offset": offsetMS,
+		"updated_by":      userID,
+	})
+}
+
+// GetSubtitleOverride returns the member's own subtitle selection, or nil
+// when they follow the room's
+func (s *PlaybackStore) GetSubtitleOverride(ctx context.Context, roomID, userID int) ([]byte, error) {
+	value, err := s.redis.HGet(ctx, subtitleOverridesKey(roomID), strconv.Itoa(userID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subtitle override: %w", err)
+	}
+	return value, nil
+}
+
+func (s *PlaybackStore) SetSubtitleOverride(ctx context.Context, roomID, userID int, selection []byte) error {
+	key := subtitleOverridesKey(roomID)
+
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, key, strconv.Itoa(userID), string(selection))
+	pipe.Expire(ctx, key, playbackTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save subtitle override: %w", err)
+	}
+	return nil
+}
+
+func (s *PlaybackStore) ClearSubtitleOverride(ctx context.Context, roomID, userID int) error {
+	if err := s.redis.HDel(ctx, subtitleOverridesKey(roomID), strconv.Itoa(userID)).Err(); err != nil {
+		return fmt.Errorf("failed to clear subtitle override: %w", err)
+	}
+	return nil
+}
+
+func (s *PlaybackStore) UpdatePosition(ctx context.Context, roomID, userID int, position float64, playing bool) error {
+	isPlaying := 0
+	if playing {
+		isPlaying = 1
+	}
+	return s.set(ctx, roomID, map[string]interface{}{
+		"position":   position,
+		"is_playing": isPlaying,
+		"updated_by": userID,
+	})
+}
+
+func (s *PlaybackStore) set(ctx context.Context, roomID int, fields map[string]interface{}) error {
+	key := playbackKey(roomID)
+	fields["updated_at"] = time.Now().Unix()
+
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, key, fields)
+	pipe.Expire(ctx, key, playbackTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save playback state: %w", err)
+	}
+	return nil
+}
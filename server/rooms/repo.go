@@ -252,6 +252,25 @@ func (r *RoomRepository) InviteToRoom(ctx context.Context, roomID, inviterID, in
 		return 0, errors.New("you don't have permission to invite users to this room")
 	}
 
+	// Blocks apply in both directions
+	var blocked bool
+	blockQuery := `
+        SELECT EXISTS(
+            SELECT 1 FROM user_blocks
+            WHERE (blocker_id = $1 AND blocked_id = $2) OR
+                (blocker_id = $2 AND blocked_id = $1)
+        )
+    `
+
+	err = r.db.QueryRow(ctx, blockQuery, inviterID, inviteeID).Scan(&blocked)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check blocks: %w", err)
+	}
+
+	if blocked {
+		return 0, errors.New("you cannot invite this user")
+	}
+
 	// Check if already has pending invitation
 	var existingInvitation bool
 	existQuery := `
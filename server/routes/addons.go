@@ -0,0 +1,65 @@
+package routes
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+
+	"zync-stream/addons"
+	"zync-stream/httpcache"
+	"zync-stream/middleware"
+	"zync-stream/rooms"
+	"zync-stream/ws"
+)
+
+func SetupAddonRoutes(ctx context.Context, router *gin.Engine, dbPool *pgxpool.Pool, redisClient *redis.Client, responseCache *httpcache.Cache) {
+	addonRepo := addons.NewAddonRepository(dbPool)
+	aggregator := addons.NewAggregatorFromEnv(addonRepo, responseCache)
+	manifestClient := addons.NewManifestClient()
+	registryRepo := addons.NewRegistryRepository(dbPool)
+	addonHandlers := addons.NewAddonHandlers(addonRepo, aggregator, responseCache)
+	registryHandlers := addons.NewRegistryHandlers(registryRepo, addonRepo, manifestClient)
+	roomAddonHandlers := addons.NewRoomAddonHandlers(addonRepo, manifestClient, aggregator,
+		rooms.NewRoomRepository(dbPool), rooms.NewPlaybackStore(redisClient), ws.PublishRoomEvent)
+
+	addons.StartRegistryHealthChecker(ctx, registryRepo, manifestClient, 30*time.Minute)
+
+	addonGroup := router.Group("/api/addons")
+	addonGroup.Use(middleware.AuthMiddleware())
+	{
+		addonGroup.GET("/catalog/:type/:id", addonHandlers.GetCatalog)
+		addonGroup.GET("/meta/:type/:id", addonHandlers.GetMeta)
+		addonGroup.GET("/stream/:type/:id", addonHandlers.GetStreams)
+		addonGroup.POST("/streams/report", addonHandlers.ReportStream)
+		addonGroup.GET("/registry", registryHandlers.GetRegistry)
+		addonGroup.POST("/registry/:entryId/install", registryHandlers.InstallEntry)
+	}
+
+	// registry curation
+	adminGroup := router.Group("/api/addons/registry")
+	adminGroup.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware(dbPool))
+	{
+		adminGroup.POST("", registryHandlers.CreateEntry)
+		adminGroup.PUT("/:entryId", registryHandlers.UpdateEntry)
+		adminGroup.DELETE("/:entryId", registryHandlers.DeleteEntry)
+	}
+
+	cacheGroup := router.Group("/api/addons/cache")
+	cacheGroup.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware(dbPool))
+	{
+		cacheGroup.GET("/stats", addonHandlers.GetCacheStats)
+	}
+
+	roomGroup := router.Group("/api/rooms")
+	roomGroup.Use(middleware.AuthMiddleware())
+	{
+		roomGroup.GET("/:id/addons", roomAddonHandlers.GetRoomAddons)
+		roomGroup.PUT("/:id/addons", roomAddonHandlers.SetRoomAddons)
+		roomGroup.GET("/:id/streams/:type/:videoId", roomAddonHandlers.GetRoomStreams)
+		roomGroup.PUT("/:id/stream", roomAddonHandlers.SelectRoomStream)
+		roomGroup.GET("/:id/playback", roomAddonHandlers.GetRoomPlayback)
+	}
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"zync-stream/dm"
+	"zync-stream/middleware"
+	"zync-stream/ws"
+)
+
+func SetupDMRoutes(router *gin.Engine, dbPool *pgxpool.Pool) {
+	dmRepo := dm.NewDMRepository(dbPool)
+	dmHandlers := dm.NewDMHandlers(dmRepo, ws.PublishUserEvent)
+	ws.SetDMRepository(dmRepo)
+
+	dmGroup := router.Group("/api/dm")
+	dmGroup.Use(middleware.AuthMiddleware())
+	{
+		dmGroup.GET("/unread", dmHandlers.GetUnreadCounts)
+		dmGroup.GET("/:userId", dmHandlers.GetConversation)
+		dmGroup.POST("/:userId/read", dmHandlers.MarkRead)
+	}
+}
@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"zync-stream/hls"
+	"zync-stream/middleware"
+	"zync-stream/rooms"
+	"zync-stream/ws"
+)
+
+func SetupHLSRoutes(ctx context.Context, router *gin.Engine, dbPool *pgxpool.Pool) {
+	manager, err := hls.NewManagerFromEnv()
+	if err != nil {
+		log.Printf("Warning: HLS conversion disabled: %v", err)
+		return
+	}
+
+	hls.StartCleaner(ctx, manager, time.Minute)
+
+	hlsHandlers := hls.NewHLSHandlers(manager, rooms.NewRoomRepository(dbPool), ws.PublishRoomEvent)
+
+	// public, players fetch segments without a token
+	router.GET(hls.PathPrefix+"/:job/:file", hlsHandlers.ServeFile)
+
+	jobGroup := router.Group("/api/hls/jobs")
+	jobGroup.Use(middleware.AuthMiddleware())
+	{
+		jobGroup.POST("", hlsHandlers.StartJob)
+		jobGroup.GET("/:job", hlsHandlers.GetJob)
+		jobGroup.DELETE("/:job", hlsHandlers.CancelJob)
+	}
+
+	roomGroup := router.Group("/api/rooms")
+	roomGroup.Use(middleware.AuthMiddleware())
+	{
+		roomGroup.GET("/:id/hls", hlsHandlers.GetRoomJob)
+	}
+}
@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"context"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"zync-stream/imports"
+	"zync-stream/metadata"
+	"zync-stream/middleware"
+	"zync-stream/users"
+)
+
+func SetupImportRoutes(ctx context.Context, router *gin.Engine, dbPool *pgxpool.Pool, userRepo *users.UserRepo, metaProvider metadata.Provider) {
+	importRepo := imports.NewImportRepository(dbPool)
+	if closed, err := importRepo.FailInterrupted(ctx); err != nil {
+		log.Printf("Warning: %v", err)
+	} else if closed > 0 {
+		log.Printf("Marked %d interrupted import jobs as failed", closed)
+	}
+
+	importHandlers := imports.NewImportHandlers(importRepo, userRepo, imports.NewRunner(ctx, importRepo, metaProvider))
+
+	importGroup := router.Group("/api/imports")
+	importGroup.Use(middleware.AuthMiddleware())
+	{
+		importGroup.GET("", importHandlers.GetJobs)
+		importGroup.GET("/:id", importHandlers.GetJob)
+		importGroup.POST("/trakt", importHandlers.ImportTrakt)
+		importGroup.POST("/letterboxd", importHandlers.ImportLetterboxd)
+	}
+
+	exportGroup := router.Group("/api/exports")
+	exportGroup.Use(middleware.AuthMiddleware())
+	{
+		exportGroup.GET("/watch-history", importHandlers.ExportWatchHistory)
+	}
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"zync-stream/media"
+)
+
+func SetupMediaRoutes(router *gin.Engine, avatarStorage media.Storage) {
+	mediaHandlers := media.NewHandlers(avatarStorage)
+
+	// public so avatars can be used directly as <img> sources
+	router.GET("/media/avatars/*path", mediaHandlers.ServeAvatar)
+	router.HEAD("/media/avatars/*path", mediaHandlers.ServeAvatar)
+}
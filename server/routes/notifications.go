@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"zync-stream/middleware"
+	"zync-stream/notifications"
+	"zync-stream/ws"
+)
+
+func SetupNotificationRoutes(router *gin.Engine, dbPool *pgxpool.Pool) {
+	notificationRepo := notifications.NewNotificationRepository(dbPool)
+	notificationHandlers := notifications.NewNotificationHandlers(notificationRepo, ws.PublishUserEvent)
+	ws.SetNotificationRepository(notificationRepo)
+	ws.SetMailer(notifications.NewMailerFromEnv())
+
+	notificationGroup := router.Group("/api/notifications")
+	notificationGroup.Use(middleware.AuthMiddleware())
+	{
+		notificationGroup.GET("", notificationHandlers.GetNotifications)
+		notificationGroup.GET("/unread-count", notificationHandlers.GetUnreadCount)
+		notificationGroup.GET("/preferences", notificationHandlers.GetPreferences)
+		notificationGroup.PUT("/preferences", notificationHandlers.UpdatePreferences)
+		notificationGroup.POST("/read-all", notificationHandlers.MarkAllRead)
+		notificationGroup.POST("/:id/read", notificationHandlers.MarkRead)
+	}
+}
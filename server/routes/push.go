@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"zync-stream/middleware"
+	"zync-stream/push"
+	"zync-stream/ws"
+)
+
+func SetupPushRoutes(ctx context.Context, router *gin.Engine, dbPool *pgxpool.Pool) {
+	vapid, err := push.NewVAPIDFromEnv()
+	if err != nil {
+		log.Printf("Warning: Web Push disabled: %v", err)
+		return
+	}
+
+	pushRepo := push.NewPushRepository(dbPool)
+	pushHandlers := push.NewPushHandlers(pushRepo, vapid)
+	ws.SetPushSender(push.NewSender(pushRepo, vapid))
+
+	push.StartExpiryPurger(ctx, pushRepo, time.Hour)
+
+	pushGroup := router.Group("/api/push")
+	pushGroup.GET("/vapid-public-key", pushHandlers.GetPublicKey)
+	pushGroup.Use(middleware.AuthMiddleware())
+	{
+		pushGroup.POST("/subscriptions", pushHandlers.Subscribe)
+		pushGroup.DELETE("/subscriptions", pushHandlers.Unsubscribe)
+	}
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"zync-stream/metadata"
+	"zync-stream/middleware"
+	"zync-stream/reviews"
+	"zync-stream/rooms"
+	"zync-stream/ws"
+)
+
+func SetupReviewRoutes(router *gin.Engine, dbPool *pgxpool.Pool, metaProvider metadata.Provider) {
+	reviewRepo := reviews.NewReviewRepository(dbPool)
+	reviewHandlers := reviews.NewReviewHandlers(reviewRepo, rooms.NewRoomRepository(dbPool), metaProvider, ws.PublishRoomEvent)
+
+	titleGroup := router.Group("/api/titles")
+	titleGroup.Use(middleware.AuthMiddleware())
+	{
+		titleGroup.GET("/:imdb_id/reviews", reviewHandlers.GetReviews)
+		titleGroup.PUT("/:imdb_id/review", reviewHandlers.RateTitle)
+		titleGroup.DELETE("/:imdb_id/review", reviewHandlers.DeleteReview)
+	}
+}
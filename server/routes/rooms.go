@@ -13,6 +13,8 @@ import (
 func SetupRoomRoutes(router *gin.Engine, dbPool *pgxpool.Pool, redisClient *redis.Client) {
 	roomRepo := rooms.NewRoomRepository(dbPool)
 	roomHandlers := rooms.NewRoomHandlers(roomRepo, redisClient)
+	ws.SetRoomRepository(roomRepo)
+	ws.SetPlaybackStore(rooms.NewPlaybackStore(redisClient))
 
 	roomGroup := router.Group("/api/rooms")
 	roomGroup.Use(middleware.AuthMiddleware())
@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"zync-stream/middleware"
+	"zync-stream/rooms"
+	"zync-stream/sessions"
+	"zync-stream/users"
+	"zync-stream/ws"
+)
+
+func SetupSessionRoutes(router *gin.Engine, dbPool *pgxpool.Pool, userRepo *users.UserRepo) {
+	sessionRepo := sessions.NewSessionRepository(dbPool)
+	sessionHandlers := sessions.NewSessionHandlers(sessionRepo, rooms.NewRoomRepository(dbPool))
+
+	// sessions still open were cut short by a restart
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if closed, err := sessionRepo.CloseAbandoned(ctx); err != nil {
+		log.Printf("Failed to close abandoned room sessions: %v", err)
+	} else if closed > 0 {
+		log.Printf("Closed %d abandoned room sessions", closed)
+	}
+	cancel()
+
+	ws.SetSessionTracker(sessions.NewTracker(sessionRepo, userRepo))
+
+	roomGroup := router.Group("/api/rooms")
+	roomGroup.Use(middleware.AuthMiddleware())
+	{
+		roomGroup.GET("/:id/sessions", sessionHandlers.GetRoomSessions)
+	}
+}
@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+
+	"zync-stream/addons"
+	"zync-stream/httpcache"
+	"zync-stream/middleware"
+	"zync-stream/rooms"
+	"zync-stream/subtitles"
+	"zync-stream/ws"
+)
+
+func SetupSubtitleRoutes(router *gin.Engine, dbPool *pgxpool.Pool, redisClient *redis.Client, responseCache *httpcache.Cache) {
+	aggregator := addons.NewAggregatorFromEnv(addons.NewAddonRepository(dbPool), responseCache)
+	subtitleHandlers := subtitles.NewSubtitleHandlers(aggregator, responseCache, subtitles.NewSignerFromEnv(),
+		rooms.NewRoomRepository(dbPool), rooms.NewPlaybackStore(redisClient), ws.PublishRoomEvent)
+
+	// signed, so <track> elements can load it without a token
+	router.GET(subtitles.ProxyPath, subtitleHandlers.ServeFile)
+
+	subtitleGroup := router.Group("/api/subtitles")
+	subtitleGroup.Use(middleware.AuthMiddleware())
+	{
+		subtitleGroup.GET("/:type/:id", subtitleHandlers.GetSubtitles)
+	}
+
+	roomGroup := router.Group("/api/rooms")
+	roomGroup.Use(middleware.AuthMiddleware())
+	{
+		roomGroup.PUT("/:id/subtitles", subtitleHandlers.SetRoomSubtitles)
+		roomGroup.PUT("/:id/subtitles/me", subtitleHandlers.SetMySubtitles)
+		roomGroup.DELETE("/:id/subtitles/me", subtitleHandlers.ClearMySubtitles)
+	}
+}
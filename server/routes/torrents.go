@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"zync-stream/middleware"
+	"zync-stream/torrents"
+)
+
+func SetupTorrentRoutes(ctx context.Context, router *gin.Engine) {
+	config, enabled := torrents.ConfigFromEnv()
+	if !enabled {
+		log.Println("Torrent streaming disabled, set TORRENT_ENABLED=true to enable it")
+		return
+	}
+
+	manager, err := torrents.NewManager(config)
+	if err != nil {
+		log.Printf("Warning: Torrent streaming disabled: %v", err)
+		return
+	}
+
+	torrents.StartIdleEvictor(ctx, manager, time.Minute)
+	go func() {
+		<-ctx.Done()
+		manager.Close()
+	}()
+
+	torrentHandlers := torrents.NewTorrentHandlers(manager)
+
+	router.GET(torrents.StreamPathPrefix+"/:infoHash/:fileIdx", torrentHandlers.StreamFile)
+
+	torrentGroup := router.Group("/api/torrents")
+	torrentGroup.Use(middleware.AuthMiddleware())
+	{
+		torrentGroup.GET("", torrentHandlers.GetUsage)
+		torrentGroup.POST("/add", torrentHandlers.AddTorrent)
+		torrentGroup.GET("/:infoHash", torrentHandlers.GetTorrent)
+		torrentGroup.DELETE("/:infoHash", torrentHandlers.RemoveTorrent)
+	}
+}
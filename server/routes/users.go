@@ -1,17 +1,27 @@
 package routes
 
 import (
+	"context"
+
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 
+	"zync-stream/addons"
+	"zync-stream/media"
+	"zync-stream/metadata"
 	"zync-stream/middleware"
 	"zync-stream/users"
 )
 
-func SetupUserRoutes(router *gin.Engine, dbPool *pgxpool.Pool, redisClient *redis.Client) *users.UserRepo {
+func SetupUserRoutes(ctx context.Context, router *gin.Engine, dbPool *pgxpool.Pool, redisClient *redis.Client, avatarStorage media.Storage, metaProvider metadata.Provider) *users.UserRepo {
 	userRepo := users.NewUserRepo(dbPool)
-	userHandlers := users.NewHandlers(userRepo, redisClient)
+	addonRepo := addons.NewAddonRepository(dbPool)
+	manifestClient := addons.NewManifestClient()
+	userHandlers := users.NewHandlers(userRepo, redisClient, avatarStorage, metaProvider, addonRepo, manifestClient)
+	middleware.SetAccountDB(dbPool)
+
+	go addons.MigrateExtensions(ctx, addonRepo, manifestClient)
 
 	// no auth required
 	publicGroup := router.Group("/api/users")
@@ -29,14 +39,28 @@ func SetupUserRoutes(router *gin.Engine, dbPool *pgxpool.Pool, redisClient *redi
 	authGroup.Use(middleware.AuthMiddleware())
 	{
 		authGroup.GET("/me", userHandlers.GetMe)
+		authGroup.DELETE("/me", userHandlers.DeleteAccount)
+		authGroup.GET("/me/export", userHandlers.ExportData)
 		authGroup.PUT("/me/password", userHandlers.ChangePassword)
+		authGroup.GET("/me/privacy", userHandlers.GetPrivacySettings)
+		authGroup.PUT("/me/privacy", userHandlers.UpdatePrivacySettings)
 		authGroup.POST("/me/extensions", userHandlers.UpdateExtensions)
+		authGroup.GET("/me/addons", userHandlers.GetAddons)
+		authGroup.PUT("/me/addons/order", userHandlers.ReorderAddons)
+		authGroup.PATCH("/me/addons/:id", userHandlers.UpdateAddon)
 		authGroup.PUT("/me/avatar", userHandlers.UpdateAvatar)
+		authGroup.POST("/me/avatar", userHandlers.UploadAvatar)
 		authGroup.GET("/me/watch-history", userHandlers.GetWatchHistory)
 		authGroup.POST("/me/watch-history", userHandlers.UpdateWatchHistory)
+		authGroup.DELETE("/me/watch-history", userHandlers.ClearWatchHistory)
 		authGroup.GET("/me/watch-history/:imdb_id", userHandlers.GetWatchHistoryItem)
+		authGroup.DELETE("/me/watch-history/:imdb_id", userHandlers.DeleteWatchHistoryItem)
+		authGroup.GET("/me/continue-watching", userHandlers.GetContinueWatching)
 		authGroup.PUT("/status", userHandlers.UpdateStatus)
 		authGroup.GET("/search", userHandlers.SearchUsers)
+		authGroup.GET("/blocks", userHandlers.GetBlockedUsers)
+		authGroup.POST("/blocks", userHandlers.BlockUser)
+		authGroup.DELETE("/blocks", userHandlers.UnblockUser)
 	}
 
 	// friend routes
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"zync-stream/middleware"
+	"zync-stream/watchlist"
+)
+
+func SetupWatchlistRoutes(router *gin.Engine, dbPool *pgxpool.Pool) {
+	watchlistRepo := watchlist.NewWatchlistRepository(dbPool)
+	watchlistHandlers := watchlist.NewWatchlistHandlers(watchlistRepo)
+
+	watchlistGroup := router.Group("/api/watchlists")
+	watchlistGroup.Use(middleware.AuthMiddleware())
+	{
+		watchlistGroup.GET("", watchlistHandlers.GetLists)
+		watchlistGroup.POST("", watchlistHandlers.CreateList)
+		watchlistGroup.GET("/friends/:userId", watchlistHandlers.GetFriendLists)
+		watchlistGroup.GET("/:id", watchlistHandlers.GetList)
+		watchlistGroup.PUT("/:id", watchlistHandlers.UpdateList)
+		watchlistGroup.DELETE("/:id", watchlistHandlers.DeleteList)
+		watchlistGroup.POST("/:id/items", watchlistHandlers.AddItem)
+		watchlistGroup.PUT("/:id/items/order", watchlistHandlers.ReorderItems)
+		watchlistGroup.DELETE("/:id/items/:itemId", watchlistHandlers.RemoveItem)
+	}
+}
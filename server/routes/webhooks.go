@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"zync-stream/middleware"
+	"zync-stream/rooms"
+	"zync-stream/webhooks"
+	"zync-stream/ws"
+)
+
+func SetupWebhookRoutes(ctx context.Context, router *gin.Engine, dbPool *pgxpool.Pool) {
+	webhookRepo := webhooks.NewWebhookRepository(dbPool)
+	webhookHandlers := webhooks.NewWebhookHandlers(webhookRepo, rooms.NewRoomRepository(dbPool))
+	ws.SetWebhookRepository(webhookRepo)
+
+	webhooks.NewDispatcher(webhookRepo).Start(ctx, 5*time.Second)
+
+	webhookGroup := router.Group("/api/webhooks")
+	webhookGroup.Use(middleware.AuthMiddleware())
+	{
+		webhookGroup.POST("", webhookHandlers.CreateWebhook)
+		webhookGroup.GET("", webhookHandlers.GetWebhooks)
+		webhookGroup.DELETE("/:id", webhookHandlers.DeleteWebhook)
+		webhookGroup.GET("/:id/deliveries", webhookHandlers.GetDeliveries)
+		webhookGroup.POST("/:id/ping", webhookHandlers.PingWebhook)
+	}
+}
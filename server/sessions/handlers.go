@@ -0,0 +1,93 @@
+package sessions
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"zync-stream/rooms"
+)
+
+type SessionHandlers struct {
+	repo     *SessionRepository
+	roomRepo *rooms.RoomRepository
+}
+
+func NewSessionHandlers(repo *SessionRepository, roomRepo *rooms.RoomRepository) *SessionHandlers {
+	return &SessionHandlers{
+		repo:     repo,
+		roomRepo: roomRepo,
+	}
+}
+
+// GetRoomSessions lists a room's viewing sessions, newest first, to its members
+func (h *SessionHandlers) GetRoomSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	limit := DefaultPageSize
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = min(limit, MaxPageSize)
+	}
+
+	beforeID := 0
+	if beforeStr := c.Query("before"); beforeStr != "" {
+		beforeID, err = strconv.Atoi(beforeStr)
+		if err != nil || beforeID < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before cursor"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+
+	isMember, _, err := h.roomRepo.IsRoomMember(ctx, roomID, userID.(int))
+	if err != nil {
+		log.Printf("Error checking room membership: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check room membership"})
+		return
+	}
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this room"})
+		return
+	}
+
+	// fetch one extra row to know whether another page exists
+	sessions, err := h.repo.ListForRoom(ctx, roomID, beforeID, limit+1)
+	if err != nil {
+		log.Printf("Error retrieving room sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve room sessions"})
+		return
+	}
+
+	hasMore := len(sessions) > limit
+	if hasMore {
+		sessions = sessions[:limit]
+	}
+
+	response := gin.H{
+		"sessions": sessions,
+		"has_more": hasMore,
+	}
+	if hasMore {
+		response["next_before"] = sessions[len(sessions)-1].ID
+	}
+
+	c.JSON(http.StatusOK, response)
+}
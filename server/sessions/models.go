@@ -0,0 +1,62 @@
+package sessions
+
+import "time"
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+
+	// positions are persisted at most this often while a session runs
+	saveInterval = 15 * time.Second
+)
+
+type Session struct {
+	ID               int        `json:"id"`
+	RoomID           int        `json:"room_id"`
+	ImdbID           *string    `json:"imdb_id,omitempty"`
+	MediaType        *string    `json:"media_type,omitempty"`
+	SeasonNumber     *int       `json:"season_number,omitempty"`
+	EpisodeNumber    *int       `json:"episode_number,omitempty"`
+	FurthestPosition int        `json:"furthest_position"`
+	DurationSeconds  *int       `json:"duration_seconds,omitempty"`
+	StartedAt        time.Time  `json:"started_at"`
+	EndedAt          *time.Time `json:"ended_at,omitempty"`
+	Attendees        []Attendee `json:"attendees"`
+}
+
+type Attendee struct {
+	UserID   int        `json:"user_id"`
+	Username string     `json:"username"`
+	JoinedAt time.Time  `json:"joined_at"`
+	LeftAt   *time.Time `json:"left_at,omitempty"`
+}
+
+// Media identifies what a room is playing, as sent along with playback_sync
+type Media struct {
+	ImdbID        string
+	MediaType     string
+	SeasonNumber  *int
+	EpisodeNumber *int
+}
+
+func (m *Media) sameAs(other *Media) bool {
+	if m == nil || other == nil {
+		return m == other
+	}
+	return m.ImdbID == other.ImdbID && m.MediaType == other.MediaType &&
+		intEqual(m.SeasonNumber, other.SeasonNumber) && intEqual(m.EpisodeNumber, other.EpisodeNumber)
+}
+
+func intEqual(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// Progress is an attendee's position in a title when they leave a session
+type Progress struct {
+	Media
+	PositionSeconds int
+	DurationSeconds int // 0 when unknown
+}
@@ -0,0 +1,176 @@
+package sessions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SessionRepository records room viewing sessions and who attended them
+type SessionRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewSessionRepository creates a new SessionRepository
+func NewSessionRepository(db *pgxpool.Pool) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) Start(ctx context.Context, roomID int, media *Media) (int, error) {
+	var imdbID, mediaType *string
+	var season, episode *int
+	if media != nil {
+		imdbID, mediaType = &media.ImdbID, &media.MediaType
+		season, episode = media.SeasonNumber, media.EpisodeNumber
+	}
+
+	var id int
+	err := r.db.QueryRow(ctx, `
+        INSERT INTO room_sessions (room_id, imdb_id, media_type, season_number, episode_number)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id
+    `, roomID, imdbID, mediaType, season, episode).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start room session: %w", err)
+	}
+
+	return id, nil
+}
+
+// AddAttendee records a user joining, or re-joining, a session
+func (r *SessionRepository) AddAttendee(ctx context.Context, sessionID, userID int) error {
+	_, err := r.db.Exec(ctx, `
+        INSERT INTO room_session_attendees (session_id, user_id)
+        VALUES ($1, $2)
+        ON CONFLICT (session_id, user_id) DO UPDATE SET left_at = NULL
+    `, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to add session attendee: %w", err)
+	}
+	return nil
+}
+
+func (r *SessionRepository) MarkLeft(ctx context.Context, sessionID, userID int) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE room_session_attendees SET left_at = NOW()
+        WHERE session_id = $1 AND user_id = $2 AND left_at IS NULL
+    `, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark session attendee left: %w", err)
+	}
+	return nil
+}
+
+func (r *SessionRepository) UpdatePosition(ctx context.Context, sessionID, furthest, duration int) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE room_sessions
+        SET furthest_position = GREATEST(furthest_position, $2),
+            duration_seconds = COALESCE(NULLIF($3, 0), duration_seconds)
+        WHERE id = $1
+    `, sessionID, furthest, duration)
+	if err != nil {
+		return fmt.Errorf("failed to update session position: %w", err)
+	}
+	return nil
+}
+
+// End closes a session along with any attendance still open
+func (r *SessionRepository) End(ctx context.Context, sessionID, furthest, duration int) error {
+	if err := r.UpdatePosition(ctx, sessionID, furthest, duration); err != nil {
+		return err
+	}
+
+	if _, err := r.db.Exec(ctx, `
+        UPDATE room_session_attendees SET left_at = NOW()
+        WHERE session_id = $1 AND left_at IS NULL
+    `, sessionID); err != nil {
+		return fmt.Errorf("failed to close session attendance: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, `UPDATE room_sessions SET ended_at = NOW() WHERE id = $1`, sessionID); err != nil {
+		return fmt.Errorf("failed to end room session: %w", err)
+	}
+
+	return nil
+}
+
+// CloseAbandoned ends sessions left open by a previous process
+func (r *SessionRepository) CloseAbandoned(ctx context.Context) (int64, error) {
+	if _, err := r.db.Exec(ctx, `
+        UPDATE room_session_attendees a SET left_at = NOW()
+        FROM room_sessions s
+        WHERE s.id = a.session_id AND s.ended_at IS NULL AND a.left_at IS NULL
+    `); err != nil {
+		return 0, fmt.Errorf("failed to close abandoned attendance: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, `UPDATE room_sessions SET ended_at = NOW() WHERE ended_at IS NULL`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to close abandoned sessions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ListForRoom returns a page of sessions, newest first, with their attendees
+func (r *SessionRepository) ListForRoom(ctx context.Context, roomID, beforeID, limit int) ([]*Session, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT id, room_id, imdb_id, media_type, season_number, episode_number,
+               furthest_position, duration_seconds, started_at, ended_at
+        FROM room_sessions
+        WHERE room_id = $1 AND ($2 = 0 OR id < $2)
+        ORDER BY id DESC
+        LIMIT $3
+    `, roomID, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query room sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	byID := make(map[int]*Session)
+	ids := []int{}
+	for rows.Next() {
+		session := &Session{Attendees: []Attendee{}}
+		if err := rows.Scan(&session.ID, &session.RoomID, &session.ImdbID, &session.MediaType,
+			&session.SeasonNumber, &session.EpisodeNumber, &session.FurthestPosition,
+			&session.DurationSeconds, &session.StartedAt, &session.EndedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan room session: %w", err)
+		}
+		sessions = append(sessions, session)
+		byID[session.ID] = session
+		ids = append(ids, session.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return sessions, nil
+	}
+
+	attendeeRows, err := r.db.Query(ctx, `
+        SELECT a.session_id, a.user_id, u.username, a.joined_at, a.left_at
+        FROM room_session_attendees a
+        JOIN users u ON u.id = a.user_id
+        WHERE a.session_id = ANY($1)
+        ORDER BY a.joined_at
+    `, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session attendees: %w", err)
+	}
+	defer attendeeRows.Close()
+
+	for attendeeRows.Next() {
+		var sessionID int
+		var attendee Attendee
+		if err := attendeeRows.Scan(&sessionID, &attendee.UserID, &attendee.Username,
+			&attendee.JoinedAt, &attendee.LeftAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session attendee: %w", err)
+		}
+		if session := byID[sessionID]; session != nil {
+			session.Attendees = append(session.Attendees, attendee)
+		}
+	}
+
+	return sessions, attendeeRows.Err()
+}
@@ -0,0 +1,326 @@
+package sessions
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// HistoryWriter stores the position an attendee reached in a session
+type HistoryWriter interface {
+	RecordSessionProgress(ctx context.Context, userID int, progress Progress) error
+}
+
+// sessionStore is the part of SessionRepository the tracker writes to
+type sessionStore interface {
+	Start(ctx context.Context, roomID int, media *Media) (int, error)
+	AddAttendee(ctx context.Context, sessionID, userID int) error
+	MarkLeft(ctx context.Context, sessionID, userID int) error
+	UpdatePosition(ctx context.Context, sessionID, furthest, duration int) error
+	End(ctx context.Context, sessionID, furthest, duration int) error
+}
+
+type activeSession struct {
+	// id is only touched by the writer, and stays 0 if the session could not be stored
+	id        int
+	media     *Media
+	furthest  int
+	duration  int
+	lastSaved time.Time
+}
+
+type roomState struct {
+	mutex   sync.Mutex
+	present map[int]int // open connections per user
+	session *activeSession
+}
+
+// Tracker follows who is in each room and what is playing, opening a
+// session on the first playback_sync and closing it when the media
+// changes or the last attendee leaves. Only rooms joined through this
+// server instance are seen. Database writes are queued and applied in
+// order by a single writer, so websocket handlers never wait on them.
+type Tracker struct {
+	repo    sessionStore
+	history HistoryWriter
+	writes  *writer
+
+	mutex sync.Mutex
+	rooms map[int]*roomState
+}
+
+func NewTracker(repo *SessionRepository, history HistoryWriter) *Tracker {
+	return newTracker(repo, history)
+}
+
+func newTracker(repo sessionStore, history HistoryWriter) *Tracker {
+	t := &Tracker{
+		repo:    repo,
+		history: history,
+		writes:  newWriter(),
+		rooms:   make(map[int]*roomState),
+	}
+	go t.writes.run()
+	return t
+}
+
+func (t *Tracker) room(roomID int) *roomState {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	state, ok := t.rooms[roomID]
+	if !ok {
+		state = &roomState{present: make(map[int]int)}
+		t.rooms[roomID] = state
+	}
+	return state
+}
+
+// Join counts one more connection of the user in the room
+func (t *Tracker) Join(roomID, userID int) {
+	state := t.room(roomID)
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	state.present[userID]++
+	if state.present[userID] == 1 && state.session != nil {
+		t.addAttendee(state.session, userID)
+	}
+}
+
+// Leave drops one of the user's connections. When it was the last, their
+// progress is recorded, and the session ends once the room empties.
+func (t *Tracker) Leave(roomID, userID int) {
+	state := t.room(roomID)
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	if state.present[userID] == 0 {
+		return
+	}
+	state.present[userID]--
+	if state.present[userID] > 0 {
+		return
+	}
+	delete(state.present, userID)
+
+	if session := state.session; session != nil {
+		t.recordProgress(session, userID)
+
+		t.writes.enqueue(func(ctx context.Context) {
+			if session.id == 0 {
+				return
+			}
+			if err := t.repo.MarkLeft(ctx, session.id, userID); err != nil {
+				log.Printf("Failed to mark user %d left session %d: %v", userID, session.id, err)
+			}
+		})
+	}
+
+	if len(state.present) == 0 {
+		t.endSession(state)
+
+		t.mutex.Lock()
+		if t.rooms[roomID] == state {
+			delete(t.rooms, roomID)
+		}
+		t.mutex.Unlock()
+	}
+}
+
+// Playback handles a playback_sync payload. Besides "timestamp" (seconds) it
+// reads the optional "duration", "imdb_id", "media_type", "season_number"
+// and "episode_number" fields; a different title starts a new session.
+func (t *Tracker) Playback(roomID, userID int, data map[string]interface{}) {
+	state := t.room(roomID)
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	if state.present[userID] == 0 {
+		state.present[userID] = 1
+	}
+	media := parseMedia(data)
+
+	if state.session != nil && media != nil && !state.session.media.sameAs(media) {
+		t.endSession(state)
+	}
+
+	if state.session == nil {
+		session := &activeSession{media: media, lastSaved: time.Now()}
+		state.session = session
+
+		attendees := make([]int, 0, len(state.present))
+		for attendeeID := range state.present {
+			attendees = append(attendees, attendeeID)
+		}
+
+		t.writes.enqueue(func(ctx context.Context) {
+			id, err := t.repo.Start(ctx, roomID, media)
+			if err != nil {
+				log.Printf("Failed to start session for room %d: %v", roomID, err)
+				return
+			}
+			session.id = id
+			for _, attendeeID := range attendees {
+				if err := t.repo.AddAttendee(ctx, id, attendeeID); err != nil {
+					log.Printf("Failed to add user %d to session %d: %v", attendeeID, id, err)
+				}
+			}
+		})
+	}
+
+	session := state.session
+	if position, ok := number(data["timestamp"]); ok && position > session.furthest {
+		session.furthest = position
+	}
+	if duration, ok := number(data["duration"]); ok && duration > 0 {
+		session.duration = duration
+	}
+
+	if time.Since(session.lastSaved) >= saveInterval {
+		session.lastSaved = time.Now()
+		furthest, duration := session.furthest, session.duration
+		t.writes.enqueue(func(ctx context.Context) {
+			if session.id == 0 {
+				return
+			}
+			if err := t.repo.UpdatePosition(ctx, session.id, furthest, duration); err != nil {
+				log.Printf("Failed to save position for session %d: %v", session.id, err)
+			}
+		})
+	}
+}
+
+// endSession closes the room's session, writing history for everyone still present
+func (t *Tracker) endSession(state *roomState) {
+	session := state.session
+	if session == nil {
+		return
+	}
+	state.session = nil
+
+	for userID := range state.present {
+		t.recordProgress(session, userID)
+	}
+
+	furthest, duration := session.furthest, session.duration
+	t.writes.enqueue(func(ctx context.Context) {
+		if session.id == 0 {
+			return
+		}
+		if err := t.repo.End(ctx, session.id, furthest, duration); err != nil {
+			log.Printf("Failed to end session %d: %v", session.id, err)
+		}
+	})
+}
+
+func (t *Tracker) addAttendee(session *activeSession, userID int) {
+	t.writes.enqueue(func(ctx context.Context) {
+		if session.id == 0 {
+			return
+		}
+		if err := t.repo.AddAttendee(ctx, session.id, userID); err != nil {
+			log.Printf("Failed to add user %d to session %d: %v", userID, session.id, err)
+		}
+	})
+}
+
+// recordProgress copies the session's furthest position into the user's
+// watch history. Sessions without a known title are not recorded.
+func (t *Tracker) recordProgress(session *activeSession, userID int) {
+	if t.history == nil || session.media == nil || session.furthest <= 0 {
+		return
+	}
+
+	progress := Progress{
+		Media:           *session.media,
+		PositionSeconds: session.furthest,
+		DurationSeconds: session.duration,
+	}
+	t.writes.enqueue(func(ctx context.Context) {
+		if err := t.history.RecordSessionProgress(ctx, userID, progress); err != nil {
+			log.Printf("Failed to record session %d progress for user %d: %v", session.id, userID, err)
+		}
+	})
+}
+
+// writer runs queued database writes one at a time, in the order they were queued
+type writer struct {
+	mutex   sync.Mutex
+	pending []func(ctx context.Context)
+	wake    chan struct{}
+}
+
+func newWriter() *writer {
+	return &writer{wake: make(chan struct{}, 1)}
+}
+
+// enqueue never blocks, so the queue grows while the database is slow
+func (w *writer) enqueue(op func(ctx context.Context)) {
+	w.mutex.Lock()
+	w.pending = append(w.pending, op)
+	w.mutex.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *writer) run() {
+	for range w.wake {
+		for {
+			w.mutex.Lock()
+			if len(w.pending) == 0 {
+				w.mutex.Unlock()
+				break
+			}
+			op := w.pending[0]
+			w.pending[0] = nil
+			w.pending = w.pending[1:]
+			w.mutex.Unlock()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			op(ctx)
+			cancel()
+		}
+	}
+}
+
+func parseMedia(data map[string]interface{}) *Media {
+	imdbID, _ := data["imdb_id"].(string)
+	if imdbID == "" {
+		return nil
+	}
+
+	mediaType, _ := data["media_type"].(string)
+	if mediaType != "series" {
+		mediaType = "movie"
+	}
+
+	media := &Media{ImdbID: imdbID, MediaType: mediaType}
+	if mediaType == "series" {
+		if season, ok := number(data["season_number"]); ok {
+			media.SeasonNumber = &season
+		}
+		if episode, ok := number(data["episode_number"]); ok {
+			media.EpisodeNumber = &episode
+		}
+	}
+	return media
+}
+
+// number reads a JSON number as whole seconds or an index
+func number(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		if v < 0 {
+			return 0, false
+		}
+		return int(v), true
+	case int:
+		return v, v >= 0
+	}
+	return 0, false
+}
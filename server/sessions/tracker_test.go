@@ -0,0 +1,147 @@
+package sessions
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// fakeStore records the writes the tracker makes, in order
+type fakeStore struct {
+	mutex  sync.Mutex
+	nextID int
+	writes []string
+	block  chan struct{} // when set, Start waits for it
+}
+
+func (s *fakeStore) record(format string, args ...interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.writes = append(s.writes, fmt.Sprintf(format, args...))
+}
+
+func (s *fakeStore) Start(ctx context.Context, roomID int, media *Media) (int, error) {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mutex.Lock()
+	s.nextID++
+	id := s.nextID
+	s.mutex.Unlock()
+	s.record("start %d room %d", id, roomID)
+	return id, nil
+}
+
+func (s *fakeStore) AddAttendee(ctx context.Context, sessionID, userID int) error {
+	s.record("attend %d user %d", sessionID, userID)
+	return nil
+}
+
+func (s *fakeStore) MarkLeft(ctx context.Context, sessionID, userID int) error {
+	s.record("left %d user %d", sessionID, userID)
+	return nil
+}
+
+func (s *fakeStore) UpdatePosition(ctx context.Context, sessionID, furthest, duration int) error {
+	s.record("position %d at %d", sessionID, furthest)
+	return nil
+}
+
+func (s *fakeStore) End(ctx context.Context, sessionID, furthest, duration int) error {
+	s.record("end %d at %d", sessionID, furthest)
+	return nil
+}
+
+func (s *fakeStore) RecordSessionProgress(ctx context.Context, userID int, progress Progress) error {
+	s.record("history user %d %s at %d", userID, progress.ImdbID, progress.PositionSeconds)
+	return nil
+}
+
+func (s *fakeStore) recorded() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.writes...)
+}
+
+// flush waits until every write queued so far has been applied
+func (t *Tracker) flush() {
+	done := make(chan struct{})
+	t.writes.enqueue(func(context.Context) { close(done) })
+	<-done
+}
+
+func movie(timestamp int) map[string]interface{} {
+	return map[string]interface{}{"imdb_id": "tt0111161", "media_type": "movie", "timestamp": float64(timestamp)}
+}
+
+func TestTrackerCountsConnections(t *testing.T) {
+	store := &fakeStore{}
+	tracker := newTracker(store, store)
+
+	// user 1 has the room open in two tabs
+	tracker.Join(7, 1)
+	tracker.Join(7, 1)
+	tracker.Join(7, 2)
+	tracker.Playback(7, 1, movie(120))
+
+	tracker.Leave(7, 1)
+	tracker.flush()
+	if got := store.recorded(); len(got) != 3 {
+		t.Fatalf("closing one of two tabs wrote %v", got)
+	}
+
+	tracker.Leave(7, 1)
+	tracker.Leave(7, 2)
+	tracker.Leave(7, 2) // a duplicate leave is ignored
+	tracker.flush()
+
+	want := []string{
+		"start 1 room 7",
+		"attend 1 user 1",
+		"attend 1 user 2",
+		"history user 1 tt0111161 at 120",
+		"left 1 user 1",
+		"history user 2 tt0111161 at 120",
+		"left 1 user 2",
+		"end 1 at 120",
+	}
+	got := store.recorded()
+	// attendees are added in map order
+	if len(got) == len(want) && got[1] == want[2] {
+		got[1], got[2] = got[2], got[1]
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("writes = %v\nwant %v", got, want)
+	}
+}
+
+func TestTrackerDoesNotWaitOnTheDatabase(t *testing.T) {
+	store := &fakeStore{block: make(chan struct{})}
+	tracker := newTracker(store, store)
+
+	tracker.Join(3, 1)
+	tracker.Playback(3, 1, movie(10))
+
+	// Start is stuck, yet playback and a title change still return
+	tracker.Playback(3, 1, movie(20))
+	tracker.Playback(3, 1, map[string]interface{}{"imdb_id": "tt0068646", "timestamp": float64(5)})
+	tracker.Join(3, 2)
+
+	close(store.block)
+	tracker.flush()
+
+	want := []string{
+		"start 1 room 3",
+		"attend 1 user 1",
+		"history user 1 tt0111161 at 20",
+		"end 1 at 20",
+		"start 2 room 3",
+		"attend 2 user 1",
+		"attend 2 user 2",
+	}
+	if got := store.recorded(); !reflect.DeepEqual(got, want) {
+		t.Errorf("writes = %v\nwant %v", got, want)
+	}
+}
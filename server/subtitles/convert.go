@@ -0,0 +1,124 @@
+package subtitles
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("subtitle file is neither SRT nor WebVTT")
+
+	srtTiming = regexp.MustCompile(`^\s*(\d{1,2}):(\d{1,2}):(\d{1,2})[,.](\d{1,3})\s*-->\s*(\d{1,2}):(\d{1,2}):(\d{1,2})[,.](\d{1,3})`)
+	assTags   = regexp.MustCompile(`\{\\[^}]*\}`)
+)
+
+// ToVTT normalises a subtitle file to UTF-8 WebVTT. Gzipped files are
+// unpacked, and text that is not valid UTF-8 is read as Windows-1252, which
+// most legacy SRT files use.
+func ToVTT(data []byte) ([]byte, error) {
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack subtitles: %w", err)
+		}
+		defer reader.Close()
+		if data, err = io.ReadAll(io.LimitReader(reader, maxFileBytes+1)); err != nil {
+			return nil, fmt.Errorf("failed to unpack subtitles: %w", err)
+		}
+		if len(data) > maxFileBytes {
+			return nil, errors.New("subtitle file is too large")
+		}
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	text := decodeText(data)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	if strings.HasPrefix(text, "WEBVTT") {
+		return []byte(text), nil
+	}
+
+	return srtToVTT(text)
+}
+
+func decodeText(data []byte) string {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(data))
+	for _, b := range data {
+		if r, ok := cp1252[b]; ok {
+			builder.WriteRune(r)
+		} else {
+			builder.WriteRune(rune(b))
+		}
+	}
+	return builder.String()
+}
+
+func srtToVTT(text string) ([]byte, error) {
+	var out strings.Builder
+	out.WriteString("WEBVTT\n")
+
+	cues := 0
+	for _, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+
+		// the cue number is optional and dropped
+		timing := 0
+		for timing < len(lines) && timing < 2 && !srtTiming.MatchString(lines[timing]) {
+			timing++
+		}
+		if timing >= len(lines) || !srtTiming.MatchString(lines[timing]) {
+			continue
+		}
+
+		match := srtTiming.FindStringSubmatch(lines[timing])
+		out.WriteString("\n")
+		out.WriteString(timestamp(match[1:5]))
+		out.WriteString(" --> ")
+		out.WriteString(timestamp(match[5:9]))
+		out.WriteString("\n")
+		for _, line := range lines[timing+1:] {
+			out.WriteString(assTags.ReplaceAllString(line, ""))
+			out.WriteString("\n")
+		}
+		cues++
+	}
+
+	if cues == 0 {
+		return nil, ErrUnsupportedFormat
+	}
+	return []byte(out.String()), nil
+}
+
+// timestamp formats hours, minutes, seconds and a fraction as HH:MM:SS.mmm
+func timestamp(parts []string) string {
+	hours, _ := strconv.Atoi(parts[0])
+	minutes, _ := strconv.Atoi(parts[1])
+	seconds, _ := strconv.Atoi(parts[2])
+
+	// a fraction of "5" means 500ms, not 5ms
+	fraction := (parts[3] + "00")[:3]
+	millis, _ := strconv.Atoi(fraction)
+
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", hours, minutes, seconds, millis)
+}
+
+// cp1252 maps the Windows-1252 bytes that differ from Latin-1
+var cp1252 = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡',
+	0x88: 'ˆ', 0x89: '‰', 0x8a: 'Š', 0x8b: '‹', 0x8c: 'Œ', 0x8e: 'Ž',
+	0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—',
+	0x98: '˜', 0x99: '™', 0x9a: 'š', 0x9b: '›', 0x9c: 'œ', 0x9e: 'ž', 0x9f: 'Ÿ',
+}
@@ -0,0 +1,123 @@
+package subtitles
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTimestamp(t *testing.T) {
+	tests := []struct {
+		parts []string
+		want  string
+	}{
+		{[]string{"00", "01", "02", "345"}, "00:01:02.345"},
+		{[]string{"1", "2", "3", "5"}, "01:02:03.500"},
+		{[]string{"1", "2", "3", "05"}, "01:02:03.050"},
+		{[]string{"10", "59", "59", "999"}, "10:59:59.999"},
+	}
+
+	for _, tt := range tests {
+		if got := timestamp(tt.parts); got != tt.want {
+			t.Errorf("timestamp(%v) = %q, want %q", tt.parts, got, tt.want)
+		}
+	}
+}
+
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write([]byte(data))
+	writer.Close()
+	return buf.Bytes()
+}
+
+func TestToVTT(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+	}{
+		{
+			name:  "srt",
+			input: []byte("1\n00:00:01,000 --> 00:00:02,500\nHello\n\n2\n00:00:03,000 --> 00:00:04,000\nTwo\nlines\n"),
+			want:  "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\nHello\n\n00:00:03.000 --> 00:00:04.000\nTwo\nlines\n",
+		},
+		{
+			name:  "crlf line endings and a bom",
+			input: []byte("\xef\xbb\xbf1\r\n00:00:01,000 --> 00:00:02,000\r\nHi\r\n\r\n"),
+			want:  "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHi\n",
+		},
+		{
+			name:  "cp1252 text",
+			input: []byte("1\n00:00:01,000 --> 00:00:02,000\n\x93Caf\xe9\x94 \x96 50\x80\n"),
+			want:  "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\n“Café” – 50€\n",
+		},
+		{
+			name:  "cue numbers are optional and short times are padded",
+			input: []byte("0:0:1.5 --> 0:0:2.25\nNo number\n"),
+			want:  "WEBVTT\n\n00:00:01.500 --> 00:00:02.250\nNo number\n",
+		},
+		{
+			name:  "ass override tags are stripped",
+			input: []byte("1\n00:00:01,000 --> 00:00:02,000\n{\\an8}{\\i1}Top{\\i0}\n"),
+			want:  "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nTop\n",
+		},
+		{
+			name:  "malformed cues are skipped",
+			input: []byte("1\n00:00:01 --> 00:00:02\nNo millis\n\n2\nnot a timing\nText\n\n3\n00:00:05,000 --> 00:00:06,000\nKept\n\n4\n"),
+			want:  "WEBVTT\n\n00:00:05.000 --> 00:00:06.000\nKept\n",
+		},
+		{
+			name:  "webvtt passes through",
+			input: []byte("\xef\xbb\xbfWEBVTT\r\n\r\n00:01.000 --> 00:02.000\r\nAlready vtt\r\n"),
+			want:  "WEBVTT\n\n00:01.000 --> 00:02.000\nAlready vtt\n",
+		},
+		{
+			name:  "gzipped srt",
+			input: gzipped(t, "1\n00:00:01,000 --> 00:00:02,000\nZipped\n"),
+			want:  "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nZipped\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToVTT(tt.input)
+			if err != nil {
+				t.Fatalf("ToVTT() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ToVTT() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToVTTRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		wantErr error
+	}{
+		{"empty", nil, ErrUnsupportedFormat},
+		{"html error page", []byte("<html><body>404</body></html>"), ErrUnsupportedFormat},
+		{"only malformed cues", []byte("1\n00:00:01 --> 00:00:02\nText\n"), ErrUnsupportedFormat},
+		{"corrupt gzip", []byte{0x1f, 0x8b, 0x00, 0x01}, nil},
+		{"gzip bomb", gzipped(t, strings.Repeat("a", maxFileBytes+1)), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ToVTT(tt.input)
+			if err == nil {
+				t.Fatal("ToVTT() succeeded")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ToVTT() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package subtitles
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"zync-stream/addons"
+	"zync-stream/httpcache"
+	"zync-stream/netguard"
+	"zync-stream/rooms"
+)
+
+// RoomPublisher posts an event into a room's realtime channel
+type RoomPublisher func(roomID int, eventType string, userID int, username string, data map[string]interface{}) error
+
+type SubtitleHandlers struct {
+	aggregator  *addons.Aggregator
+	cache       *httpcache.Cache
+	signer      *Signer
+	roomRepo    *rooms.RoomRepository
+	playback    *rooms.PlaybackStore
+	publishRoom RoomPublisher
+}
+
+func NewSubtitleHandlers(aggregator *addons.Aggregator, cache *httpcache.Cache, signer *Signer, roomRepo *rooms.RoomRepository, playback *rooms.PlaybackStore, publishRoom RoomPublisher) *SubtitleHandlers {
+	return &SubtitleHandlers{
+		aggregator:  aggregator,
+		cache:       cache,
+		signer:      signer,
+		roomRepo:    roomRepo,
+		playback:    playback,
+		publishRoom: publishRoom,
+	}
+}
+
+// GetSubtitles lists subtitle tracks from the caller's addons. An optional
+// lang narrows the list; the remaining query (videoHash, videoSize,
+// filename) is passed on to the addons.
+func (h *SubtitleHandlers) GetSubtitles(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	mediaType := c.Param("type")
+	id := strings.TrimSuffix(c.Param("id"), ".json")
+
+	query := c.Request.URL.Query()
+	lang := query.Get("lang")
+	query.Del("lang")
+
+	found, results, err := h.aggregator.Subtitles(c.Request.Context(), userID.(int), mediaType, id, addons.ExtraFromQuery(query))
+	if err != nil {
+		log.Printf("Error aggregating subtitles: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load subtitles"})
+		return
+	}
+
+	tracks := []Track{}
+	for _, item := range found {
+		source := item["url"].(string)
+		if !Proxiable(source) {
+			continue
+		}
+		track := Track{URL: h.signer.ProxyURL(source)}
+		track.ID, _ = item["id"].(string)
+		track.Lang, _ = item["lang"].(string)
+		if lang != "" && !strings.EqualFold(track.Lang, lang) {
+			continue
+		}
+		track.Addon, _ = json.Marshal(item["addon"])
+		tracks = append(tracks, track)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subtitles": tracks,
+		"addons":    results,
+	})
+}
+
+// ServeFile fetches a signed subtitle source and serves it as WebVTT. It is
+// public because <track> elements cannot send an Authorization header; the
+// signature limits it to files an addon offered, and addons can still point
+// at internal hosts, so the source must resolve to a public address. The
+// cache dials through netguard too, which catches hosts that re-resolve.
+func (h *SubtitleHandlers) ServeFile(c *gin.Context) {
+	source := c.Query("src")
+	if source == "" || !h.signer.Verify(source, c.Query("sig")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid subtitle signature"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := netguard.CheckURL(ctx, source); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Subtitle source is not allowed"})
+		return
+	}
+
+	data, err := h.cache.Get(ctx, source)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch subtitles"})
+		return
+	}
+
+	vtt, err := ToVTT(data)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "text/vtt; charset=utf-8", vtt)
+}
+
+// memberRoom reads :id and checks the caller belongs to the room, returning
+// their role in it
+func (h *SubtitleHandlers) memberRoom(c *gin.Context) (int, int, string, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return 0, 0, "", false
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return 0, 0, "", false
+	}
+
+	isMember, role, err := h.roomRepo.IsRoomMember(c.Request.Context(), roomID, userID.(int))
+	if err != nil {
+		log.Printf("Error checking room membership: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check room membership"})
+		return 0, 0, "", false
+	}
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this room"})
+		return 0, 0, "", false
+	}
+
+	return roomID, userID.(int), role, true
+}
+
+// bindSelection reads and checks a subtitle selection from the request body
+func (h *SubtitleHandlers) bindSelection(c *gin.Context) (*Selection, bool) {
+	var selection Selection
+	if err := c.ShouldBindJSON(&selection); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return nil, false
+	}
+
+	if selection.OffsetMS < -MaxOffsetMS || selection.OffsetMS > MaxOffsetMS {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("offset_ms must be within ±%d", MaxOffsetMS)})
+		return nil, false
+	}
+
+	if selection.Track != nil && !h.signer.VerifyURL(selection.Track.URL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Track must come from the subtitles endpoint"})
+		return nil, false
+	}
+
+	return &selection, true
+}
+
+// SetRoomSubtitles changes the track and offset every member plays with.
+// Only owners and admins may; members override for themselves instead.
+func (h *SubtitleHandlers) SetRoomSubtitles(c *gin.Context) {
+	roomID, userID, role, ok := h.memberRoom(c)
+	if !ok {
+		return
+	}
+	if role != rooms.RoleOwner && role != rooms.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only room owners and admins can change the room's subtitles"})
+		return
+	}
+
+	selection, ok := h.bindSelection(c)
+	if !ok {
+		return
+	}
+
+	var track []byte
+	if selection.Track != nil {
+		track, _ = json.Marshal(selection.Track)
+	}
+
+	if err := h.playback.SetSubtitles(c.Request.Context(), roomID, userID, track, selection.OffsetMS); err != nil {
+		log.Printf("Error saving room subtitles: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save room subtitles"})
+		return
+	}
+
+	data := map[string]interface{}{
+		"track":     selection.Track,
+		"offset_ms": selection.OffsetMS,
+	}
+	if err := h.publishRoom(roomID, "subtitles_changed", userID, c.GetString("username"), data); err != nil {
+		log.Printf("Failed to publish subtitle change for room %d: %v", roomID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subtitles": selection})
+}
+
+// SetMySubtitles overrides the room's subtitles for the caller only
+func (h *SubtitleHandlers) SetMySubtitles(c *gin.Context) {
+	roomID, userID, _, ok := h.memberRoom(c)
+	if !ok {
+		return
+	}
+
+	selection, ok := h.bindSelection(c)
+	if !ok {
+		return
+	}
+
+	encoded, err := json.Marshal(selection)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode subtitles"})
+		return
+	}
+
+	if err := h.playback.SetSubtitleOverride(c.Request.Context(), roomID, userID, encoded); err != nil {
+		log.Printf("Error saving subtitle override: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save subtitles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subtitles": selection})
+}
+
+// ClearMySubtitles goes back to following the room's subtitles
+func (h *SubtitleHandlers) ClearMySubtitles(c *gin.Context) {
+	roomID, userID, _, ok := h.memberRoom(c)
+	if !ok {
+		return
+	}
+
+	if err := h.playback.ClearSubtitleOverride(c.Request.Context(), roomID, userID); err != nil {
+		log.Printf("Error clearing subtitle override: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear subtitles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Following room subtitles"})
+}
@@ -0,0 +1,27 @@
+package subtitles
+
+import "encoding/json"
+
+const (
+	// subtitle files larger than this are refused
+	maxFileBytes = 5 << 20
+
+	// room and personal offsets are limited to ten minutes either way
+	MaxOffsetMS = 10 * 60 * 1000
+)
+
+// Track is one subtitle file offered by an addon. URL points at this
+// server's signed proxy, which always serves WebVTT.
+type Track struct {
+	ID    string          `json:"id"`
+	Lang  string          `json:"lang"`
+	URL   string          `json:"url"`
+	Addon json.RawMessage `json:"addon,omitempty"`
+}
+
+// Selection is a subtitle track together with a timing offset. A nil
+// Track means subtitles are off.
+type Selection struct {
+	Track    *Track `json:"track"`
+	OffsetMS int    `json:"offset_ms"`
+}
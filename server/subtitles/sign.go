@@ -0,0 +1,76 @@
+package subtitles
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ProxyPath serves subtitle files converted to WebVTT
+const ProxyPath = "/api/subtitles/file"
+
+// Signer issues proxy URLs so the proxy only fetches files an addon offered.
+// Signatures do not expire, letting a room keep its track for as long as it
+// plays the title.
+type Signer struct {
+	key []byte
+}
+
+// NewSignerFromEnv reads SUBTITLE_PROXY_SECRET, falling back to JWT_SECRET_KEY.
+// The signing key is always derived from the secret: signing addon-supplied
+// strings with the raw JWT key would let anyone mint tokens.
+func NewSignerFromEnv() *Signer {
+	secret := os.Getenv("SUBTITLE_PROXY_SECRET")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET_KEY")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("subtitle-proxy"))
+	return &Signer{key: mac.Sum(nil)}
+}
+
+// Proxiable reports whether source is an http(s) URL the proxy may fetch
+func Proxiable(source string) bool {
+	parsed, err := url.Parse(source)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	scheme := strings.ToLower(parsed.Scheme)
+	return scheme == "http" || scheme == "https"
+}
+
+func (s *Signer) signature(source string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(source))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ProxyURL returns the proxied address of an addon's subtitle file
+func (s *Signer) ProxyURL(source string) string {
+	query := url.Values{}
+	query.Set("src", source)
+	query.Set("sig", s.signature(source))
+	return ProxyPath + "?" + query.Encode()
+}
+
+// Verify checks a src/sig pair from a proxy request
+func (s *Signer) Verify(source, signature string) bool {
+	if !Proxiable(source) {
+		return false
+	}
+	return hmac.Equal([]byte(s.signature(source)), []byte(signature))
+}
+
+// VerifyURL checks that a track URL is a proxy URL this server signed
+func (s *Signer) VerifyURL(proxyURL string) bool {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil || parsed.Path != ProxyPath {
+		return false
+	}
+	query := parsed.Query()
+	return s.Verify(query.Get("src"), query.Get("sig"))
+}
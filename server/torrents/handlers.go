@@ -0,0 +1,126 @@
+package torrents
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TorrentHandlers struct {
+	manager *Manager
+}
+
+func NewTorrentHandlers(manager *Manager) *TorrentHandlers {
+	return &TorrentHandlers{manager: manager}
+}
+
+// AddTorrent replaces the sidecar's POST /add: it joins the swarm, selects
+// a file and answers with the torrent's files and their stream URLs
+func (h *TorrentHandlers) AddTorrent(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req AddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), DefaultMetadataTimeout)
+	defer cancel()
+
+	status, err := h.manager.Add(ctx, userID.(int), req)
+	if err != nil {
+		respondWithError(c, err, "Failed to add torrent")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"torrent": status})
+}
+
+func (h *TorrentHandlers) GetTorrent(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	status, err := h.manager.Status(userID.(int), c.Param("infoHash"))
+	if err != nil {
+		respondWithError(c, err, "Failed to load torrent")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"torrent": status})
+}
+
+// GetUsage lists the caller's torrents and how much of the disk quota is used
+func (h *TorrentHandlers) GetUsage(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.manager.Usage(userID.(int)))
+}
+
+func (h *TorrentHandlers) RemoveTorrent(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.manager.Remove(userID.(int), c.Param("infoHash")); err != nil {
+		respondWithError(c, err, "Failed to remove torrent")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// StreamFile serves a torrent file with HTTP range support. It is public
+// like the sidecar's stream URL, because <video> elements cannot send an
+// Authorization header, but only files a user selected through AddTorrent
+// can be streamed.
+func (h *TorrentHandlers) StreamFile(c *gin.Context) {
+	fileIdx, err := strconv.Atoi(c.Param("fileIdx"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file index"})
+		return
+	}
+
+	stream, err := h.manager.Open(c.Request.Context(), c.Param("infoHash"), fileIdx)
+	if err != nil {
+		respondWithError(c, err, "Failed to open stream")
+		return
+	}
+	defer stream.Close()
+
+	http.ServeContent(c.Writer, c.Request, stream.Name, time.Time{}, stream)
+}
+
+func respondWithError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrInvalidSource):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrFileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrMetadataTimeout):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrQuotaExceeded):
+		c.JSON(http.StatusInsufficientStorage, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
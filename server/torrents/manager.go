@@ -0,0 +1,529 @@
+package torrents
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+)
+
+type entry struct {
+	t        *torrent.Torrent
+	holders  map[int]bool  // users that added the torrent and have not removed it
+	selected map[int]int64 // file index -> bytes reserved against the quota
+	streams  int
+	lastUsed time.Time
+}
+
+func (e *entry) reserved() int64 {
+	var total int64
+	for _, length := range e.selected {
+		total += length
+	}
+	return total
+}
+
+// Manager runs an embedded torrent client that only downloads the files
+// somebody selected, prioritising the pieces under each open stream.
+// Torrents nobody has streamed for IdleTimeout are dropped with their data.
+type Manager struct {
+	client  *torrent.Client
+	storage storage.ClientImplCloser
+	config  Config
+
+	mu       sync.Mutex
+	torrents map[metainfo.Hash]*entry
+}
+
+// ConfigFromEnv reports whether TORRENT_ENABLED is set and reads
+// TORRENT_DATA_DIR, TORRENT_DISK_QUOTA_MB, TORRENT_IDLE_TIMEOUT,
+// TORRENT_LISTEN_PORT, TORRENT_DISABLE_DHT and TORRENT_SEED
+func ConfigFromEnv() (Config, bool) {
+	config := Config{
+		DataDir:     filepath.Join(os.TempDir(), "zync-torrents"),
+		DiskQuota:   DefaultDiskQuotaMB << 20,
+		IdleTimeout: DefaultIdleTimeout,
+		ListenPort:  DefaultListenPort,
+	}
+
+	enabled, _ := strconv.ParseBool(os.Getenv("TORRENT_ENABLED"))
+	if !enabled {
+		return config, false
+	}
+
+	if value := os.Getenv("TORRENT_DATA_DIR"); value != "" {
+		config.DataDir = value
+	}
+	if value := os.Getenv("TORRENT_DISK_QUOTA_MB"); value != "" {
+		if parsed, err := strconv.ParseInt(value, 10, 64); err == nil && parsed > 0 {
+			config.DiskQuota = parsed << 20
+		}
+	}
+	if value := os.Getenv("TORRENT_IDLE_TIMEOUT"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			config.IdleTimeout = parsed
+		}
+	}
+	if value := os.Getenv("TORRENT_LISTEN_PORT"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			config.ListenPort = parsed
+		}
+	}
+	config.NoDHT, _ = strconv.ParseBool(os.Getenv("TORRENT_DISABLE_DHT"))
+	config.Seed, _ = strconv.ParseBool(os.Getenv("TORRENT_SEED"))
+
+	return config, true
+}
+
+// NewManager starts the torrent client. Data left in DataDir by a previous
+// run is deleted, since piece completion is only tracked in memory.
+func NewManager(config Config) (*Manager, error) {
+	if err := os.MkdirAll(config.DataDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create torrent data dir: %w", err)
+	}
+	removeStaleData(config.DataDir)
+
+	store := storage.NewFileOpts(storage.NewFileClientOpts{
+		ClientBaseDir: config.DataDir,
+		TorrentDirMaker: func(baseDir string, info *metainfo.Info, infoHash metainfo.Hash) string {
+			return filepath.Join(baseDir, infoHash.HexString())
+		},
+		PieceCompletion: storage.NewMapPieceCompletion(),
+	})
+
+	clientConfig := torrent.NewDefaultClientConfig()
+	clientConfig.DefaultStorage = store
+	clientConfig.NoDHT = config.NoDHT
+	clientConfig.Seed = config.Seed
+	clientConfig.ListenPort = config.ListenPort
+
+	client, err := torrent.NewClient(clientConfig)
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to start torrent client: %w", err)
+	}
+
+	return &Manager{
+		client:   client,
+		storage:  store,
+		config:   config,
+		torrents: make(map[metainfo.Hash]*entry),
+	}, nil
+}
+
+// Add joins the swarm for a magnet or info hash, waits for its metadata and
+// selects a file for download: FileIdx when given, else the largest file
+func (m *Manager) Add(ctx context.Context, userID int, req AddRequest) (*Status, error) {
+	spec, err := specFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	t, _, err := m.client.AddTorrentSpec(spec)
+	if err != nil {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("failed to add torrent: %w", err)
+	}
+	e := m.torrents[spec.InfoHash]
+	if e == nil {
+		e = &entry{
+			t:        t,
+			holders:  make(map[int]bool),
+			selected: make(map[int]int64),
+		}
+		m.torrents[spec.InfoHash] = e
+	}
+	e.holders[userID] = true
+	e.lastUsed = time.Now()
+	m.mu.Unlock()
+
+	select {
+	case <-t.GotInfo():
+	case <-ctx.Done():
+		m.Remove(userID, spec.InfoHash.HexString())
+		return nil, ErrMetadataTimeout
+	}
+
+	fileIdx := -1
+	if req.FileIdx != nil {
+		fileIdx = *req.FileIdx
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.torrents[spec.InfoHash] != e {
+		return nil, ErrNotFound
+	}
+	fileIdx, err = m.selectFileLocked(e, fileIdx)
+	if err != nil {
+		m.releaseLocked(spec.InfoHash, e, userID)
+		return nil, err
+	}
+
+	status := m.statusLocked(spec.InfoHash, e)
+	status.FileIdx = &fileIdx
+	return status, nil
+}
+
+// Remove drops the user's interest in a torrent. It is deleted straight away
+// once nobody holds it or is streaming it; otherwise idle eviction takes over.
+func (m *Manager) Remove(userID int, infoHash string) error {
+	hash, err := parseInfoHash(infoHash)
+	if err != nil {
+		return ErrNotFound
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.torrents[hash]
+	if e == nil || !e.holders[userID] {
+		return ErrNotFound
+	}
+	m.releaseLocked(hash, e, userID)
+	return nil
+}
+
+// Open returns a seekable reader over a file selected through Add. Reads
+// block until the pieces they need arrive, and those pieces are fetched
+// ahead of the rest.
+func (m *Manager) Open(ctx context.Context, infoHash string, fileIdx int) (*Stream, error) {
+	hash, err := parseInfoHash(infoHash)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	m.mu.Lock()
+	e := m.torrents[hash]
+	m.mu.Unlock()
+	if e == nil {
+		return nil, ErrNotFound
+	}
+
+	select {
+	case <-e.t.GotInfo():
+	case <-ctx.Done():
+		return nil, ErrMetadataTimeout
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.torrents[hash] != e {
+		return nil, ErrNotFound
+	}
+	// streaming is public, so it must not reserve quota for other files
+	if _, ok := e.selected[fileIdx]; !ok {
+		return nil, ErrFileNotFound
+	}
+
+	file := e.t.Files()[fileIdx]
+	reader := file.NewReader()
+	reader.SetResponsive()
+	reader.SetReadahead(readahead)
+
+	e.streams++
+	e.lastUsed = time.Now()
+
+	return &Stream{
+		ctx:     ctx,
+		reader:  reader,
+		Name:    filepath.Base(file.DisplayPath()),
+		manager: m,
+		entry:   e,
+	}, nil
+}
+
+// Status reports on a torrent the user holds
+func (m *Manager) Status(userID int, infoHash string) (*Status, error) {
+	hash, err := parseInfoHash(infoHash)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.torrents[hash]
+	if e == nil || !e.holders[userID] {
+		return nil, ErrNotFound
+	}
+	return m.statusLocked(hash, e), nil
+}
+
+// Usage lists the user's torrents along with the disk space reserved
+// against the quota by everyone's
+func (m *Manager) Usage(userID int) *Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usage := &Usage{
+		Torrents:   []Status{},
+		UsedBytes:  m.usedLocked(),
+		QuotaBytes: m.config.DiskQuota,
+	}
+	for hash, e := range m.torrents {
+		if e.holders[userID] {
+			usage.Torrents = append(usage.Torrents, *m.statusLocked(hash, e))
+		}
+	}
+	sort.Slice(usage.Torrents, func(i, j int) bool {
+		return usage.Torrents[i].LastUsed.After(usage.Torrents[j].LastUsed)
+	})
+	return usage
+}
+
+// EvictIdle drops torrents with no open streams that were last used more
+// than IdleTimeout before now
+func (m *Manager) EvictIdle(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, e := range m.torrents {
+		if e.streams == 0 && now.Sub(e.lastUsed) > m.config.IdleTimeout {
+			m.dropLocked(hash, e)
+		}
+	}
+}
+
+// StartIdleEvictor runs EvictIdle every interval until ctx is done
+func StartIdleEvictor(ctx context.Context, manager *Manager, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				manager.EvictIdle(now)
+			}
+		}
+	}()
+}
+
+// Close drops every torrent, deleting its data, and stops the client
+func (m *Manager) Close() {
+	m.mu.Lock()
+	for hash, e := range m.torrents {
+		m.dropLocked(hash, e)
+	}
+	m.mu.Unlock()
+
+	for _, err := range m.client.Close() {
+		log.Printf("Error closing torrent client: %v", err)
+	}
+	if err := m.storage.Close(); err != nil {
+		log.Printf("Error closing torrent storage: %v", err)
+	}
+}
+
+// selectFileLocked starts downloading a file once its size fits the quota,
+// evicting idle torrents to make room. A negative index picks the largest file.
+func (m *Manager) selectFileLocked(e *entry, fileIdx int) (int, error) {
+	files := e.t.Files()
+	if fileIdx < 0 {
+		fileIdx = largestFile(files)
+	}
+	if fileIdx >= len(files) {
+		return 0, ErrFileNotFound
+	}
+	if _, ok := e.selected[fileIdx]; ok {
+		return fileIdx, nil
+	}
+
+	length := files[fileIdx].Length()
+	if !m.reserveLocked(e, length) {
+		return 0, ErrQuotaExceeded
+	}
+	e.selected[fileIdx] = length
+	files[fileIdx].SetPriority(torrent.PiecePriorityNormal)
+
+	return fileIdx, nil
+}
+
+// reserveLocked makes room for length more bytes, evicting the least recently
+// used torrents nobody is streaming, but only when that frees enough space
+func (m *Manager) reserveLocked(keep *entry, length int64) bool {
+	used := m.usedLocked()
+	if used+length <= m.config.DiskQuota {
+		return true
+	}
+
+	type candidate struct {
+		hash metainfo.Hash
+		e    *entry
+	}
+	var idle []candidate
+	var freeable int64
+	for hash, e := range m.torrents {
+		if e != keep && e.streams == 0 {
+			idle = append(idle, candidate{hash, e})
+			freeable += e.reserved()
+		}
+	}
+	if used-freeable+length > m.config.DiskQuota {
+		return false
+	}
+
+	sort.Slice(idle, func(i, j int) bool {
+		return idle[i].e.lastUsed.Before(idle[j].e.lastUsed)
+	})
+	for _, c := range idle {
+		if used+length <= m.config.DiskQuota {
+			break
+		}
+		used -= c.e.reserved()
+		m.dropLocked(c.hash, c.e)
+	}
+	return true
+}
+
+func (m *Manager) usedLocked() int64 {
+	var used int64
+	for _, e := range m.torrents {
+		used += e.reserved()
+	}
+	return used
+}
+
+func (m *Manager) releaseLocked(hash metainfo.Hash, e *entry, userID int) {
+	delete(e.holders, userID)
+	if len(e.holders) == 0 && e.streams == 0 {
+		m.dropLocked(hash, e)
+	}
+}
+
+func (m *Manager) dropLocked(hash metainfo.Hash, e *entry) {
+	e.t.Drop()
+	delete(m.torrents, hash)
+
+	if err := os.RemoveAll(filepath.Join(m.config.DataDir, hash.HexString())); err != nil {
+		log.Printf("Failed to delete data for torrent %s: %v", hash.HexString(), err)
+	}
+	log.Printf("Dropped torrent %s", hash.HexString())
+}
+
+func (m *Manager) closeStream(e *entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e.streams--
+	e.lastUsed = time.Now()
+}
+
+func (m *Manager) statusLocked(hash metainfo.Hash, e *entry) *Status {
+	stats := e.t.Stats()
+	status := &Status{
+		InfoHash:      hash.HexString(),
+		Name:          e.t.Name(),
+		Files:         []File{},
+		ReservedBytes: e.reserved(),
+		ActivePeers:   stats.ActivePeers,
+		TotalPeers:    stats.TotalPeers,
+		Streams:       e.streams,
+		LastUsed:      e.lastUsed,
+	}
+
+	if e.t.Info() == nil {
+		return status
+	}
+
+	for i, file := range e.t.Files() {
+		_, selected := e.selected[i]
+		completed := file.BytesCompleted()
+		status.BytesCompleted += completed
+		status.Files = append(status.Files, File{
+			Index:          i,
+			Path:           file.DisplayPath(),
+			Length:         file.Length(),
+			BytesCompleted: completed,
+			Selected:       selected,
+			StreamURL:      fmt.Sprintf("%s/%s/%d", StreamPathPrefix, status.InfoHash, i),
+		})
+	}
+	return status
+}
+
+func specFromRequest(req AddRequest) (*torrent.TorrentSpec, error) {
+	var spec *torrent.TorrentSpec
+	switch {
+	case req.Magnet != "":
+		parsed, err := torrent.TorrentSpecFromMagnetUri(req.Magnet)
+		if err != nil || parsed.InfoHash.IsZero() {
+			return nil, ErrInvalidSource
+		}
+		spec = parsed
+	case req.InfoHash != "":
+		hash, err := parseInfoHash(req.InfoHash)
+		if err != nil {
+			return nil, ErrInvalidSource
+		}
+		spec = &torrent.TorrentSpec{InfoHash: hash}
+	default:
+		return nil, ErrInvalidSource
+	}
+
+	var trackers []string
+	for _, source := range req.Trackers {
+		tracker := strings.TrimPrefix(source, "tracker:")
+		for _, scheme := range []string{"http://", "https://", "udp://", "ws://", "wss://"} {
+			if strings.HasPrefix(tracker, scheme) {
+				trackers = append(trackers, tracker)
+				break
+			}
+		}
+	}
+	if len(trackers) > 0 {
+		spec.Trackers = append(spec.Trackers, trackers)
+	}
+	spec.PeerAddrs = append(spec.PeerAddrs, req.Peers...)
+
+	return spec, nil
+}
+
+func parseInfoHash(value string) (metainfo.Hash, error) {
+	var hash metainfo.Hash
+	err := hash.FromHexString(strings.ToLower(value))
+	return hash, err
+}
+
+func largestFile(files []*torrent.File) int {
+	largest := 0
+	for i, file := range files {
+		if file.Length() > files[largest].Length() {
+			largest = i
+		}
+	}
+	return largest
+}
+
+// removeStaleData deletes the per-torrent directories of an earlier run,
+// leaving anything else in the data dir alone
+func removeStaleData(dataDir string) {
+	dirEntries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return
+	}
+	for _, dirEntry := range dirEntries {
+		if _, err := parseInfoHash(dirEntry.Name()); err != nil || !dirEntry.IsDir() {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dataDir, dirEntry.Name())); err != nil {
+			log.Printf("Failed to delete stale torrent data %s: %v", dirEntry.Name(), err)
+		}
+	}
+}
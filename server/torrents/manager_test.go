@@ -0,0 +1,317 @@
+package torrents
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// fixture is a torrent seeded by a local client with no trackers or DHT,
+// reachable only through its address
+type fixture struct {
+	infoHash string
+	peer     string
+	files    map[string][]byte
+}
+
+func seedFixture(t *testing.T, name string, files map[string][]byte) fixture {
+	t.Helper()
+
+	dir := t.TempDir()
+	for path, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, path), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info := metainfo.Info{Name: name, PieceLength: 64 << 10}
+	if err := info.BuildFromFilePath(dir); err != nil {
+		t.Fatal(err)
+	}
+	info.Name = name
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mi := &metainfo.MetaInfo{InfoBytes: infoBytes}
+
+	// serve the files straight from dir, whatever the info name
+	store := storage.NewFileOpts(storage.NewFileClientOpts{
+		ClientBaseDir: dir,
+		FilePathMaker: func(opts storage.FilePathMakerOpts) string {
+			return filepath.Join(opts.File.BestPath()...)
+		},
+		PieceCompletion: storage.NewMapPieceCompletion(),
+	})
+
+	config := torrent.NewDefaultClientConfig()
+	config.DefaultStorage = store
+	config.Seed = true
+	config.NoDHT = true
+	config.ListenPort = 0
+	seeder, err := torrent.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		seeder.Close()
+		store.Close()
+	})
+
+	seeded, err := seeder.AddTorrent(mi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seeded.VerifyData()
+
+	return fixture{
+		infoHash: mi.HashInfoBytes().HexString(),
+		peer:     fmt.Sprintf("127.0.0.1:%d", seeder.LocalPort()),
+		files:    files,
+	}
+}
+
+func (f fixture) magnet() string {
+	return "magnet:?xt=urn:btih:" + f.infoHash + "&x.pe=" + f.peer
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func newTestManager(t *testing.T, quota int64) *Manager {
+	t.Helper()
+	manager, err := NewManager(Config{
+		DataDir:     t.TempDir(),
+		DiskQuota:   quota,
+		IdleTimeout: time.Minute,
+		NoDHT:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(manager.Close)
+	return manager
+}
+
+func addFixture(t *testing.T, manager *Manager, userID int, req AddRequest) (*Status, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return manager.Add(ctx, userID, req)
+}
+
+func TestStreamRangesFromSwarm(t *testing.T) {
+	movie := randomBytes(t, 1<<20+4321)
+	swarm := seedFixture(t, "fixture", map[string][]byte{
+		"movie.mkv": movie,
+		"notes.txt": []byte("not selected"),
+	})
+	manager := newTestManager(t, 8<<20)
+
+	status, err := addFixture(t, manager, 1, AddRequest{Magnet: swarm.magnet()})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if len(status.Files) != 2 {
+		t.Fatalf("Add() files = %+v", status.Files)
+	}
+	selected := status.Files[*status.FileIdx]
+	if selected.Path != "movie.mkv" || !selected.Selected {
+		t.Fatalf("Add() selected %+v, want the largest file", selected)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET(StreamPathPrefix+"/:infoHash/:fileIdx", NewTorrentHandlers(manager).StreamFile)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ranges := []struct {
+		header     string
+		start, end int
+	}{
+		{"bytes=0-99", 0, 99},
+		{"bytes=700000-900000", 700000, 900000},
+		{fmt.Sprintf("bytes=%d-", len(movie)-500), len(movie) - 500, len(movie) - 1},
+	}
+	for _, r := range ranges {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+selected.StreamURL, nil)
+		req.Header.Set("Range", r.header)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusPartialContent {
+			t.Fatalf("%s: status = %d", r.header, resp.StatusCode)
+		}
+		if want := fmt.Sprintf("bytes %d-%d/%d", r.start, r.end, len(movie)); resp.Header.Get("Content-Range") != want {
+			t.Errorf("%s: Content-Range = %q, want %q", r.header, resp.Header.Get("Content-Range"), want)
+		}
+		if !bytes.Equal(body, movie[r.start:r.end+1]) {
+			t.Errorf("%s: body does not match the fixture", r.header)
+		}
+	}
+
+	// files nobody selected cannot be opened through the public route
+	resp, err := http.Get(server.URL + status.Files[1-*status.FileIdx].StreamURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unselected file status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestHoldersAndUsage(t *testing.T) {
+	swarm := seedFixture(t, "shared", map[string][]byte{"a.mkv": randomBytes(t, 200<<10)})
+	manager := newTestManager(t, 8<<20)
+
+	fileIdx := 0
+	for _, userID := range []int{1, 2} {
+		if _, err := addFixture(t, manager, userID, AddRequest{InfoHash: swarm.infoHash, Peers: []string{swarm.peer}, FileIdx: &fileIdx}); err != nil {
+			t.Fatalf("Add() for user %d error = %v", userID, err)
+		}
+	}
+
+	if usage := manager.Usage(3); len(usage.Torrents) != 0 {
+		t.Errorf("Usage() for a stranger lists %d torrents", len(usage.Torrents))
+	}
+	if _, err := manager.Status(3, swarm.infoHash); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Status() for a stranger error = %v, want ErrNotFound", err)
+	}
+	if err := manager.Remove(3, swarm.infoHash); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Remove() for a stranger error = %v, want ErrNotFound", err)
+	}
+
+	if err := manager.Remove(1, swarm.infoHash); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := manager.Status(2, swarm.infoHash); err != nil {
+		t.Fatalf("torrent dropped while user 2 still holds it: %v", err)
+	}
+
+	if err := manager.Remove(2, swarm.infoHash); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if usage := manager.Usage(2); len(usage.Torrents) != 0 || usage.UsedBytes != 0 {
+		t.Errorf("Usage() after the last holder left = %+v", usage)
+	}
+	if _, err := os.Stat(filepath.Join(manager.config.DataDir, swarm.infoHash)); !os.IsNotExist(err) {
+		t.Errorf("torrent data left on disk: %v", err)
+	}
+}
+
+func TestQuotaAndIdleEviction(t *testing.T) {
+	first := seedFixture(t, "first", map[string][]byte{"a.mkv": randomBytes(t, 600<<10)})
+	second := seedFixture(t, "second", map[string][]byte{"b.mkv": randomBytes(t, 600<<10)})
+	large := seedFixture(t, "large", map[string][]byte{"c.mkv": randomBytes(t, 2<<20)})
+	manager := newTestManager(t, 1<<20)
+
+	if _, err := addFixture(t, manager, 1, AddRequest{Magnet: first.magnet()}); err != nil {
+		t.Fatalf("Add(first) error = %v", err)
+	}
+
+	if _, err := addFixture(t, manager, 2, AddRequest{Magnet: large.magnet()}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Add(large) error = %v, want ErrQuotaExceeded", err)
+	}
+	if _, err := manager.Status(1, first.infoHash); err != nil {
+		t.Fatalf("a rejected add evicted another torrent: %v", err)
+	}
+
+	// room is made by evicting the idle torrent
+	if _, err := addFixture(t, manager, 2, AddRequest{Magnet: second.magnet()}); err != nil {
+		t.Fatalf("Add(second) error = %v", err)
+	}
+	if _, err := manager.Status(1, first.infoHash); !errors.Is(err, ErrNotFound) {
+		t.Errorf("idle torrent was not evicted for quota: %v", err)
+	}
+
+	stream, err := manager.Open(context.Background(), second.infoHash, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	manager.EvictIdle(time.Now().Add(time.Hour))
+	if _, err := manager.Status(2, second.infoHash); err != nil {
+		t.Fatalf("torrent with an open stream was evicted: %v", err)
+	}
+
+	stream.Close()
+	manager.EvictIdle(time.Now().Add(time.Hour))
+	if _, err := manager.Status(2, second.infoHash); !errors.Is(err, ErrNotFound) {
+		t.Errorf("idle torrent was not evicted: %v", err)
+	}
+}
+
+func TestSpecFromRequest(t *testing.T) {
+	hash := "0123456789abcdef0123456789abcdef01234567"
+
+	tests := []struct {
+		name         string
+		req          AddRequest
+		wantErr      bool
+		wantTrackers int
+		wantPeers    int
+	}{
+		{"info hash", AddRequest{InfoHash: hash}, false, 0, 0},
+		{"upper case info hash", AddRequest{InfoHash: "0123456789ABCDEF0123456789ABCDEF01234567"}, false, 0, 0},
+		{"magnet with peer", AddRequest{Magnet: "magnet:?xt=urn:btih:" + hash + "&x.pe=127.0.0.1:6881"}, false, 0, 1},
+		{"stremio sources", AddRequest{InfoHash: hash, Trackers: []string{"tracker:udp://tracker.example:80", "dht:" + hash, "https://tracker.example/announce"}}, false, 2, 0},
+		{"extra peers", AddRequest{InfoHash: hash, Peers: []string{"127.0.0.1:1", "127.0.0.1:2"}}, false, 0, 2},
+		{"short hash", AddRequest{InfoHash: "abc"}, true, 0, 0},
+		{"bad magnet", AddRequest{Magnet: "http://example.com"}, true, 0, 0},
+		{"empty", AddRequest{}, true, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := specFromRequest(tt.req)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSource) {
+					t.Fatalf("specFromRequest() error = %v, want ErrInvalidSource", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("specFromRequest() error = %v", err)
+			}
+			if spec.InfoHash.HexString() != hash {
+				t.Errorf("info hash = %s", spec.InfoHash.HexString())
+			}
+			trackers := 0
+			for _, tier := range spec.Trackers {
+				trackers += len(tier)
+			}
+			if trackers != tt.wantTrackers {
+				t.Errorf("trackers = %v, want %d", spec.Trackers, tt.wantTrackers)
+			}
+			if len(spec.PeerAddrs) != tt.wantPeers {
+				t.Errorf("peers = %v, want %d", spec.PeerAddrs, tt.wantPeers)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package torrents
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	DefaultDiskQuotaMB     = 20 * 1024
+	DefaultIdleTimeout     = 10 * time.Minute
+	DefaultMetadataTimeout = 60 * time.Second
+	DefaultListenPort      = 42069
+
+	// readahead is how far past the playback position pieces are prioritised
+	readahead = 8 << 20
+
+	StreamPathPrefix = "/api/torrents/stream"
+)
+
+var (
+	ErrInvalidSource   = errors.New("a magnet link or 40 character info hash is required")
+	ErrNotFound        = errors.New("torrent not found")
+	ErrFileNotFound    = errors.New("file not found in torrent")
+	ErrMetadataTimeout = errors.New("timed out fetching torrent metadata")
+	ErrQuotaExceeded   = errors.New("not enough disk space for this file")
+)
+
+// Config controls the embedded torrent client
+type Config struct {
+	DataDir     string
+	DiskQuota   int64 // bytes reserved across every selected file
+	IdleTimeout time.Duration
+	ListenPort  int // zero picks a free port
+	NoDHT       bool
+	Seed        bool
+}
+
+// AddRequest mirrors the Node sidecar's /add body. Either Magnet or InfoHash
+// is required; Trackers accepts Stremio "tracker:" sources as well as plain
+// announce URLs, and Peers lists host:port addresses to dial directly.
+type AddRequest struct {
+	Magnet   string   `json:"magnet"`
+	InfoHash string   `json:"info_hash"`
+	FileIdx  *int     `json:"file_idx"`
+	Trackers []string `json:"trackers"`
+	Peers    []string `json:"peers"`
+}
+
+type File struct {
+	Index          int    `json:"index"`
+	Path           string `json:"path"`
+	Length         int64  `json:"length"`
+	BytesCompleted int64  `json:"bytes_completed"`
+	Selected       bool   `json:"selected"`
+	StreamURL      string `json:"stream_url"`
+}
+
+type Status struct {
+	InfoHash       string    `json:"info_hash"`
+	Name           string    `json:"name"`
+	Files          []File    `json:"files"`
+	FileIdx        *int      `json:"file_idx,omitempty"`
+	BytesCompleted int64     `json:"bytes_completed"`
+	ReservedBytes  int64     `json:"reserved_bytes"`
+	ActivePeers    int       `json:"active_peers"`
+	TotalPeers     int       `json:"total_peers"`
+	Streams        int       `json:"streams"`
+	LastUsed       time.Time `json:"last_used"`
+}
+
+type Usage struct {
+	Torrents   []Status `json:"torrents"`
+	UsedBytes  int64    `json:"used_bytes"`
+	QuotaBytes int64    `json:"quota_bytes"`
+}
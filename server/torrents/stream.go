@@ -0,0 +1,34 @@
+package torrents
+
+import (
+	"context"
+	"sync"
+
+	"github.com/anacrolix/torrent"
+)
+
+// Stream is an io.ReadSeekCloser over one torrent file. Reads wait for
+// missing pieces until the request context is cancelled.
+type Stream struct {
+	ctx     context.Context
+	reader  torrent.Reader
+	Name    string
+	manager *Manager
+	entry   *entry
+	once    sync.Once
+}
+
+func (s *Stream) Read(p []byte) (int, error) {
+	return s.reader.ReadContext(s.ctx, p)
+}
+
+func (s *Stream) Seek(offset int64, whence int) (int64, error) {
+	return s.reader.Seek(offset, whence)
+}
+
+func (s *Stream) Close() error {
+	s.once.Do(func() {
+		s.manager.closeStream(s.entry)
+	})
+	return s.reader.Close()
+}
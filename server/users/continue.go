@@ -0,0 +1,60 @@
+package users
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"zync-stream/metadata"
+)
+
+// ContinueWatchingItem is either an unfinished entry to resume or, for a
+// finished episode, the episode that comes after it
+type ContinueWatchingItem struct {
+	Kind        string             `json:"kind"` // ContinueResume or ContinueNextEpisode
+	Entry       *WatchHistoryEntry `json:"entry"`
+	NextEpisode *metadata.Video    `json:"next_episode,omitempty"`
+}
+
+// resolveContinueWatching looks up next episodes concurrently. Finished
+// episodes without a known, aired successor are dropped.
+func resolveContinueWatching(ctx context.Context, provider metadata.Provider, entries []*WatchHistoryEntry) []ContinueWatchingItem {
+	slots := make([]*ContinueWatchingItem, len(entries))
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	for i, entry := range entries {
+		if entry.PercentageWatched < CompletedThreshold {
+			slots[i] = &ContinueWatchingItem{Kind: ContinueResume, Entry: entry}
+			continue
+		}
+		if provider == nil || entry.SeasonNumber == nil || entry.EpisodeNumber == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, entry *WatchHistoryEntry) {
+			defer wg.Done()
+
+			meta, err := provider.Meta(ctx, entry.MediaType, entry.ImdbID)
+			if err != nil {
+				log.Printf("Failed to resolve next episode of %s: %v", entry.ImdbID, err)
+				return
+			}
+
+			if next := metadata.NextEpisode(meta, *entry.SeasonNumber, *entry.EpisodeNumber, now); next != nil {
+				slots[i] = &ContinueWatchingItem{Kind: ContinueNextEpisode, Entry: entry, NextEpisode: next}
+			}
+		}(i, entry)
+	}
+	wg.Wait()
+
+	items := []ContinueWatchingItem{}
+	for _, item := range slots {
+		if item != nil {
+			items = append(items, *item)
+		}
+	}
+	return items
+}
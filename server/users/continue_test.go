@@ -0,0 +1,88 @@
+package users
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"zync-stream/httpcache"
+	"zync-stream/metadata"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestResolveContinueWatching(t *testing.T) {
+	cinemeta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/meta/series/tt0903747.json":
+			w.Write([]byte(`{"meta":{"id":"tt0903747","type":"series","name":"Breaking Bad","videos":[
+				{"id":"tt0903747:1:1","season":1,"episode":1,"released":"2008-01-20T00:00:00.000Z"},
+				{"id":"tt0903747:1:2","season":1,"episode":2,"released":"2008-01-27T00:00:00.000Z"},
+				{"id":"tt0903747:1:3","season":1,"episode":3,"released":"2999-01-01T00:00:00.000Z"}
+			]}}`))
+		case "/meta/series/tt0000001.json":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer cinemeta.Close()
+
+	provider := metadata.NewCinemetaProvider(cinemeta.URL, httpcache.NewCache(nil, http.DefaultClient, time.Minute, time.Minute))
+
+	entries := []*WatchHistoryEntry{
+		{ID: 1, ImdbID: "tt0111161", MediaType: "movie", PercentageWatched: 40},
+		{ID: 2, ImdbID: "tt0903747", MediaType: "series", SeasonNumber: intPtr(1), EpisodeNumber: intPtr(1), PercentageWatched: 95},
+		{ID: 3, ImdbID: "tt0903747", MediaType: "series", SeasonNumber: intPtr(1), EpisodeNumber: intPtr(2), PercentageWatched: 100},
+		{ID: 4, ImdbID: "tt0000001", MediaType: "series", SeasonNumber: intPtr(1), EpisodeNumber: intPtr(1), PercentageWatched: 100},
+		{ID: 5, ImdbID: "tt0068646", MediaType: "movie", PercentageWatched: 100},
+		{ID: 6, ImdbID: "tt0903747", MediaType: "series", SeasonNumber: intPtr(1), EpisodeNumber: intPtr(1), PercentageWatched: 12},
+	}
+
+	items := resolveContinueWatching(context.Background(), provider, entries)
+
+	// finished movies, unaired successors and failed lookups are dropped,
+	// and the rest keep the history order
+	want := []struct {
+		entryID int
+		kind    string
+		next    string
+	}{
+		{1, ContinueResume, ""},
+		{2, ContinueNextEpisode, "tt0903747:1:2"},
+		{6, ContinueResume, ""},
+	}
+
+	if len(items) != len(want) {
+		t.Fatalf("resolveContinueWatching() returned %d items: %+v", len(items), items)
+	}
+	for i, w := range want {
+		item := items[i]
+		if item.Entry.ID != w.entryID || item.Kind != w.kind {
+			t.Errorf("item %d = entry %d %s, want entry %d %s", i, item.Entry.ID, item.Kind, w.entryID, w.kind)
+		}
+		next := ""
+		if item.NextEpisode != nil {
+			next = item.NextEpisode.ID
+		}
+		if next != w.next {
+			t.Errorf("item %d next episode = %q, want %q", i, next, w.next)
+		}
+	}
+}
+
+func TestResolveContinueWatchingWithoutProvider(t *testing.T) {
+	entries := []*WatchHistoryEntry{
+		{ID: 1, ImdbID: "tt0903747", MediaType: "series", SeasonNumber: intPtr(1), EpisodeNumber: intPtr(1), PercentageWatched: 100},
+		{ID: 2, ImdbID: "tt0111161", MediaType: "movie", PercentageWatched: 10},
+	}
+
+	items := resolveContinueWatching(context.Background(), nil, entries)
+	if len(items) != 1 || items[0].Entry.ID != 2 || items[0].Kind != ContinueResume {
+		t.Errorf("resolveContinueWatching() = %+v", items)
+	}
+}
@@ -0,0 +1,61 @@
+package users
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"zync-stream/media"
+)
+
+const defaultDeletionGracePeriod = 30 * 24 * time.Hour
+
+// AccountDeletionGracePeriod is read from ACCOUNT_DELETION_GRACE (a Go
+// duration such as "720h") and defaults to 30 days.
+func AccountDeletionGracePeriod() time.Duration {
+	if value := os.Getenv("ACCOUNT_DELETION_GRACE"); value != "" {
+		if grace, err := time.ParseDuration(value); err == nil && grace >= 0 {
+			return grace
+		}
+		log.Printf("warning: invalid ACCOUNT_DELETION_GRACE %q, using default", value)
+	}
+	return defaultDeletionGracePeriod
+}
+
+// StartDeletionPurger periodically hard deletes accounts whose grace period
+// has expired, along with their uploaded avatars. It stops when ctx is done.
+func StartDeletionPurger(ctx context.Context, repo *UserRepo, avatars media.Storage, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			purgeDeletedUsers(ctx, repo, avatars)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func purgeDeletedUsers(ctx context.Context, repo *UserRepo, avatars media.Storage) {
+	purgeCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	purged, err := repo.PurgeDeletedUsers(purgeCtx)
+	if err != nil {
+		log.Printf("Failed to purge deleted users: %v", err)
+		return
+	}
+
+	for _, user := range purged {
+		if avatars != nil && user.ProfilePictureURL != "" {
+			deleteAvatarFiles(purgeCtx, avatars, user.ID, user.ProfilePictureURL)
+		}
+		log.Printf("Purged deleted user %d", user.ID)
+	}
+}
@@ -1,12 +1,25 @@
 package users
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+	"zync-stream/addons"
+	"zync-stream/media"
+	"zync-stream/metadata"
+	"zync-stream/middleware"
 	"zync-stream/ws"
 
 	"github.com/gin-gonic/gin"
@@ -16,14 +29,22 @@ import (
 )
 
 type UserHandlers struct {
-	repo  *UserRepo
-	redis *redis.Client
+	repo      *UserRepo
+	redis     *redis.Client
+	avatars   media.Storage
+	metadata  metadata.Provider
+	addons    *addons.AddonRepository
+	manifests *addons.ManifestClient
 }
 
-func NewHandlers(repo *UserRepo, redis *redis.Client) *UserHandlers {
+func NewHandlers(repo *UserRepo, redis *redis.Client, avatars media.Storage, meta metadata.Provider, addonRepo *addons.AddonRepository, manifests *addons.ManifestClient) *UserHandlers {
 	return &UserHandlers{
-		repo:  repo,
-		redis: redis,
+		repo:      repo,
+		redis:     redis,
+		avatars:   avatars,
+		metadata:  meta,
+		addons:    addonRepo,
+		manifests: manifests,
 	}
 }
 
@@ -165,6 +186,15 @@ func (h *UserHandlers) Login(c *gin.Context) {
 
 	h.repo.UpdateLastLogin(ctx, user.ID)
 
+	// logging back in during the grace period restores the account
+	deletionCancelled, err := h.repo.CancelDeletion(ctx, user.ID)
+	if err != nil {
+		log.Printf("Error cancelling deletion for user %d: %v", user.ID, err)
+	}
+	if deletionCancelled {
+		middleware.ForgetAccount(user.ID)
+	}
+
 	token, err := GenerateJWT(user)
 	if err != nil {
 		log.Printf("DATABASE ERROR: %v", err)
@@ -181,7 +211,8 @@ func (h *UserHandlers) Login(c *gin.Context) {
 			"profile_picture": user.ProfilePictureURL,
 			"bio":             user.Bio,
 		},
-		"token": token,
+		"token":              token,
+		"deletion_cancelled": deletionCancelled,
 	})
 }
 
@@ -253,6 +284,121 @@ func (h *UserHandlers) ChangePassword(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
 }
 
+func (h *UserHandlers) ExportData(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		h.respondWithError(c, http.StatusBadRequest, "Format must be json or zip")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	export, err := h.repo.ExportUserData(ctx, userID.(int))
+	if err != nil {
+		log.Printf("Error exporting data for user %d: %v", userID.(int), err)
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to export user data")
+		return
+	}
+
+	if export == nil {
+		h.respondWithError(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	filename := fmt.Sprintf("zync-export-%s-%s", export.Profile.Username, export.ExportedAt.Format("20060102"))
+
+	if format == "json" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+		c.JSON(http.StatusOK, export)
+		return
+	}
+
+	files := map[string]interface{}{
+		"profile.json":         export.Profile,
+		"friends.json":         export.Friends,
+		"rooms.json":           export.Rooms,
+		"messages.json":        export.Messages,
+		"watch_history.json":   export.WatchHistory,
+		"extensions.json":      export.Extensions,
+		"direct_messages.json": export.DirectMessages,
+		"notifications.json":   export.Notifications,
+		"watchlists.json":      export.Watchlists,
+		"reviews.json":         export.Reviews,
+		"addons.json":          export.Addons,
+		"webhooks.json":        export.Webhooks,
+		"sessions.json":        export.Sessions,
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			h.respondWithError(c, http.StatusInternalServerError, "Failed to build export archive")
+			return
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(content); err != nil {
+			h.respondWithError(c, http.StatusInternalServerError, "Failed to build export archive")
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to build export archive")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+func (h *UserHandlers) DeleteAccount(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, "Password is required to delete your account")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.repo.GetByID(ctx, userID.(int))
+	if err != nil || user == nil {
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to retrieve user")
+		return
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		h.respondWithError(c, http.StatusUnauthorized, "Password is incorrect")
+		return
+	}
+
+	deleteAt := time.Now().Add(AccountDeletionGracePeriod())
+	if err := h.repo.ScheduleDeletion(ctx, user.ID, deleteAt); err != nil {
+		log.Printf("Error scheduling deletion for user %d: %v", user.ID, err)
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to schedule account deletion")
+		return
+	}
+	middleware.ForgetAccount(user.ID)
+
+	log.Printf("Account deletion scheduled for user %d at %s", user.ID, deleteAt.Format(time.RFC3339))
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":     "Account scheduled for deletion and signed out everywhere. Log in again before the deletion date to cancel.",
+		"deletion_at": deleteAt,
+	})
+}
+
 func (h *UserHandlers) UpdateAvatar(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
@@ -265,6 +411,11 @@ func (h *UserHandlers) UpdateAvatar(c *gin.Context) {
 		return
 	}
 
+	if !ownsAvatar(userID.(int), req.AvatarURL) {
+		h.respondWithError(c, http.StatusForbidden, "Avatar belongs to another user")
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
@@ -277,6 +428,124 @@ func (h *UserHandlers) UpdateAvatar(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Avatar updated successfully"})
 }
 
+func (h *UserHandlers) UploadAvatar(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if h.avatars == nil {
+		h.respondWithError(c, http.StatusServiceUnavailable, "Avatar storage unavailable")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, media.MaxAvatarBytes+1<<20)
+
+	file, _, err := c.Request.FormFile("avatar")
+	if err != nil {
+		h.respondWithError(c, http.StatusBadRequest, "Avatar file is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, media.MaxAvatarBytes+1))
+	if err != nil {
+		h.respondWithError(c, http.StatusBadRequest, "Failed to read avatar")
+		return
+	}
+
+	if len(data) > media.MaxAvatarBytes {
+		h.respondWithError(c, http.StatusRequestEntityTooLarge, "Avatar must be 5MB or smaller")
+		return
+	}
+
+	if _, err := media.SniffImageType(data); err != nil {
+		h.respondWithError(c, http.StatusUnsupportedMediaType, "Avatar must be a JPEG, PNG or GIF image")
+		return
+	}
+
+	thumbs, err := media.MakeThumbnails(data, media.AvatarSizes)
+	if err != nil {
+		if errors.Is(err, media.ErrImageTooLarge) {
+			h.respondWithError(c, http.StatusBadRequest, "Avatar dimensions are too large")
+			return
+		}
+		h.respondWithError(c, http.StatusBadRequest, "Invalid image")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	user, err := h.repo.GetByID(ctx, userID.(int))
+	if err != nil || user == nil {
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to retrieve user")
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:8])
+
+	urls := make(map[string]string, len(thumbs))
+	for _, size := range media.AvatarSizes {
+		name := fmt.Sprintf("%d/%s_%d.jpg", user.ID, hash, size)
+		thumb := thumbs[size]
+
+		if err := h.avatars.Put(ctx, "avatars/"+name, bytes.NewReader(thumb), int64(len(thumb)), "image/jpeg"); err != nil {
+			log.Printf("Error storing avatar for user %d: %v", user.ID, err)
+			h.respondWithError(c, http.StatusInternalServerError, "Failed to store avatar")
+			return
+		}
+		urls[strconv.Itoa(size)] = media.AvatarURLPrefix + name
+	}
+
+	avatarURL := urls[strconv.Itoa(media.AvatarSizes[len(media.AvatarSizes)-1])]
+	if err := h.repo.UpdateAvatar(ctx, user.ID, avatarURL); err != nil {
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to update avatar")
+		return
+	}
+
+	if user.ProfilePictureURL != "" && user.ProfilePictureURL != avatarURL {
+		deleteAvatarFiles(ctx, h.avatars, user.ID, user.ProfilePictureURL)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Avatar updated successfully",
+		"avatar_url": avatarURL,
+		"thumbnails": urls,
+	})
+}
+
+// ownsAvatar reports whether an avatar URL is external or one of the
+// user's own uploads
+func ownsAvatar(userID int, avatarURL string) bool {
+	key, ok := media.AvatarKey(avatarURL)
+	if !ok {
+		return !strings.Contains(avatarURL, media.AvatarURLPrefix)
+	}
+	return strings.HasPrefix(key, fmt.Sprintf("avatars/%d/", userID)) && !strings.Contains(key, "..")
+}
+
+// deleteAvatarFiles removes every thumbnail of an avatar the user uploaded.
+// External URLs and other users' uploads are left alone.
+func deleteAvatarFiles(ctx context.Context, storage media.Storage, userID int, avatarURL string) {
+	key, ok := media.AvatarKey(avatarURL)
+	if !ok || !ownsAvatar(userID, avatarURL) {
+		return
+	}
+
+	suffix := fmt.Sprintf("_%d.jpg", media.AvatarSizes[len(media.AvatarSizes)-1])
+	if !strings.HasSuffix(key, suffix) {
+		return
+	}
+	base := strings.TrimSuffix(key, suffix)
+
+	for _, size := range media.AvatarSizes {
+		if err := storage.Delete(ctx, fmt.Sprintf("%s_%d.jpg", base, size)); err != nil {
+			log.Printf("Failed to delete old avatar %s: %v", base, err)
+		}
+	}
+}
+
+// UpdateExtensions validates every addon manifest before replacing the
+// user's extensions. Nothing is saved unless all of them pass.
 func (h *UserHandlers) UpdateExtensions(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -293,14 +562,27 @@ func (h *UserHandlers) UpdateExtensions(c *gin.Context) {
 		return
 	}
 
-	if req.Extensions == nil {
-		req.Extensions = []string{}
+	if len(req.Extensions) > addons.MaxAddonsPerUser {
+		h.respondWithError(c, http.StatusBadRequest, fmt.Sprintf("At most %d extensions are allowed", addons.MaxAddonsPerUser))
+		return
+	}
+
+	fetchCtx, cancelFetch := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancelFetch()
+
+	resolved, itemErrors := h.manifests.ResolveAll(fetchCtx, req.Extensions)
+	if len(itemErrors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Some extensions could not be added",
+			"errors": itemErrors,
+		})
+		return
 	}
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	err := h.repo.UpdateExtensions(ctx, userID.(int), req.Extensions)
+	extensions, err := h.addons.ReplaceForUser(ctx, userID.(int), resolved)
 	if err != nil {
 		log.Printf("Error updating extensions: %v", err)
 		h.respondWithError(c, http.StatusInternalServerError, "Failed to update extensions")
@@ -309,7 +591,143 @@ func (h *UserHandlers) UpdateExtensions(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Extensions updated successfully",
-		"extensions": req.Extensions,
+		"extensions": extensions,
+	})
+}
+
+func (h *UserHandlers) GetAddons(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	installed, err := h.addons.ListForUser(ctx, userID.(int))
+	if err != nil {
+		log.Printf("Error retrieving addons: %v", err)
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to retrieve addons")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"addons": installed})
+}
+
+// ReorderAddons sets the order addons are queried and listed in
+func (h *UserHandlers) ReorderAddons(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req struct {
+		AddonIDs []int `json:"addon_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	extensions, err := h.addons.Reorder(ctx, userID.(int), req.AddonIDs)
+	if err != nil {
+		if errors.Is(err, addons.ErrInvalidOrder) {
+			h.respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Error reordering addons: %v", err)
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to reorder addons")
+		return
+	}
+
+	h.respondWithAddons(c, userID.(int), extensions)
+}
+
+// UpdateAddon enables or disables an addon and changes its settings. New
+// settings are only saved once the configured manifest validates.
+func (h *UserHandlers) UpdateAddon(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	addonID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.respondWithError(c, http.StatusBadRequest, "Invalid addon ID")
+		return
+	}
+
+	var req struct {
+		Enabled *bool   `json:"enabled"`
+		Config  *string `json:"config"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	addon, err := h.addons.GetForUser(ctx, userID.(int), addonID)
+	if err != nil {
+		log.Printf("Error retrieving addon: %v", err)
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to retrieve addon")
+		return
+	}
+	if addon == nil {
+		h.respondWithError(c, http.StatusNotFound, "Addon not found")
+		return
+	}
+
+	enabled := addon.Enabled
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	var configured *addons.Resolved
+	config := addon.Config
+	if req.Config != nil && *req.Config != addon.Config {
+		config = strings.TrimSpace(*req.Config)
+		if err := addons.ValidateConfig(config); err != nil {
+			h.respondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		manifest, raw, err := h.manifests.Fetch(ctx, addons.ConfiguredURL(addon.TransportURL, config))
+		if err != nil {
+			h.respondWithError(c, http.StatusUnprocessableEntity, "Configured addon is invalid: "+err.Error())
+			return
+		}
+		if manifest.ID != addon.AddonID {
+			h.respondWithError(c, http.StatusUnprocessableEntity, "Configured addon has a different id")
+			return
+		}
+		configured = &addons.Resolved{URL: addon.TransportURL, Manifest: manifest, Raw: raw}
+	}
+
+	extensions, err := h.addons.UpdateSettings(ctx, userID.(int), addonID, enabled, config, configured)
+	if err != nil {
+		if errors.Is(err, addons.ErrNotInstalled) {
+			h.respondWithError(c, http.StatusNotFound, "Addon not found")
+			return
+		}
+		log.Printf("Error updating addon: %v", err)
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to update addon")
+		return
+	}
+
+	h.respondWithAddons(c, userID.(int), extensions)
+}
+
+func (h *UserHandlers) respondWithAddons(c *gin.Context, userID int, extensions []string) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	installed, err := h.addons.ListForUser(ctx, userID)
+	if err != nil {
+		log.Printf("Error retrieving addons: %v", err)
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to retrieve addons")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"addons":     installed,
+		"extensions": extensions,
 	})
 }
 
@@ -367,6 +785,74 @@ func (h *UserHandlers) UpdateStatus(c *gin.Context) {
 	}
 }
 
+func (h *UserHandlers) GetPrivacySettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	settings, err := h.repo.GetPrivacySettings(ctx, userID.(int))
+	if err != nil {
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to retrieve privacy settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *UserHandlers) UpdatePrivacySettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req struct {
+		HideActivity      *bool   `json:"hide_activity"`
+		Invisible         *bool   `json:"invisible"`
+		FriendRequests    *string `json:"friend_requests" binding:"omitempty,oneof=everyone nobody"`
+		ProfileVisibility *string `json:"profile_visibility" binding:"omitempty,oneof=everyone friends nobody"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	settings, err := h.repo.GetPrivacySettings(ctx, userID.(int))
+	if err != nil {
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to retrieve privacy settings")
+		return
+	}
+
+	if req.HideActivity != nil {
+		settings.HideActivity = *req.HideActivity
+	}
+	if req.Invisible != nil {
+		settings.Invisible = *req.Invisible
+	}
+	if req.FriendRequests != nil {
+		settings.FriendRequests = *req.FriendRequests
+	}
+	if req.ProfileVisibility != nil {
+		settings.ProfileVisibility = *req.ProfileVisibility
+	}
+
+	if err := h.repo.UpdatePrivacySettings(ctx, userID.(int), settings); err != nil {
+		log.Printf("Error updating privacy settings: %v", err)
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to update privacy settings")
+		return
+	}
+
+	if presenceManager := ws.GetPresenceManager(); presenceManager != nil {
+		presenceManager.SetPrivacy(userID.(int), ws.PresencePrivacy{
+			HideActivity: settings.HideActivity,
+			Invisible:    settings.Invisible,
+		})
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
 func (h *UserHandlers) UpdateWatchHistory(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
@@ -404,41 +890,121 @@ func (h *UserHandlers) UpdateWatchHistory(c *gin.Context) {
 func (h *UserHandlers) GetWatchHistory(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
+	filter := WatchHistoryFilter{
+		MediaType: c.Query("media_type"),
+		Status:    c.Query("status"),
+		ImdbID:    c.Query("imdb_id"),
+		Limit:     DefaultHistoryPageSize,
+	}
+
+	if filter.MediaType != "" && filter.MediaType != "movie" && filter.MediaType != "series" {
+		h.respondWithError(c, http.StatusBadRequest, "media_type must be movie or series")
+		return
+	}
+
+	if filter.Status != "" && filter.Status != HistoryCompleted && filter.Status != HistoryInProgress {
+		h.respondWithError(c, http.StatusBadRequest, "status must be completed or in_progress")
+		return
+	}
+
+	var err error
+	if filter.From, err = parseHistoryDate(c.Query("from"), false); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, "Invalid from date")
+		return
+	}
+	if filter.To, err = parseHistoryDate(c.Query("to"), true); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, "Invalid to date")
+		return
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		filter.Limit, err = strconv.Atoi(limitStr)
+		if err != nil || filter.Limit < 1 {
+			h.respondWithError(c, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		filter.Limit = min(filter.Limit, MaxHistoryPageSize)
+	}
+
+	if beforeStr := c.Query("before"); beforeStr != "" {
+		filter.BeforeID, err = strconv.Atoi(beforeStr)
+		if err != nil || filter.BeforeID < 0 {
+			h.respondWithError(c, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	entries, err := h.repo.GetWatchHistory(ctx, userID.(int))
+	// fetch one extra row to know whether another page exists
+	limit := filter.Limit
+	filter.Limit++
+	entries, err := h.repo.GetWatchHistory(ctx, userID.(int), filter)
 	if err != nil {
 		log.Printf("Error retrieving watch history: %v", err)
 		h.respondWithError(c, http.StatusInternalServerError, "Failed to retrieve watch history")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"history": entries})
+	hasMore := len(entries) > limit
+	if hasMore {
+		entries = entries[:limit]
+	}
+
+	response := gin.H{
+		"history":  entries,
+		"has_more": hasMore,
+	}
+	if hasMore {
+		response["next_before"] = entries[len(entries)-1].ID
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
-func (h *UserHandlers) GetWatchHistoryItem(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	imdbID := c.Param("imdb_id")
+// parseHistoryDate accepts RFC 3339 timestamps or plain dates. A plain "to"
+// date is inclusive, so it is moved to the start of the following day.
+func parseHistoryDate(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
 
-	var seasonNum, episodeNum *int
-	seasonStr := c.Query("season")
-	episodeStr := c.Query("episode")
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
 
-	if seasonStr != "" {
-		season, err := strconv.Atoi(seasonStr)
-		if err == nil {
-			seasonNum = &season
-		}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
 	}
+	return &t, nil
+}
 
-	if episodeStr != "" {
-		episode, err := strconv.Atoi(episodeStr)
-		if err == nil {
-			episodeNum = &episode
-		}
+func (h *UserHandlers) GetContinueWatching(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	entries, err := h.repo.GetContinueWatching(ctx, userID.(int), ContinueWatchingPageSize)
+	if err != nil {
+		log.Printf("Error retrieving continue watching: %v", err)
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to retrieve continue watching")
+		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{"continue_watching": resolveContinueWatching(ctx, h.metadata, entries)})
+}
+
+func (h *UserHandlers) GetWatchHistoryItem(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	imdbID := c.Param("imdb_id")
+	seasonNum, episodeNum := episodeQuery(c)
+
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
@@ -457,6 +1023,66 @@ func (h *UserHandlers) GetWatchHistoryItem(c *gin.Context) {
 	c.JSON(http.StatusOK, entry)
 }
 
+func (h *UserHandlers) DeleteWatchHistoryItem(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	imdbID := c.Param("imdb_id")
+	seasonNum, episodeNum := episodeQuery(c)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	deleted, err := h.repo.DeleteWatchHistoryItem(ctx, userID.(int), imdbID, seasonNum, episodeNum)
+	if err != nil {
+		log.Printf("Error deleting watch history item: %v", err)
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to delete watch history item")
+		return
+	}
+
+	if !deleted {
+		h.respondWithError(c, http.StatusNotFound, "No watch history found for this content")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Watch history entry deleted"})
+}
+
+func (h *UserHandlers) ClearWatchHistory(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	deleted, err := h.repo.ClearWatchHistory(ctx, userID.(int))
+	if err != nil {
+		log.Printf("Error clearing watch history: %v", err)
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to clear watch history")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Watch history cleared", "deleted": deleted})
+}
+
+// episodeQuery reads the optional ?season=&episode= selectors
+func episodeQuery(c *gin.Context) (*int, *int) {
+	var seasonNum, episodeNum *int
+
+	if seasonStr := c.Query("season"); seasonStr != "" {
+		season, err := strconv.Atoi(seasonStr)
+		if err == nil {
+			seasonNum = &season
+		}
+	}
+
+	if episodeStr := c.Query("episode"); episodeStr != "" {
+		episode, err := strconv.Atoi(episodeStr)
+		if err == nil {
+			episodeNum = &episode
+		}
+	}
+
+	return seasonNum, episodeNum
+}
+
 func (h *UserHandlers) SearchUsers(c *gin.Context) {
 	query := c.Query("q")
 	if query == "" {
@@ -467,7 +1093,7 @@ func (h *UserHandlers) SearchUsers(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	users, err := h.repo.SearchUsers(ctx, query)
+	users, err := h.repo.SearchUsers(ctx, c.GetInt("user_id"), query)
 	if err != nil {
 		h.respondWithError(c, http.StatusInternalServerError, "Failed to search users")
 		return
@@ -535,6 +1161,30 @@ func (h *UserHandlers) SendFriendRequest(c *gin.Context) {
 		return
 	}
 
+	blocked, err := h.repo.IsBlocked(ctx, senderID, targetUser.ID)
+	if err != nil {
+		log.Printf("Error checking blocks between %d and %d: %v", senderID, targetUser.ID, err)
+		h.respondWithError(c, http.StatusInternalServerError, "Error checking user settings")
+		return
+	}
+
+	if blocked {
+		h.respondWithError(c, http.StatusForbidden, "Cannot send friend request to this user")
+		return
+	}
+
+	targetPrivacy, err := h.repo.GetPrivacySettings(ctx, targetUser.ID)
+	if err != nil {
+		log.Printf("Error loading privacy settings for user %d: %v", targetUser.ID, err)
+		h.respondWithError(c, http.StatusInternalServerError, "Error checking user settings")
+		return
+	}
+
+	if targetPrivacy.FriendRequests == FriendRequestsNobody {
+		h.respondWithError(c, http.StatusForbidden, "This user is not accepting friend requests")
+		return
+	}
+
 	existingFriend, err := h.repo.GetFriendship(ctx, senderID, targetUser.ID)
 	if err != nil {
 		log.Printf("Error checking existing friendship: %v", err)
@@ -713,6 +1363,85 @@ func (h *UserHandlers) RemoveFriend(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Friend removed successfully"})
 }
 
+func (h *UserHandlers) GetBlockedUsers(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	blocked, err := h.repo.GetBlockedUsers(ctx, userID.(int))
+	if err != nil {
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to get blocked users")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"blocked": blocked})
+}
+
+func (h *UserHandlers) BlockUser(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req struct {
+		Username string `json:"username" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	targetUser, err := h.repo.GetByUsername(ctx, req.Username)
+	if err != nil || targetUser == nil {
+		h.respondWithError(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if targetUser.ID == userID.(int) {
+		h.respondWithError(c, http.StatusBadRequest, "Cannot block yourself")
+		return
+	}
+
+	if err := h.repo.BlockUser(ctx, userID.(int), targetUser.ID); err != nil {
+		log.Printf("Error blocking user %d for %d: %v", targetUser.ID, userID.(int), err)
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to block user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User blocked"})
+}
+
+func (h *UserHandlers) UnblockUser(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req struct {
+		Username string `json:"username" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	targetUser, err := h.repo.GetByUsername(ctx, req.Username)
+	if err != nil || targetUser == nil {
+		h.respondWithError(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err := h.repo.UnblockUser(ctx, userID.(int), targetUser.ID); err != nil {
+		h.respondWithError(c, http.StatusInternalServerError, "Failed to unblock user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unblocked"})
+}
+
 func (h *UserHandlers) getCurrentUsername(c *gin.Context) string {
 	if username, exists := c.Get("username"); exists {
 		return username.(string)
@@ -1,9 +1,32 @@
 package users
 
 import (
+	"encoding/json"
 	"time"
 )
 
+const (
+	FriendRequestsEveryone = "everyone"
+	FriendRequestsNobody   = "nobody"
+
+	VisibilityEveryone = "everyone"
+	VisibilityFriends  = "friends"
+	VisibilityNobody   = "nobody"
+
+	HistoryCompleted  = "completed"
+	HistoryInProgress = "in_progress"
+
+	// CompletedThreshold is the percentage at which a title counts as watched
+	CompletedThreshold = 90.0
+
+	DefaultHistoryPageSize   = 20
+	MaxHistoryPageSize       = 100
+	ContinueWatchingPageSize = 20
+
+	ContinueResume      = "resume"
+	ContinueNextEpisode = "next_episode"
+)
+
 type User struct {
 	ID                int       `json:"id"`
 	Username          string    `json:"username"`
@@ -42,6 +65,17 @@ type WatchHistoryRequest struct {
 	PercentageWatched float64 `json:"percentage_watched" binding:"required,min=0,max=100"`
 }
 
+// WatchHistoryFilter narrows a watch history page. Zero values match everything.
+type WatchHistoryFilter struct {
+	MediaType string
+	Status    string // HistoryCompleted or HistoryInProgress
+	ImdbID    string
+	From      *time.Time
+	To        *time.Time
+	BeforeID  int // cursor: the last entry of the previous page
+	Limit     int
+}
+
 type FriendRequestDetails struct {
 	ID         int    `json:"id"`
 	SenderID   int    `json:"sender_id"`
@@ -57,3 +91,59 @@ type Friendship struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+type ExportedRoom struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	OwnerID     int       `json:"owner_id"`
+	IsPrivate   bool      `json:"is_private"`
+	Role        string    `json:"role"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+type ExportedMessage struct {
+	ID        int       `json:"id"`
+	RoomID    int       `json:"room_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UserExport struct {
+	ExportedAt   time.Time                `json:"exported_at"`
+	Profile      *User                    `json:"profile"`
+	Friends      []map[string]interface{} `json:"friends"`
+	Rooms        []ExportedRoom           `json:"rooms"`
+	Messages     []ExportedMessage        `json:"messages"`
+	WatchHistory []*WatchHistoryEntry     `json:"watch_history"`
+	Extensions   []string                 `json:"extensions"`
+
+	// the remaining sections are exported as the rows are stored
+	DirectMessages json.RawMessage `json:"direct_messages"`
+	Notifications  json.RawMessage `json:"notifications"`
+	Watchlists     json.RawMessage `json:"watchlists"`
+	Reviews        json.RawMessage `json:"reviews"`
+	Addons         json.RawMessage `json:"addons"`
+	Webhooks       json.RawMessage `json:"webhooks"`
+	Sessions       json.RawMessage `json:"sessions"`
+}
+
+type PurgedUser struct {
+	ID                int
+	ProfilePictureURL string
+}
+
+type PrivacySettings struct {
+	HideActivity      bool      `json:"hide_activity"`
+	Invisible         bool      `json:"invisible"`
+	FriendRequests    string    `json:"friend_requests"`    // "everyone" or "nobody"
+	ProfileVisibility string    `json:"profile_visibility"` // "everyone", "friends" or "nobody"
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+func DefaultPrivacySettings() *PrivacySettings {
+	return &PrivacySettings{
+		FriendRequests:    FriendRequestsEveryone,
+		ProfileVisibility: VisibilityEveryone,
+	}
+}
@@ -2,8 +2,10 @@ package users
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
+	"strings"
 	"time"
 	"zync-stream/ws"
 
@@ -149,24 +151,6 @@ func (r *UserRepo) Update(ctx context.Context, user *User) error {
 	return r.db.QueryRow(ctx, query, user.DisplayName, user.ProfilePictureURL, user.Bio, user.ID).Scan(&user.UpdatedAt)
 }
 
-func (r *UserRepo) UpdateExtensions(ctx context.Context, userID int, extensions []string) error {
-	query := `
-    UPDATE users
-    SET extensions = $1, updated_at = NOW()
-    WHERE id = $2
-    RETURNING extensions
-    `
-	var updatedExtensions []string
-	err := r.db.QueryRow(ctx, query, extensions, userID).Scan(&updatedExtensions)
-	if err != nil {
-		log.Printf("Error updating extensions: %v", err)
-		return err
-	}
-
-	log.Printf("Extensions after update: %v", updatedExtensions)
-	return nil
-}
-
 func (r *UserRepo) UpdateLastLogin(ctx context.Context, id int) error {
 	query := `
     UPDATE users
@@ -214,33 +198,21 @@ func (r *UserRepo) UpdateWatchHistory(ctx context.Context, userID int, req *Watc
 	}
 
 	query := `
-    WITH upsert AS (
-        INSERT INTO watch_history 
-        (user_id, imdb_id, media_type, season_number, episode_number, 
+        INSERT INTO watch_history
+        (user_id, imdb_id, media_type, season_number, episode_number,
         timestamp_seconds, duration_seconds, percentage_watched, last_watched)
         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
-        ON CONFLICT (user_id, imdb_id, COALESCE(season_number, 0), COALESCE(episode_number, 0)) 
+        ON CONFLICT (user_id, imdb_id, season_number, episode_number)
         DO UPDATE SET
             timestamp_seconds = EXCLUDED.timestamp_seconds,
             duration_seconds = EXCLUDED.duration_seconds,
             percentage_watched = EXCLUDED.percentage_watched,
             last_watched = NOW()
-        RETURNING *
-    ),
-    cleanup AS (
-        DELETE FROM watch_history
-        WHERE id IN (
-            SELECT id FROM watch_history 
-            WHERE user_id = $1 
-            ORDER BY last_watched DESC 
-            OFFSET 10
-        )
-    )
-    SELECT * FROM upsert
+        RETURNING id, user_id, imdb_id, media_type, season_number, episode_number,
+                  timestamp_seconds, COALESCE(duration_seconds, 0), percentage_watched, last_watched
     `
 
-	var entry WatchHistoryEntry
-	err := r.db.QueryRow(ctx, query,
+	entry, err := scanWatchHistoryEntry(r.db.QueryRow(ctx, query,
 		userID,
 		req.ImdbID,
 		req.MediaType,
@@ -249,7 +221,94 @@ func (r *UserRepo) UpdateWatchHistory(ctx context.Context, userID int, req *Watc
 		req.TimestampSeconds,
 		req.DurationSeconds,
 		req.PercentageWatched,
-	).Scan(
+	))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// GetWatchHistory returns one page of the user's history, most recent first.
+// Callers pass Limit+1 to detect whether another page exists.
+func (r *UserRepo) GetWatchHistory(ctx context.Context, userID int, filter WatchHistoryFilter) ([]*WatchHistoryEntry, error) {
+	conditions := []string{"user_id = $1"}
+	args := []interface{}{userID}
+
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.MediaType != "" {
+		addCondition("media_type = $%d", filter.MediaType)
+	}
+	if filter.ImdbID != "" {
+		addCondition("imdb_id = $%d", filter.ImdbID)
+	}
+	switch filter.Status {
+	case HistoryCompleted:
+		addCondition("percentage_watched >= $%d", CompletedThreshold)
+	case HistoryInProgress:
+		addCondition("percentage_watched < $%d", CompletedThreshold)
+	}
+	if filter.From != nil {
+		addCondition("last_watched >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("last_watched < $%d", *filter.To)
+	}
+	if filter.BeforeID > 0 {
+		addCondition(`(last_watched, id) < (
+            SELECT last_watched, id FROM watch_history WHERE id = $%d AND user_id = $1
+        )`, filter.BeforeID)
+	}
+
+	args = append(args, filter.Limit)
+	query := fmt.Sprintf(`
+        SELECT id, user_id, imdb_id, media_type, season_number, episode_number,
+               timestamp_seconds, COALESCE(duration_seconds, 0), percentage_watched, last_watched
+        FROM watch_history
+        WHERE %s
+        ORDER BY last_watched DESC, id DESC
+        LIMIT $%d
+    `, strings.Join(conditions, " AND "), len(args))
+
+	return r.queryWatchHistory(ctx, query, args...)
+}
+
+// GetContinueWatching returns the latest entry of each title that is either
+// unfinished or a finished series episode (a candidate for the next episode)
+func (r *UserRepo) GetContinueWatching(ctx context.Context, userID, limit int) ([]*WatchHistoryEntry, error) {
+	query := `
+        SELECT id, user_id, imdb_id, media_type, season_number, episode_number,
+               timestamp_seconds, duration_seconds, percentage_watched, last_watched
+        FROM (
+            SELECT DISTINCT ON (imdb_id)
+                   id, user_id, imdb_id, media_type, season_number, episode_number,
+                   timestamp_seconds, COALESCE(duration_seconds, 0) AS duration_seconds,
+                   percentage_watched, last_watched
+            FROM watch_history
+            WHERE user_id = $1
+            ORDER BY imdb_id, last_watched DESC
+        ) latest
+        WHERE (percentage_watched > 0 AND percentage_watched < $2)
+           OR (media_type = 'series' AND percentage_watched >= $2)
+        ORDER BY last_watched DESC
+        LIMIT $3
+    `
+
+	return r.queryWatchHistory(ctx, query, userID, CompletedThreshold, limit)
+}
+
+// scanWatchHistoryEntry reads the columns every watch history query
+// selects. duration_seconds stays NULL until a player reports it, so it is
+// read through a pointer and left at 0 instead of failing the whole query.
+func scanWatchHistoryEntry(row pgx.Row) (*WatchHistoryEntry, error) {
+	entry := &WatchHistoryEntry{}
+	var duration *int
+	if err := row.Scan(
 		&entry.ID,
 		&entry.UserID,
 		&entry.ImdbID,
@@ -257,49 +316,29 @@ func (r *UserRepo) UpdateWatchHistory(ctx context.Context, userID int, req *Watc
 		&entry.SeasonNumber,
 		&entry.EpisodeNumber,
 		&entry.TimestampSeconds,
-		&entry.DurationSeconds,
+		&duration,
 		&entry.PercentageWatched,
 		&entry.LastWatched,
-	)
-
-	if err != nil {
+	); err != nil {
 		return nil, err
 	}
-
-	return &entry, nil
+	if duration != nil {
+		entry.DurationSeconds = *duration
+	}
+	return entry, nil
 }
 
-func (r *UserRepo) GetWatchHistory(ctx context.Context, userID int) ([]*WatchHistoryEntry, error) {
-	query := `
-    SELECT id, user_id, imdb_id, media_type, season_number, episode_number, 
-           timestamp_seconds, duration_seconds, percentage_watched, last_watched
-    FROM watch_history
-    WHERE user_id = $1
-    ORDER BY last_watched DESC
-    LIMIT 10
-    `
-
-	rows, err := r.db.Query(ctx, query, userID)
+func (r *UserRepo) queryWatchHistory(ctx context.Context, query string, args ...interface{}) ([]*WatchHistoryEntry, error) {
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var entries []*WatchHistoryEntry
+	entries := []*WatchHistoryEntry{}
 	for rows.Next() {
-		entry := &WatchHistoryEntry{}
-		if err := rows.Scan(
-			&entry.ID,
-			&entry.UserID,
-			&entry.ImdbID,
-			&entry.MediaType,
-			&entry.SeasonNumber,
-			&entry.EpisodeNumber,
-			&entry.TimestampSeconds,
-			&entry.DurationSeconds,
-			&entry.PercentageWatched,
-			&entry.LastWatched,
-		); err != nil {
+		entry, err := scanWatchHistoryEntry(rows)
+		if err != nil {
 			return nil, err
 		}
 		entries = append(entries, entry)
@@ -308,30 +347,40 @@ func (r *UserRepo) GetWatchHistory(ctx context.Context, userID int) ([]*WatchHis
 	return entries, rows.Err()
 }
 
+// DeleteWatchHistoryItem removes a single movie or episode from the history
+func (r *UserRepo) DeleteWatchHistoryItem(ctx context.Context, userID int, imdbID string, seasonNum, episodeNum *int) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+        DELETE FROM watch_history
+        WHERE user_id = $1 AND imdb_id = $2
+        AND COALESCE(season_number, 0) = COALESCE($3, 0)
+        AND COALESCE(episode_number, 0) = COALESCE($4, 0)
+    `, userID, imdbID, seasonNum, episodeNum)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete watch history entry: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ClearWatchHistory removes the user's entire history
+func (r *UserRepo) ClearWatchHistory(ctx context.Context, userID int) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM watch_history WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear watch history: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
 func (r *UserRepo) GetWatchHistoryItem(ctx context.Context, userID int, imdbID string, seasonNum, episodeNum *int) (*WatchHistoryEntry, error) {
 	query := `
     SELECT id, user_id, imdb_id, media_type, season_number, episode_number, 
-           timestamp_seconds, duration_seconds, percentage_watched, last_watched
+           timestamp_seconds, COALESCE(duration_seconds, 0), percentage_watched, last_watched
     FROM watch_history
     WHERE user_id = $1 AND imdb_id = $2 
     AND COALESCE(season_number, 0) = COALESCE($3, 0)
     AND COALESCE(episode_number, 0) = COALESCE($4, 0)
     `
 
-	entry := &WatchHistoryEntry{}
-	err := r.db.QueryRow(ctx, query, userID, imdbID, seasonNum, episodeNum).Scan(
-		&entry.ID,
-		&entry.UserID,
-		&entry.ImdbID,
-		&entry.MediaType,
-		&entry.SeasonNumber,
-		&entry.EpisodeNumber,
-		&entry.TimestampSeconds,
-		&entry.DurationSeconds,
-		&entry.PercentageWatched,
-		&entry.LastWatched,
-	)
-
+	entry, err := scanWatchHistoryEntry(r.db.QueryRow(ctx, query, userID, imdbID, seasonNum, episodeNum))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -342,15 +391,33 @@ func (r *UserRepo) GetWatchHistoryItem(ctx context.Context, userID int, imdbID s
 	return entry, nil
 }
 
-func (r *UserRepo) SearchUsers(ctx context.Context, query string) ([]map[string]interface{}, error) {
+// SearchUsers only returns profiles the searcher is allowed to see
+func (r *UserRepo) SearchUsers(ctx context.Context, searcherID int, query string) ([]map[string]interface{}, error) {
 	rows, err := r.db.Query(ctx, `
-        SELECT id, username, display_name, profile_picture_url
-        FROM users
+        SELECT u.id, u.username, u.display_name, u.profile_picture_url
+        FROM users u
+        LEFT JOIN user_privacy_settings p ON p.user_id = u.id
         WHERE 
-            username ILIKE $1 OR
-            display_name ILIKE $1
+            (u.username ILIKE $1 OR
+            u.display_name ILIKE $1) AND
+            u.deletion_scheduled_at IS NULL AND
+            NOT EXISTS (
+                SELECT 1 FROM user_blocks b
+                WHERE (b.blocker_id = $2 AND b.blocked_id = u.id) OR
+                    (b.blocker_id = u.id AND b.blocked_id = $2)
+            ) AND
+            (
+                u.id = $2 OR
+                COALESCE(p.profile_visibility, 'everyone') = 'everyone' OR
+                (p.profile_visibility = 'friends' AND EXISTS (
+                    SELECT 1 FROM friendships f
+                    WHERE f.status = 'accepted' AND
+                        ((f.user_id = u.id AND f.friend_id = $2) OR
+                        (f.user_id = $2 AND f.friend_id = u.id))
+                ))
+            )
         LIMIT 10
-    `, "%"+query+"%")
+    `, "%"+query+"%", searcherID)
 
 	if err != nil {
 		return nil, err
@@ -400,6 +467,11 @@ func (r *UserRepo) GetFriends(ctx context.Context, userID int) ([]map[string]int
         WHERE 
             (f.user_id = $1 OR f.friend_id = $1) AND
             f.status = 'accepted' AND
+            NOT EXISTS (
+                SELECT 1 FROM user_blocks b
+                WHERE (b.blocker_id = $1 AND b.blocked_id = u.id) OR
+                    (b.blocker_id = u.id AND b.blocked_id = $1)
+            ) AND
             u.id != $1
         ORDER BY u.username
     `
@@ -655,6 +727,11 @@ func (r *UserRepo) GetUserFriends(ctx context.Context, userID int) ([]int, error
         FROM friendships f
         WHERE (f.user_id = $1 OR f.friend_id = $1) 
         AND f.status = 'accepted'
+        AND NOT EXISTS (
+            SELECT 1 FROM user_blocks b
+            WHERE (b.blocker_id = f.user_id AND b.blocked_id = f.friend_id) OR
+                (b.blocker_id = f.friend_id AND b.blocked_id = f.user_id)
+        )
     `
 
 	rows, err := r.db.Query(ctx, query, userID)
@@ -689,6 +766,11 @@ func (r *UserRepo) GetFriendsWithStatus(ctx context.Context, userID int) ([]ws.F
         WHERE 
             (f.user_id = $1 OR f.friend_id = $1) AND
             f.status = 'accepted' AND
+            NOT EXISTS (
+                SELECT 1 FROM user_blocks b
+                WHERE (b.blocker_id = $1 AND b.blocked_id = u.id) OR
+                    (b.blocker_id = u.id AND b.blocked_id = $1)
+            ) AND
             u.id != $1
     `
 
@@ -709,3 +791,425 @@ func (r *UserRepo) GetFriendsWithStatus(ctx context.Context, userID int) ([]ws.F
 
 	return friends, nil
 }
+
+// ScheduleDeletion marks the account for hard deletion once the grace period
+// ends and revokes every token issued so far. JWT iat has second precision,
+// so the cut-off is truncated to match.
+func (r *UserRepo) ScheduleDeletion(ctx context.Context, userID int, at time.Time) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE users
+        SET deletion_scheduled_at = $1,
+            tokens_valid_after = date_trunc('second', NOW()),
+            updated_at = NOW()
+        WHERE id = $2
+    `, at, userID)
+	return err
+}
+
+// CancelDeletion clears a pending deletion and reports whether one existed
+func (r *UserRepo) CancelDeletion(ctx context.Context, userID int) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+        UPDATE users
+        SET deletion_scheduled_at = NULL, updated_at = NOW()
+        WHERE id = $1 AND deletion_scheduled_at IS NOT NULL
+    `, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// PurgeDeletedUsers hard deletes every account whose grace period has passed.
+// Authored room messages are kept but detached from the user, and owned rooms
+// pass to another member.
+func (r *UserRepo) PurgeDeletedUsers(ctx context.Context) ([]PurgedUser, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+        SELECT id, COALESCE(profile_picture_url, '')
+        FROM users
+        WHERE deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= NOW()
+        FOR UPDATE
+    `)
+	if err != nil {
+		return nil, err
+	}
+
+	var purged []PurgedUser
+	var ids []int
+	for rows.Next() {
+		var user PurgedUser
+		if err := rows.Scan(&user.ID, &user.ProfilePictureURL); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		purged = append(purged, user)
+		ids = append(ids, user.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE room_messages SET user_id = NULL WHERE user_id = ANY($1)`, ids); err != nil {
+		return nil, err
+	}
+
+	// deleting an owner cascades to their rooms, so hand each room to its
+	// longest-standing admin, or else member, first. Rooms nobody else is in go.
+	if _, err := tx.Exec(ctx, `
+        WITH heirs AS (
+            SELECT DISTINCT ON (m.room_id) m.room_id, m.user_id
+            FROM room_members m
+            JOIN watch_rooms r ON m.room_id = r.id
+            WHERE r.owner_id = ANY($1) AND m.user_id <> ALL($1)
+            ORDER BY m.room_id, (m.role = 'admin') DESC, m.joined_at
+        ), transferred AS (
+            UPDATE watch_rooms r
+            SET owner_id = h.user_id, updated_at = NOW()
+            FROM heirs h
+            WHERE r.id = h.room_id
+            RETURNING r.id, r.owner_id
+        )
+        UPDATE room_members m
+        SET role = 'owner'
+        FROM transferred t
+        WHERE m.room_id = t.id AND m.user_id = t.owner_id
+    `, ids); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM users WHERE id = ANY($1)`, ids); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return purged, nil
+}
+
+// ExportUserData collects everything stored about a user
+func (r *UserRepo) ExportUserData(ctx context.Context, userID int) (*UserExport, error) {
+	user, err := r.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+
+	friends, err := r.GetFriends(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &UserExport{
+		ExportedAt:   time.Now(),
+		Profile:      user,
+		Friends:      friends,
+		Rooms:        []ExportedRoom{},
+		Messages:     []ExportedMessage{},
+		WatchHistory: []*WatchHistoryEntry{},
+		Extensions:   user.Extensions,
+	}
+
+	roomRows, err := r.db.Query(ctx, `
+        SELECT r.id, r.name, COALESCE(r.description, ''), r.owner_id, r.is_private, m.role, m.joined_at
+        FROM room_members m
+        JOIN watch_rooms r ON m.room_id = r.id
+        WHERE m.user_id = $1
+        ORDER BY m.joined_at
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer roomRows.Close()
+
+	for roomRows.Next() {
+		var room ExportedRoom
+		if err := roomRows.Scan(&room.ID, &room.Name, &room.Description, &room.OwnerID,
+			&room.IsPrivate, &room.Role, &room.JoinedAt); err != nil {
+			return nil, err
+		}
+		export.Rooms = append(export.Rooms, room)
+	}
+	if err := roomRows.Err(); err != nil {
+		return nil, err
+	}
+
+	messageRows, err := r.db.Query(ctx, `
+        SELECT id, room_id, content, created_at
+        FROM room_messages
+        WHERE user_id = $1
+        ORDER BY created_at
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer messageRows.Close()
+
+	for messageRows.Next() {
+		var message ExportedMessage
+		if err := messageRows.Scan(&message.ID, &message.RoomID, &message.Content, &message.CreatedAt); err != nil {
+			return nil, err
+		}
+		export.Messages = append(export.Messages, message)
+	}
+	if err := messageRows.Err(); err != nil {
+		return nil, err
+	}
+
+	historyRows, err := r.db.Query(ctx, `
+    SELECT id, user_id, imdb_id, media_type, season_number, episode_number, 
+           timestamp_seconds, COALESCE(duration_seconds, 0), percentage_watched, last_watched
+    FROM watch_history
+    WHERE user_id = $1
+    ORDER BY last_watched DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer historyRows.Close()
+
+	for historyRows.Next() {
+		entry, err := scanWatchHistoryEntry(historyRows)
+		if err != nil {
+			return nil, err
+		}
+		export.WatchHistory = append(export.WatchHistory, entry)
+	}
+
+	if err := historyRows.Err(); err != nil {
+		return nil, err
+	}
+
+	sections := []struct {
+		dest  *json.RawMessage
+		query string
+	}{
+		{&export.DirectMessages, `
+            SELECT id, sender_id, recipient_id, content, created_at, read_at
+            FROM direct_messages
+            WHERE sender_id = $1 OR recipient_id = $1
+            ORDER BY id
+        `},
+		{&export.Notifications, `
+            SELECT id, type, data, read_at, created_at
+            FROM notifications
+            WHERE user_id = $1
+            ORDER BY id
+        `},
+		{&export.Watchlists, `
+            SELECT l.id, l.name, l.description, l.is_default, l.shared, l.created_at, l.updated_at,
+                   COALESCE((
+                       SELECT json_agg(json_build_object(
+                           'imdb_id', i.imdb_id, 'media_type', i.media_type,
+                           'season_number', i.season_number, 'episode_number', i.episode_number,
+                           'note', i.note, 'added_at', i.added_at
+                       ) ORDER BY i.position)
+                       FROM watchlist_items i
+                       WHERE i.list_id = l.id
+                   ), '[]'::json) AS items
+            FROM watchlists l
+            WHERE l.user_id = $1
+            ORDER BY l.id
+        `},
+		{&export.Reviews, `
+            SELECT imdb_id, media_type, rating, review, created_at, updated_at
+            FROM title_reviews
+            WHERE user_id = $1
+            ORDER BY created_at
+        `},
+		{&export.Addons, `
+            SELECT transport_url, addon_id, version, name, enabled, position, created_at
+            FROM user_addons
+            WHERE user_id = $1
+            ORDER BY position
+        `},
+		// signing secrets are credentials, not personal data
+		{&export.Webhooks, `
+            SELECT id, room_id, url, events, active, created_at
+            FROM webhooks
+            WHERE user_id = $1
+            ORDER BY id
+        `},
+		{&export.Sessions, `
+            SELECT s.id, s.room_id, s.imdb_id, s.media_type, s.season_number, s.episode_number,
+                   s.started_at, s.ended_at, a.joined_at, a.left_at
+            FROM room_session_attendees a
+            JOIN room_sessions s ON a.session_id = s.id
+            WHERE a.user_id = $1
+            ORDER BY s.id
+        `},
+	}
+
+	for _, section := range sections {
+		if err := r.queryJSON(ctx, section.dest, section.query, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return export, nil
+}
+
+// queryJSON runs query and stores its rows as a JSON array in dest
+func (r *UserRepo) queryJSON(ctx context.Context, dest *json.RawMessage, query string, args ...interface{}) error {
+	var data []byte
+	err := r.db.QueryRow(ctx, `SELECT COALESCE(json_agg(t), '[]'::json) FROM (`+query+`) t`, args...).Scan(&data)
+	if err != nil {
+		return fmt.Errorf("failed to export data: %w", err)
+	}
+	*dest = data
+	return nil
+}
+
+func (r *UserRepo) GetPrivacySettings(ctx context.Context, userID int) (*PrivacySettings, error) {
+	settings := DefaultPrivacySettings()
+	err := r.db.QueryRow(ctx, `
+        SELECT hide_activity, invisible, friend_requests, profile_visibility, updated_at
+        FROM user_privacy_settings
+        WHERE user_id = $1
+    `, userID).Scan(
+		&settings.HideActivity,
+		&settings.Invisible,
+		&settings.FriendRequests,
+		&settings.ProfileVisibility,
+		&settings.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return DefaultPrivacySettings(), nil
+		}
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+func (r *UserRepo) UpdatePrivacySettings(ctx context.Context, userID int, settings *PrivacySettings) error {
+	return r.db.QueryRow(ctx, `
+        INSERT INTO user_privacy_settings (user_id, hide_activity, invisible, friend_requests, profile_visibility, updated_at)
+        VALUES ($1, $2, $3, $4, $5, NOW())
+        ON CONFLICT (user_id) DO UPDATE
+        SET hide_activity = $2, invisible = $3, friend_requests = $4, profile_visibility = $5, updated_at = NOW()
+        RETURNING updated_at
+    `, userID, settings.HideActivity, settings.Invisible, settings.FriendRequests, settings.ProfileVisibility).Scan(&settings.UpdatedAt)
+}
+
+// GetPresencePrivacy - presence related subset of the privacy settings for the presence manager
+func (r *UserRepo) GetPresencePrivacy(ctx context.Context, userID int) (ws.PresencePrivacy, error) {
+	settings, err := r.GetPrivacySettings(ctx, userID)
+	if err != nil {
+		return ws.PresencePrivacy{}, err
+	}
+
+	return ws.PresencePrivacy{
+		HideActivity: settings.HideActivity,
+		Invisible:    settings.Invisible,
+	}, nil
+}
+
+// BlockUser blocks a user and drops any friendship or pending request between the two
+func (r *UserRepo) BlockUser(ctx context.Context, blockerID, blockedID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+        INSERT INTO user_blocks (blocker_id, blocked_id)
+        VALUES ($1, $2)
+        ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+    `, blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+        DELETE FROM friendships
+        WHERE 
+            (user_id = $1 AND friend_id = $2) OR
+            (user_id = $2 AND friend_id = $1)
+    `, blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *UserRepo) UnblockUser(ctx context.Context, blockerID, blockedID int) error {
+	_, err := r.db.Exec(ctx, `
+        DELETE FROM user_blocks
+        WHERE blocker_id = $1 AND blocked_id = $2
+    `, blockerID, blockedID)
+	return err
+}
+
+// IsBlocked reports whether either user has blocked the other
+func (r *UserRepo) IsBlocked(ctx context.Context, userID1, userID2 int) (bool, error) {
+	var blocked bool
+	err := r.db.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1 FROM user_blocks
+            WHERE 
+                (blocker_id = $1 AND blocked_id = $2) OR
+                (blocker_id = $2 AND blocked_id = $1)
+        )
+    `, userID1, userID2).Scan(&blocked)
+	return blocked, err
+}
+
+func (r *UserRepo) GetBlockedUsers(ctx context.Context, userID int) ([]map[string]interface{}, error) {
+	rows, err := r.db.Query(ctx, `
+        SELECT u.id, u.username, u.display_name, u.profile_picture_url, b.created_at
+        FROM user_blocks b
+        JOIN users u ON u.id = b.blocked_id
+        WHERE b.blocker_id = $1
+        ORDER BY b.created_at DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocked := []map[string]interface{}{}
+
+	for rows.Next() {
+		var id int
+		var username string
+		var displayName, profilePic pgtype.Text
+		var blockedAt time.Time
+
+		if err := rows.Scan(&id, &username, &displayName, &profilePic, &blockedAt); err != nil {
+			return nil, err
+		}
+
+		user := map[string]interface{}{
+			"id":         id,
+			"username":   username,
+			"blocked_at": blockedAt,
+		}
+
+		if displayName.Valid {
+			user["display_name"] = displayName.String
+		}
+
+		if profilePic.Valid {
+			user["profile_picture_url"] = profilePic.String
+		}
+
+		blocked = append(blocked, user)
+	}
+
+	return blocked, rows.Err()
+}
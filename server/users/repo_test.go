@@ -0,0 +1,57 @@
+package users
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeRow follows pgx's rules for NULL: a pointer destination is set to nil,
+// anything else fails the scan
+type fakeRow []interface{}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	if len(dest) != len(r) {
+		return fmt.Errorf("%d destinations for %d columns", len(dest), len(r))
+	}
+	for i, value := range r {
+		target := reflect.ValueOf(dest[i]).Elem()
+		if value == nil {
+			if target.Kind() != reflect.Pointer {
+				return fmt.Errorf("can't scan into dest[%d]: cannot scan NULL into %s", i, reflect.TypeOf(dest[i]))
+			}
+			target.Set(reflect.Zero(target.Type()))
+			continue
+		}
+		v := reflect.ValueOf(value)
+		if target.Kind() == reflect.Pointer {
+			ptr := reflect.New(target.Type().Elem())
+			ptr.Elem().Set(v)
+			v = ptr
+		}
+		target.Set(v)
+	}
+	return nil
+}
+
+func TestScanWatchHistoryEntryNullDuration(t *testing.T) {
+	watched := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	row := func(duration interface{}) fakeRow {
+		return fakeRow{1, 7, "tt0903747", "series", 1, 2, 600, duration, 0.0, watched}
+	}
+
+	// room sessions record progress before the player reports a duration
+	entry, err := scanWatchHistoryEntry(row(nil))
+	if err != nil {
+		t.Fatalf("scanWatchHistoryEntry() with a NULL duration error = %v", err)
+	}
+	if entry.DurationSeconds != 0 || entry.TimestampSeconds != 600 || *entry.EpisodeNumber != 2 || !entry.LastWatched.Equal(watched) {
+		t.Errorf("entry = %+v", entry)
+	}
+
+	entry, err = scanWatchHistoryEntry(row(2700))
+	if err != nil || entry.DurationSeconds != 2700 {
+		t.Errorf("scanWatchHistoryEntry() = %+v, %v", entry, err)
+	}
+}
@@ -0,0 +1,43 @@
+package users
+
+import (
+	"context"
+	"fmt"
+
+	"zync-stream/sessions"
+)
+
+// RecordSessionProgress writes the position a room session reached into the
+// attendee's watch history. A duration of zero keeps the one already stored.
+func (r *UserRepo) RecordSessionProgress(ctx context.Context, userID int, progress sessions.Progress) error {
+	season, episode := progress.SeasonNumber, progress.EpisodeNumber
+	if progress.MediaType == "movie" || season == nil || episode == nil {
+		zero := 0
+		season, episode = &zero, &zero
+	}
+
+	_, err := r.db.Exec(ctx, `
+        INSERT INTO watch_history
+        (user_id, imdb_id, media_type, season_number, episode_number,
+        timestamp_seconds, duration_seconds, percentage_watched, last_watched)
+        VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0),
+                CASE WHEN $7 > 0 THEN LEAST(100, $6 * 100.0 / $7) ELSE 0 END, NOW())
+        ON CONFLICT (user_id, imdb_id, season_number, episode_number)
+        DO UPDATE SET
+            timestamp_seconds = EXCLUDED.timestamp_seconds,
+            duration_seconds = COALESCE(EXCLUDED.duration_seconds, watch_history.duration_seconds),
+            percentage_watched = CASE
+                WHEN COALESCE(EXCLUDED.duration_seconds, watch_history.duration_seconds) > 0
+                THEN LEAST(100, EXCLUDED.timestamp_seconds * 100.0 /
+                    COALESCE(EXCLUDED.duration_seconds, watch_history.duration_seconds))
+                ELSE watch_history.percentage_watched
+            END,
+            last_watched = NOW()
+    `, userID, progress.ImdbID, progress.MediaType, season, episode,
+		progress.PositionSeconds, progress.DurationSeconds)
+	if err != nil {
+		return fmt.Errorf("failed to record session progress: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,366 @@
+package watchlist
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WatchlistHandlers struct {
+	repo *WatchlistRepository
+}
+
+func NewWatchlistHandlers(repo *WatchlistRepository) *WatchlistHandlers {
+	return &WatchlistHandlers{repo: repo}
+}
+
+func (h *WatchlistHandlers) GetLists(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.repo.EnsureDefault(ctx, userID.(int)); err != nil {
+		log.Printf("Error creating default watchlist for user %d: %v", userID.(int), err)
+	}
+
+	lists, err := h.repo.ListByUser(ctx, userID.(int), false)
+	if err != nil {
+		log.Printf("Error listing watchlists: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve watchlists"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"watchlists": lists})
+}
+
+// GetFriendLists returns the lists a friend has shared
+func (h *WatchlistHandlers) GetFriendLists(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	friendID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	friends, err := h.repo.AreFriends(ctx, userID.(int), friendID)
+	if err != nil {
+		log.Printf("Error checking friendship: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve watchlists"})
+		return
+	}
+	if !friends {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only view your friends' lists"})
+		return
+	}
+
+	lists, err := h.repo.ListByUser(ctx, friendID, true)
+	if err != nil {
+		log.Printf("Error listing shared watchlists: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve watchlists"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"watchlists": lists})
+}
+
+func (h *WatchlistHandlers) CreateList(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req CreateListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > MaxNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name must be between 1 and 100 characters"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	count, err := h.repo.CountByUser(ctx, userID.(int))
+	if err != nil {
+		log.Printf("Error counting watchlists: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create watchlist"})
+		return
+	}
+	if count >= MaxListsPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Watchlist limit reached"})
+		return
+	}
+
+	list := &List{
+		UserID:      userID.(int),
+		Name:        name,
+		Description: req.Description,
+		Shared:      req.Shared,
+	}
+
+	if err := h.repo.Create(ctx, list); err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			c.JSON(http.StatusConflict, gin.H{"error": "You already have a list with this name"})
+			return
+		}
+		log.Printf("Error creating watchlist: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create watchlist"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"watchlist": list})
+}
+
+func (h *WatchlistHandlers) GetList(c *gin.Context) {
+	list, ok := h.viewableList(c)
+	if !ok {
+		return
+	}
+
+	items, err := h.repo.GetItems(c.Request.Context(), list.ID)
+	if err != nil {
+		log.Printf("Error loading watchlist %d items: %v", list.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve watchlist"})
+		return
+	}
+	list.Items = items
+
+	c.JSON(http.StatusOK, gin.H{"watchlist": list})
+}
+
+func (h *WatchlistHandlers) UpdateList(c *gin.Context) {
+	list, ok := h.ownedList(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len(name) > MaxNameLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name must be between 1 and 100 characters"})
+			return
+		}
+		if list.IsDefault && name != list.Name {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The default watchlist cannot be renamed"})
+			return
+		}
+		list.Name = name
+	}
+	if req.Description != nil {
+		list.Description = req.Description
+	}
+	if req.Shared != nil {
+		list.Shared = *req.Shared
+	}
+
+	if err := h.repo.Update(c.Request.Context(), list); err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			c.JSON(http.StatusConflict, gin.H{"error": "You already have a list with this name"})
+			return
+		}
+		log.Printf("Error updating watchlist %d: %v", list.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update watchlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"watchlist": list})
+}
+
+func (h *WatchlistHandlers) DeleteList(c *gin.Context) {
+	list, ok := h.ownedList(c)
+	if !ok {
+		return
+	}
+
+	if list.IsDefault {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The default watchlist cannot be deleted"})
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), list.ID); err != nil {
+		log.Printf("Error deleting watchlist %d: %v", list.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete watchlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Watchlist deleted"})
+}
+
+func (h *WatchlistHandlers) AddItem(c *gin.Context) {
+	list, ok := h.ownedList(c)
+	if !ok {
+		return
+	}
+
+	var req AddItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	if req.Note != nil && len(*req.Note) > MaxNoteLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Note is too long"})
+		return
+	}
+
+	item := &Item{
+		ListID:        list.ID,
+		UserID:        list.UserID,
+		ImdbID:        req.ImdbID,
+		MediaType:     req.MediaType,
+		SeasonNumber:  req.SeasonNumber,
+		EpisodeNumber: req.EpisodeNumber,
+		Note:          req.Note,
+	}
+
+	if err := h.repo.AddItem(c.Request.Context(), item); err != nil {
+		if errors.Is(err, ErrListFull) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This list is full"})
+			return
+		}
+		log.Printf("Error adding to watchlist %d: %v", list.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add title"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"item": item})
+}
+
+func (h *WatchlistHandlers) RemoveItem(c *gin.Context) {
+	list, ok := h.ownedList(c)
+	if !ok {
+		return
+	}
+
+	itemID, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	removed, err := h.repo.RemoveItem(c.Request.Context(), list.ID, itemID)
+	if err != nil {
+		log.Printf("Error removing item %d from watchlist %d: %v", itemID, list.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove title"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Title removed"})
+}
+
+func (h *WatchlistHandlers) ReorderItems(c *gin.Context) {
+	list, ok := h.ownedList(c)
+	if !ok {
+		return
+	}
+
+	var req ReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	if err := h.repo.Reorder(c.Request.Context(), list.ID, req.ItemIDs); err != nil {
+		if errors.Is(err, ErrInvalidOrder) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error reordering watchlist %d: %v", list.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder watchlist"})
+		return
+	}
+
+	items, err := h.repo.GetItems(c.Request.Context(), list.ID)
+	if err != nil {
+		log.Printf("Error loading watchlist %d items: %v", list.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve watchlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// loadList resolves :id and writes an error response when it does not exist
+func (h *WatchlistHandlers) loadList(c *gin.Context) (*List, int, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, 0, false
+	}
+
+	listID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid list ID"})
+		return nil, 0, false
+	}
+
+	list, err := h.repo.GetByID(c.Request.Context(), listID)
+	if err != nil {
+		log.Printf("Error loading watchlist %d: %v", listID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve watchlist"})
+		return nil, 0, false
+	}
+	if list == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Watchlist not found"})
+		return nil, 0, false
+	}
+
+	return list, userID.(int), true
+}
+
+func (h *WatchlistHandlers) ownedList(c *gin.Context) (*List, bool) {
+	list, userID, ok := h.loadList(c)
+	if !ok {
+		return nil, false
+	}
+	if list.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Watchlist not found"})
+		return nil, false
+	}
+	return list, true
+}
+
+func (h *WatchlistHandlers) viewableList(c *gin.Context) (*List, bool) {
+	list, userID, ok := h.loadList(c)
+	if !ok {
+		return nil, false
+	}
+
+	canView, err := h.repo.CanView(c.Request.Context(), userID, list)
+	if err != nil {
+		log.Printf("Error checking watchlist %d access: %v", list.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve watchlist"})
+		return nil, false
+	}
+	if !canView {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Watchlist not found"})
+		return nil, false
+	}
+	return list, true
+}
@@ -0,0 +1,65 @@
+package watchlist
+
+import "time"
+
+const (
+	DefaultListName = "Watchlist"
+
+	MaxListsPerUser = 50
+	MaxItemsPerList = 1000
+	MaxNameLength   = 100
+	MaxNoteLength   = 500
+)
+
+type List struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description,omitempty"`
+	IsDefault   bool      `json:"is_default"`
+	Shared      bool      `json:"shared"`
+	ItemCount   int       `json:"item_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Items       []*Item   `json:"items,omitempty"`
+}
+
+// Item uses the same title fields as users.WatchHistoryEntry so clients can
+// merge a list with the user's history
+type Item struct {
+	ID            int       `json:"id"`
+	ListID        int       `json:"list_id"`
+	UserID        int       `json:"user_id"`
+	ImdbID        string    `json:"imdb_id"`
+	MediaType     string    `json:"media_type"` // "movie" or "series"
+	SeasonNumber  *int      `json:"season_number,omitempty"`
+	EpisodeNumber *int      `json:"episode_number,omitempty"`
+	Position      int       `json:"position"`
+	Note          *string   `json:"note,omitempty"`
+	AddedAt       time.Time `json:"added_at"`
+}
+
+type CreateListRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description *string `json:"description"`
+	Shared      bool    `json:"shared"`
+}
+
+type UpdateListRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Shared      *bool   `json:"shared"`
+}
+
+type AddItemRequest struct {
+	ImdbID        string  `json:"imdb_id" binding:"required"`
+	MediaType     string  `json:"media_type" binding:"required,oneof=movie series"`
+	SeasonNumber  *int    `json:"season_number,omitempty"`
+	EpisodeNumber *int    `json:"episode_number,omitempty"`
+	Note          *string `json:"note,omitempty"`
+}
+
+// ReorderRequest lists item ids in their new order
+type ReorderRequest struct {
+	ItemIDs []int `json:"item_ids" binding:"required"`
+}
@@ -0,0 +1,307 @@
+package watchlist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrInvalidOrder = errors.New("item ids must list every item of the list exactly once")
+	ErrListFull     = errors.New("list is full")
+)
+
+// WatchlistRepository handles saved-title lists
+type WatchlistRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewWatchlistRepository creates a new WatchlistRepository
+func NewWatchlistRepository(db *pgxpool.Pool) *WatchlistRepository {
+	return &WatchlistRepository{db: db}
+}
+
+const listColumns = `
+        l.id, l.user_id, l.name, l.description, l.is_default, l.shared,
+        (SELECT COUNT(*) FROM watchlist_items i WHERE i.list_id = l.id),
+        l.created_at, l.updated_at
+    `
+
+func scanList(row pgx.Row) (*List, error) {
+	var list List
+	err := row.Scan(&list.ID, &list.UserID, &list.Name, &list.Description, &list.IsDefault,
+		&list.Shared, &list.ItemCount, &list.CreatedAt, &list.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// EnsureDefault creates the user's default "Watchlist" on first use
+func (r *WatchlistRepository) EnsureDefault(ctx context.Context, userID int) error {
+	_, err := r.db.Exec(ctx, `
+        INSERT INTO watchlists (user_id, name, is_default)
+        VALUES ($1, $2, true)
+        ON CONFLICT DO NOTHING
+    `, userID, DefaultListName)
+	if err != nil {
+		return fmt.Errorf("failed to create default watchlist: %w", err)
+	}
+	return nil
+}
+
+// ListByUser returns a user's lists, default first. sharedOnly hides private lists.
+func (r *WatchlistRepository) ListByUser(ctx context.Context, userID int, sharedOnly bool) ([]*List, error) {
+	query := `SELECT ` + listColumns + `
+        FROM watchlists l
+        WHERE l.user_id = $1 AND (l.shared OR NOT $2)
+        ORDER BY l.is_default DESC, l.created_at
+    `
+
+	rows, err := r.db.Query(ctx, query, userID, sharedOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlists: %w", err)
+	}
+	defer rows.Close()
+
+	lists := []*List{}
+	for rows.Next() {
+		list, err := scanList(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan watchlist: %w", err)
+		}
+		lists = append(lists, list)
+	}
+
+	return lists, rows.Err()
+}
+
+func (r *WatchlistRepository) GetByID(ctx context.Context, id int) (*List, error) {
+	query := `SELECT ` + listColumns + ` FROM watchlists l WHERE l.id = $1`
+
+	list, err := scanList(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get watchlist: %w", err)
+	}
+
+	return list, nil
+}
+
+func (r *WatchlistRepository) CountByUser(ctx context.Context, userID int) (int, error) {
+	var count int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM watchlists WHERE user_id = $1`, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count watchlists: %w", err)
+	}
+	return count, nil
+}
+
+func (r *WatchlistRepository) Create(ctx context.Context, list *List) error {
+	query := `
+        INSERT INTO watchlists (user_id, name, description, shared)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at, updated_at
+    `
+
+	err := r.db.QueryRow(ctx, query, list.UserID, list.Name, list.Description, list.Shared).
+		Scan(&list.ID, &list.CreatedAt, &list.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create watchlist: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WatchlistRepository) Update(ctx context.Context, list *List) error {
+	query := `
+        UPDATE watchlists
+        SET name = $2, description = $3, shared = $4, updated_at = NOW()
+        WHERE id = $1
+        RETURNING updated_at
+    `
+
+	if err := r.db.QueryRow(ctx, query, list.ID, list.Name, list.Description, list.Shared).Scan(&list.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update watchlist: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WatchlistRepository) Delete(ctx context.Context, id int) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM watchlists WHERE id = $1 AND NOT is_default`, id); err != nil {
+		return fmt.Errorf("failed to delete watchlist: %w", err)
+	}
+	return nil
+}
+
+// CanView reports whether viewer may see a list: owners always, accepted
+// friends only for shared lists and only without a block in either direction
+func (r *WatchlistRepository) CanView(ctx context.Context, viewerID int, list *List) (bool, error) {
+	if list.UserID == viewerID {
+		return true, nil
+	}
+	if !list.Shared {
+		return false, nil
+	}
+
+	return r.AreFriends(ctx, viewerID, list.UserID)
+}
+
+func (r *WatchlistRepository) AreFriends(ctx context.Context, userID, otherID int) (bool, error) {
+	query := `
+        SELECT EXISTS (
+            SELECT 1 FROM friendships
+            WHERE
+                ((user_id = $1 AND friend_id = $2) OR
+                (user_id = $2 AND friend_id = $1)) AND
+                status = 'accepted'
+        ) AND NOT EXISTS (
+            SELECT 1 FROM user_blocks
+            WHERE
+                (blocker_id = $1 AND blocked_id = $2) OR
+                (blocker_id = $2 AND blocked_id = $1)
+        )
+    `
+
+	var friends bool
+	if err := r.db.QueryRow(ctx, query, userID, otherID).Scan(&friends); err != nil {
+		return false, fmt.Errorf("failed to check friendship: %w", err)
+	}
+
+	return friends, nil
+}
+
+func (r *WatchlistRepository) GetItems(ctx context.Context, listID int) ([]*Item, error) {
+	query := `
+        SELECT id, list_id, user_id, imdb_id, media_type, season_number, episode_number,
+               position, note, added_at
+        FROM watchlist_items
+        WHERE list_id = $1
+        ORDER BY position, id
+    `
+
+	rows, err := r.db.Query(ctx, query, listID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlist items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*Item{}
+	for rows.Next() {
+		item := &Item{}
+		if err := rows.Scan(&item.ID, &item.ListID, &item.UserID, &item.ImdbID, &item.MediaType,
+			&item.SeasonNumber, &item.EpisodeNumber, &item.Position, &item.Note, &item.AddedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan watchlist item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// AddItem appends a title to the end of the list. Adding a title that is
+// already saved updates it in place and keeps its position.
+func (r *WatchlistRepository) AddItem(ctx context.Context, item *Item) error {
+	var count int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM watchlist_items WHERE list_id = $1`, item.ListID).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count watchlist items: %w", err)
+	}
+	if count >= MaxItemsPerList {
+		return ErrListFull
+	}
+
+	query := `
+        INSERT INTO watchlist_items
+        (list_id, user_id, imdb_id, media_type, season_number, episode_number, note, position)
+        VALUES ($1, $2, $3, $4, $5, $6, $7,
+            (SELECT COALESCE(MAX(position), -1) + 1 FROM watchlist_items WHERE list_id = $1))
+        ON CONFLICT (list_id, imdb_id, media_type) DO UPDATE SET
+            season_number = EXCLUDED.season_number,
+            episode_number = EXCLUDED.episode_number,
+            note = EXCLUDED.note
+        RETURNING id, position, added_at
+    `
+
+	err := r.db.QueryRow(ctx, query, item.ListID, item.UserID, item.ImdbID, item.MediaType,
+		item.SeasonNumber, item.EpisodeNumber, item.Note).Scan(&item.ID, &item.Position, &item.AddedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add watchlist item: %w", err)
+	}
+
+	r.touch(ctx, item.ListID)
+	return nil
+}
+
+func (r *WatchlistRepository) RemoveItem(ctx context.Context, listID, itemID int) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM watchlist_items WHERE list_id = $1 AND id = $2`, listID, itemID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove watchlist item: %w", err)
+	}
+
+	r.touch(ctx, listID)
+	return tag.RowsAffected() > 0, nil
+}
+
+// Reorder assigns positions following itemIDs, which must contain every item once
+func (r *WatchlistRepository) Reorder(ctx context.Context, listID int, itemIDs []int) error {
+	seen := make(map[int]bool, len(itemIDs))
+	for _, id := range itemIDs {
+		if seen[id] {
+			return ErrInvalidOrder
+		}
+		seen[id] = true
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT id FROM watchlist_items WHERE list_id = $1 FOR UPDATE`, listID)
+	if err != nil {
+		return fmt.Errorf("failed to lock watchlist items: %w", err)
+	}
+	existing := 0
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan watchlist item: %w", err)
+		}
+		if !seen[id] {
+			rows.Close()
+			return ErrInvalidOrder
+		}
+		existing++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read watchlist items: %w", err)
+	}
+	if existing != len(itemIDs) {
+		return ErrInvalidOrder
+	}
+
+	for position, id := range itemIDs {
+		if _, err := tx.Exec(ctx, `UPDATE watchlist_items SET position = $3 WHERE list_id = $1 AND id = $2`,
+			listID, id, position); err != nil {
+			return fmt.Errorf("failed to reorder watchlist: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE watchlists SET updated_at = NOW() WHERE id = $1`, listID); err != nil {
+		return fmt.Errorf("failed to reorder watchlist: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *WatchlistRepository) touch(ctx context.Context, listID int) {
+	r.db.Exec(ctx, `UPDATE watchlists SET updated_at = NOW() WHERE id = $1`, listID)
+}
@@ -0,0 +1,168 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"zync-stream/netguard"
+)
+
+const (
+	SignatureHeader = "X-Zync-Signature"
+	EventHeader     = "X-Zync-Event"
+	DeliveryHeader  = "X-Zync-Delivery"
+	TimestampHeader = "X-Zync-Timestamp"
+
+	baseRetryDelay = 30 * time.Second
+	maxRetryDelay  = time.Hour
+	claimBatchSize = 20
+	// a batch is sent in parallel, so it finishes within deliveryTimeout,
+	// well before its lease lets another tick claim it again
+	claimLease      = 2 * time.Minute
+	deliveryTimeout = 10 * time.Second
+)
+
+// Sign returns the signature header value for a payload sent at timestamp.
+// Receivers recompute HMAC-SHA256 over "<timestamp>.<body>" with their secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// RetryDelay is the backoff before attempt number attempts+1
+func RetryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
+
+// Dispatcher drains the delivery queue and posts signed payloads
+type Dispatcher struct {
+	repo   *WebhookRepository
+	client *http.Client
+}
+
+func NewDispatcher(repo *WebhookRepository) *Dispatcher {
+	// targets are checked when a webhook is created, but the host may
+	// resolve elsewhere by delivery time, so every dial is checked again.
+	// Redirects are reported as failed deliveries rather than followed.
+	client := netguard.NewClient(deliveryTimeout)
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Dispatcher{
+		repo:   repo,
+		client: client,
+	}
+}
+
+// Start polls for due deliveries every interval until ctx is cancelled
+func (d *Dispatcher) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				d.RunOnce(ctx)
+			}
+		}
+	}()
+}
+
+// RunOnce claims one batch of due deliveries and attempts them in parallel
+func (d *Dispatcher) RunOnce(ctx context.Context) {
+	deliveries, err := d.repo.ClaimDue(ctx, claimBatchSize, claimLease)
+	if err != nil {
+		log.Printf("Failed to claim webhook deliveries: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery pendingDelivery) {
+			defer wg.Done()
+			d.attempt(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery pendingDelivery) {
+	statusCode, err := d.send(ctx, delivery)
+	if err == nil {
+		if markErr := d.repo.MarkDelivered(ctx, delivery.ID, statusCode); markErr != nil {
+			log.Printf("Failed to mark webhook delivery %d delivered: %v", delivery.ID, markErr)
+		}
+		return
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+
+	attempts := delivery.Attempts + 1
+	var retryAt *time.Time
+	if attempts < MaxAttempts {
+		next := time.Now().Add(RetryDelay(attempts))
+		retryAt = &next
+	} else {
+		log.Printf("Webhook delivery %d failed permanently after %d attempts: %v", delivery.ID, attempts, err)
+	}
+
+	if markErr := d.repo.MarkAttemptFailed(ctx, delivery.ID, code, err.Error(), retryAt); markErr != nil {
+		log.Printf("Failed to record webhook delivery %d failure: %v", delivery.ID, markErr)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery pendingDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("invalid request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Zync-Webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,148 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := RetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("RetryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// receiver records what a webhook endpoint was sent
+type receiver struct {
+	event, delivery, signature string
+	timestamp                  int64
+	body                       []byte
+}
+
+func TestSendSignsPayload(t *testing.T) {
+	var got receiver
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.event = r.Header.Get(EventHeader)
+		got.delivery = r.Header.Get(DeliveryHeader)
+		got.signature = r.Header.Get(SignatureHeader)
+		got.timestamp, _ = strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		got.body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	delivery := pendingDelivery{
+		ID:      42,
+		Event:   "chat_message",
+		Payload: []byte(`{"room_id":1,"content":"hi"}`),
+		URL:     server.URL,
+		Secret:  "whsec_test",
+	}
+
+	dispatcher := NewDispatcher(nil)
+	dispatcher.client = server.Client()
+
+	statusCode, err := dispatcher.send(context.Background(), delivery)
+	if err != nil || statusCode != http.StatusNoContent {
+		t.Fatalf("send() = %d, %v", statusCode, err)
+	}
+
+	if got.event != "chat_message" || got.delivery != "42" {
+		t.Errorf("headers: event %q, delivery %q", got.event, got.delivery)
+	}
+	if string(got.body) != string(delivery.Payload) {
+		t.Errorf("body = %s", got.body)
+	}
+	if want := Sign(delivery.Secret, got.timestamp, got.body); got.signature != want {
+		t.Errorf("signature = %q, want %q", got.signature, want)
+	}
+	if Sign("other secret", got.timestamp, got.body) == got.signature {
+		t.Error("signature does not depend on the secret")
+	}
+	if !strings.HasPrefix(got.signature, "sha256=") {
+		t.Errorf("signature %q lacks the sha256= prefix", got.signature)
+	}
+}
+
+func TestSendFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/error":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/redirect":
+			w.WriteHeader(http.StatusMultipleChoices)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	dispatcher := NewDispatcher(nil)
+	dispatcher.client = &http.Client{Timeout: 50 * time.Millisecond}
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{"server error", server.URL + "/error", http.StatusBadGateway},
+		{"non-2xx", server.URL + "/redirect", http.StatusMultipleChoices},
+		{"timeout", server.URL + "/slow", 0},
+		{"unreachable", closed.URL, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statusCode, err := dispatcher.send(context.Background(), pendingDelivery{
+				ID: 1, Event: "ping", Payload: []byte(`{}`), URL: tt.url, Secret: "s",
+			})
+			if err == nil {
+				t.Fatal("send() succeeded, want an error")
+			}
+			if statusCode != tt.wantStatus {
+				t.Errorf("send() status = %d, want %d", statusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSendRefusesInternalTargets(t *testing.T) {
+	hit := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer server.Close()
+
+	// the default client re-checks the address on every dial
+	statusCode, err := NewDispatcher(nil).send(context.Background(), pendingDelivery{
+		ID: 1, Event: "ping", Payload: []byte(`{}`), URL: server.URL, Secret: "s",
+	})
+	if err == nil || statusCode != 0 {
+		t.Errorf("send() to a loopback target = %d, %v", statusCode, err)
+	}
+	if hit {
+		t.Error("delivery reached a loopback server")
+	}
+}
@@ -0,0 +1,256 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"zync-stream/netguard"
+	"zync-stream/rooms"
+)
+
+const (
+	MaxWebhooksPerUser     = 20
+	DefaultDeliveriesLimit = 50
+	MaxDeliveriesLimit     = 200
+)
+
+type WebhookHandlers struct {
+	repo     *WebhookRepository
+	roomRepo *rooms.RoomRepository
+}
+
+func NewWebhookHandlers(repo *WebhookRepository, roomRepo *rooms.RoomRepository) *WebhookHandlers {
+	return &WebhookHandlers{
+		repo:     repo,
+		roomRepo: roomRepo,
+	}
+}
+
+type createWebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required"`
+	RoomID *int     `json:"room_id"`
+}
+
+func (h *WebhookHandlers) CreateWebhook(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req createWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	if len(req.Events) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one event is required"})
+		return
+	}
+
+	allowed := isUserEvent
+	if req.RoomID != nil {
+		allowed = isRoomEvent
+	}
+	for _, event := range req.Events {
+		if !allowed(event) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported event: " + event})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+
+	if err := netguard.CheckURL(ctx, req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": targetURLError(err)})
+		return
+	}
+
+	if req.RoomID != nil {
+		isMember, role, err := h.roomRepo.IsRoomMember(ctx, *req.RoomID, userID.(int))
+		if err != nil {
+			log.Printf("Error checking room membership: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify room access"})
+			return
+		}
+		if !isMember || (role != rooms.RoleOwner && role != rooms.RoleAdmin) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only room owners and admins can add webhooks"})
+			return
+		}
+	}
+
+	existing, err := h.repo.ListByUser(ctx, userID.(int))
+	if err != nil {
+		log.Printf("Error listing webhooks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+	if len(existing) >= MaxWebhooksPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Webhook limit reached"})
+		return
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	webhook := &Webhook{
+		UserID: userID.(int),
+		RoomID: req.RoomID,
+		URL:    req.URL,
+		Secret: secret,
+		Events: req.Events,
+	}
+
+	if err := h.repo.Create(ctx, webhook); err != nil {
+		log.Printf("Error creating webhook: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	// the secret is only ever shown once
+	c.JSON(http.StatusCreated, gin.H{"webhook": webhook})
+}
+
+func (h *WebhookHandlers) GetWebhooks(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	webhooks, err := h.repo.ListByUser(c.Request.Context(), userID.(int))
+	if err != nil {
+		log.Printf("Error listing webhooks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks":    webhooks,
+		"room_events": RoomEvents,
+		"user_events": UserEvents,
+	})
+}
+
+func (h *WebhookHandlers) DeleteWebhook(c *gin.Context) {
+	webhook, ok := h.ownedWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), webhook.ID); err != nil {
+		log.Printf("Error deleting webhook %d: %v", webhook.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+func (h *WebhookHandlers) GetDeliveries(c *gin.Context) {
+	webhook, ok := h.ownedWebhook(c)
+	if !ok {
+		return
+	}
+
+	limit := DefaultDeliveriesLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = min(limit, MaxDeliveriesLimit)
+	}
+
+	deliveries, err := h.repo.ListDeliveries(c.Request.Context(), webhook.ID, limit)
+	if err != nil {
+		log.Printf("Error listing deliveries for webhook %d: %v", webhook.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// PingWebhook queues a test delivery so receivers can verify their signature check
+func (h *WebhookHandlers) PingWebhook(c *gin.Context) {
+	webhook, ok := h.ownedWebhook(c)
+	if !ok {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"type":       "ping",
+		"webhook_id": webhook.ID,
+		"timestamp":  time.Now().Unix(),
+	}
+
+	if _, err := h.repo.EnqueuePing(c.Request.Context(), webhook.ID, payload); err != nil {
+		log.Printf("Error queueing ping for webhook %d: %v", webhook.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue ping"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Ping queued"})
+}
+
+// ownedWebhook loads the :id webhook and writes an error response unless it belongs to the caller
+func (h *WebhookHandlers) ownedWebhook(c *gin.Context) (*Webhook, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return nil, false
+	}
+
+	webhook, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Error loading webhook %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhook"})
+		return nil, false
+	}
+	if webhook == nil || webhook.UserID != userID.(int) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return nil, false
+	}
+
+	return webhook, true
+}
+
+func targetURLError(err error) string {
+	switch {
+	case errors.Is(err, netguard.ErrInvalidURL):
+		return "URL must be an absolute http or https URL"
+	case errors.Is(err, netguard.ErrBlockedAddress):
+		return "URL must point to a public address"
+	default:
+		return "Could not resolve the URL's host"
+	}
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,72 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+
+	MaxAttempts = 8
+)
+
+// RoomEvents can be subscribed to on a room webhook, UserEvents on a personal one
+var (
+	RoomEvents = []string{"playback_update", "stream_selected", "subtitles_changed", "hls_started", "user_joined", "user_left", "chat_message"}
+	UserEvents = []string{"friend_request_received", "friend_request_accepted", "room_invitation"}
+)
+
+type Webhook struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	RoomID    *int      `json:"room_id,omitempty"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Delivery struct {
+	ID             int             `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// pendingDelivery is a claimed delivery joined with its webhook target
+type pendingDelivery struct {
+	ID       int
+	Event    string
+	Payload  []byte
+	Attempts int
+	URL      string
+	Secret   string
+}
+
+func isRoomEvent(event string) bool {
+	return contains(RoomEvents, event)
+}
+
+func isUserEvent(event string) bool {
+	return contains(UserEvents, event)
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
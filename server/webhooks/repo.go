@@ -0,0 +1,262 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WebhookRepository handles webhook subscriptions and the delivery queue
+type WebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewWebhookRepository creates a new WebhookRepository
+func NewWebhookRepository(db *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, webhook *Webhook) error {
+	query := `
+        INSERT INTO webhooks (user_id, room_id, url, secret, events)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, active, created_at
+    `
+
+	err := r.db.QueryRow(ctx, query, webhook.UserID, webhook.RoomID, webhook.URL, webhook.Secret, webhook.Events).
+		Scan(&webhook.ID, &webhook.Active, &webhook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return nil
+}
+
+// ListByUser returns the user's webhooks without their secrets
+func (r *WebhookRepository) ListByUser(ctx context.Context, userID int) ([]Webhook, error) {
+	query := `
+        SELECT id, user_id, room_id, url, events, active, created_at
+        FROM webhooks
+        WHERE user_id = $1
+        ORDER BY created_at DESC
+    `
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		var webhook Webhook
+		if err := rows.Scan(&webhook.ID, &webhook.UserID, &webhook.RoomID, &webhook.URL,
+			&webhook.Events, &webhook.Active, &webhook.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id int) (*Webhook, error) {
+	query := `
+        SELECT id, user_id, room_id, url, events, active, created_at
+        FROM webhooks
+        WHERE id = $1
+    `
+
+	var webhook Webhook
+	err := r.db.QueryRow(ctx, query, id).Scan(&webhook.ID, &webhook.UserID, &webhook.RoomID,
+		&webhook.URL, &webhook.Events, &webhook.Active, &webhook.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id int) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM webhooks WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID, limit int) ([]Delivery, error) {
+	query := `
+        SELECT id, webhook_id, event, payload, status, attempts, next_attempt_at,
+               last_status_code, last_error, created_at, delivered_at
+        FROM webhook_deliveries
+        WHERE webhook_id = $1
+        ORDER BY id DESC
+        LIMIT $2
+    `
+
+	rows, err := r.db.Query(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var delivery Delivery
+		var payload []byte
+		if err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &payload,
+			&delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt,
+			&delivery.LastStatusCode, &delivery.LastError, &delivery.CreatedAt, &delivery.DeliveredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		delivery.Payload = payload
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// EnqueueRoomEvent queues a delivery for every active webhook of the room subscribed to event.
+// Hooks whose creator has left the room or is no longer an owner or admin are skipped.
+func (r *WebhookRepository) EnqueueRoomEvent(ctx context.Context, roomID int, event string, payload interface{}) (int64, error) {
+	return r.enqueue(ctx, `room_id = $1 AND $2 = ANY(events) AND EXISTS (
+            SELECT 1 FROM room_members m
+            WHERE m.room_id = webhooks.room_id AND m.user_id = webhooks.user_id
+              AND m.role IN ('owner', 'admin')
+        )`, roomID, event, payload)
+}
+
+// EnqueueUserEvent queues a delivery for every personal webhook of the user subscribed to event
+func (r *WebhookRepository) EnqueueUserEvent(ctx context.Context, userID int, event string, payload interface{}) (int64, error) {
+	return r.enqueue(ctx, `user_id = $1 AND room_id IS NULL AND $2 = ANY(events)`, userID, event, payload)
+}
+
+// EnqueuePing queues a single "ping" delivery regardless of the subscribed events
+func (r *WebhookRepository) EnqueuePing(ctx context.Context, webhookID int, payload interface{}) (int64, error) {
+	return r.enqueue(ctx, `id = $1`, webhookID, "ping", payload)
+}
+
+func (r *WebhookRepository) enqueue(ctx context.Context, where string, scopeID int, event string, payload interface{}) (int64, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	query := `
+        INSERT INTO webhook_deliveries (webhook_id, event, payload)
+        SELECT id, $2::text, $3::jsonb
+        FROM webhooks
+        WHERE active AND ` + where
+
+	tag, err := r.db.Exec(ctx, query, scopeID, event, string(payloadJSON))
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// ClaimDue leases up to limit due deliveries. The lease pushes next_attempt_at
+// forward so a crashed worker's deliveries are retried by someone else.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]pendingDelivery, error) {
+	query := `
+        WITH due AS (
+            SELECT id FROM webhook_deliveries
+            WHERE status = 'pending' AND next_attempt_at <= NOW()
+            ORDER BY next_attempt_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        UPDATE webhook_deliveries d
+        SET next_attempt_at = NOW() + make_interval(secs => $2)
+        FROM due, webhooks w
+        WHERE d.id = due.id AND w.id = d.webhook_id
+        RETURNING d.id, d.event, d.payload, d.attempts, w.url, w.secret
+    `
+
+	rows, err := r.db.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []pendingDelivery
+	for rows.Next() {
+		var delivery pendingDelivery
+		if err := rows.Scan(&delivery.ID, &delivery.Event, &delivery.Payload, &delivery.Attempts,
+			&delivery.URL, &delivery.Secret); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, id, statusCode int) error {
+	_, err := r.db.Exec(ctx, `
+        UPDATE webhook_deliveries
+        SET status = 'delivered', attempts = attempts + 1, last_status_code = $2,
+            last_error = NULL, delivered_at = NOW()
+        WHERE id = $1
+    `, id, statusCode)
+	return err
+}
+
+// MarkAttemptFailed records a failed attempt and either schedules a retry or
+// gives up when retryAt is nil
+func (r *WebhookRepository) MarkAttemptFailed(ctx context.Context, id int, statusCode *int, errMsg string, retryAt *time.Time) error {
+	status := DeliveryPending
+	nextAttempt := time.Now()
+	if retryAt == nil {
+		status = DeliveryFailed
+	} else {
+		nextAttempt = *retryAt
+	}
+
+	_, err := r.db.Exec(ctx, `
+        UPDATE webhook_deliveries
+        SET status = $2, attempts = attempts + 1, last_status_code = $3,
+            last_error = $4, next_attempt_at = $5
+        WHERE id = $1
+    `, id, status, statusCode, errMsg, nextAttempt)
+	return err
+}
+
+// EnqueueRoomEventAsync queues a room event in the background; the websocket
+// layer has no request context and must not block on the database
+func (r *WebhookRepository) EnqueueRoomEventAsync(roomID int, event string, payload interface{}) {
+	if !isRoomEvent(event) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := r.EnqueueRoomEvent(ctx, roomID, event, payload); err != nil {
+			log.Printf("Failed to queue %s webhook for room %d: %v", event, roomID, err)
+		}
+	}()
+}
+
+// EnqueueUserEventAsync is the user-scoped counterpart of EnqueueRoomEventAsync
+func (r *WebhookRepository) EnqueueUserEventAsync(userID int, event string, payload interface{}) {
+	if !isUserEvent(event) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := r.EnqueueUserEvent(ctx, userID, event, payload); err != nil {
+			log.Printf("Failed to queue %s webhook for user %d: %v", event, userID, err)
+		}
+	}()
+}
@@ -0,0 +1,167 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"zync-stream/dm"
+	"zync-stream/push"
+)
+
+var globalDMRepo *dm.DMRepository
+
+func SetDMRepository(repo *dm.DMRepository) {
+	globalDMRepo = repo
+}
+
+func GetDMRepository() *dm.DMRepository {
+	return globalDMRepo
+}
+
+// PublishUserEvent sends a realtime event to every connection of a user
+func PublishUserEvent(userID int, eventType string, data map[string]interface{}) error {
+	redisClient, err := GetRedisClient()
+	if err != nil {
+		return fmt.Errorf("failed to get Redis client: %v", err)
+	}
+
+	event := map[string]interface{}{
+		"type":      eventType,
+		"user_id":   userID,
+		"timestamp": time.Now().Unix(),
+		"data":      data,
+	}
+
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %v", eventType, err)
+	}
+
+	userChannel := fmt.Sprintf("user:%d:notifications", userID)
+	if err := redisClient.Publish(context.Background(), userChannel, eventJSON).Err(); err != nil {
+		return fmt.Errorf("failed to publish %s event: %v", eventType, err)
+	}
+
+	return nil
+}
+
+func (mc *MasterConn) handleDirectMessage(msg MasterMessage) {
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		mc.sendError("Invalid direct message data")
+		return
+	}
+
+	recipientIDFloat, ok := data["recipient_id"].(float64)
+	if !ok {
+		mc.sendError("Invalid recipient ID")
+		return
+	}
+	recipientID := int(recipientIDFloat)
+
+	content, _ := data["content"].(string)
+
+	dmRepo := GetDMRepository()
+	if dmRepo == nil {
+		mc.sendError("Direct messages unavailable")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	message, err := dmRepo.Send(ctx, mc.UserID, recipientID, content)
+	switch {
+	case errors.Is(err, dm.ErrEmptyMessage):
+		mc.sendError("Message cannot be empty")
+		return
+	case errors.Is(err, dm.ErrMessageTooLong):
+		mc.sendError(fmt.Sprintf("Message cannot exceed %d characters", dm.MaxMessageLength))
+		return
+	case errors.Is(err, dm.ErrNotAllowed):
+		mc.sendError("You can only message your friends")
+		return
+	case err != nil:
+		log.Printf("Failed to send direct message %d -> %d: %v", mc.UserID, recipientID, err)
+		mc.sendError("Failed to send message")
+		return
+	}
+
+	payload := map[string]interface{}{
+		"id":              message.ID,
+		"sender_id":       message.SenderID,
+		"sender_username": mc.Username,
+		"recipient_id":    message.RecipientID,
+		"content":         message.Content,
+		"created_at":      message.CreatedAt.Unix(),
+	}
+
+	if clientID, ok := data["client_id"]; ok {
+		payload["client_id"] = clientID
+	}
+
+	// deliver to the recipient and echo to the sender's other sessions
+	for _, userID := range []int{recipientID, mc.UserID} {
+		if err := PublishUserEvent(userID, "direct_message", payload); err != nil {
+			log.Printf("Failed to deliver direct message %d to user %d: %v", message.ID, userID, err)
+		}
+	}
+
+	// the message waits in the recipient's unread counts; a push lets an
+	// offline recipient know it is there
+	if delivery := routeNotification(recipientID, push.TypeDirectMessage); delivery.Live && !delivery.Silent {
+		pushIfOffline(recipientID, push.TypeDirectMessage, map[string]interface{}{
+			"message_id":      message.ID,
+			"sender_id":       message.SenderID,
+			"sender_username": mc.Username,
+			"content":         dm.Preview(message.Content),
+		})
+	}
+}
+
+func (mc *MasterConn) handleDirectMessageRead(msg MasterMessage) {
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		mc.sendError("Invalid read receipt data")
+		return
+	}
+
+	senderIDFloat, ok := data["user_id"].(float64)
+	if !ok {
+		mc.sendError("Invalid user ID")
+		return
+	}
+	senderID := int(senderIDFloat)
+
+	upToID := 0
+	if upToFloat, ok := data["up_to_id"].(float64); ok {
+		upToID = int(upToFloat)
+	}
+
+	dmRepo := GetDMRepository()
+	if dmRepo == nil {
+		mc.sendError("Direct messages unavailable")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	receipt, err := dmRepo.MarkRead(ctx, mc.UserID, senderID, upToID)
+	if err != nil {
+		log.Printf("Failed to mark messages read for user %d: %v", mc.UserID, err)
+		mc.sendError("Failed to mark messages read")
+		return
+	}
+
+	if receipt.ReadCount == 0 {
+		return
+	}
+
+	if err := PublishUserEvent(senderID, "direct_message_read", dm.ReceiptData(receipt)); err != nil {
+		log.Printf("Failed to send read receipt to user %d: %v", senderID, err)
+	}
+}
@@ -67,6 +67,16 @@ func GetRoomRepository() *rooms.RoomRepository {
 	return globalRoomRepo
 }
 
+var globalPlaybackStore *rooms.PlaybackStore
+
+func SetPlaybackStore(store *rooms.PlaybackStore) {
+	globalPlaybackStore = store
+}
+
+func GetPlaybackStore() *rooms.PlaybackStore {
+	return globalPlaybackStore
+}
+
 func HandleMasterWebSocket(c *gin.Context) {
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -123,6 +133,18 @@ func HandleMasterWebSocket(c *gin.Context) {
 
 	userID := int(userIDFloat)
 
+	checkCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	err = middleware.CheckAccountActive(checkCtx, userID, middleware.TokenIssuedAt(claims))
+	cancel()
+	if err != nil {
+		log.Printf("Rejected websocket auth for user %d: %v", userID, err)
+		conn.WriteJSON(map[string]string{
+			"type":    "auth_error",
+			"message": "Account is not active",
+		})
+		return
+	}
+
 	username, ok := claims["username"].(string)
 	if !ok {
 		username = fmt.Sprintf("user_%d", userID)
@@ -155,6 +177,7 @@ func HandleMasterWebSocket(c *gin.Context) {
 
 	go masterConn.writePump()
 	go masterConn.sendConnectionEstablished()
+	go masterConn.sendUnreadCount()
 	go masterConn.subscribeToNotifications()
 
 	defer func() {
@@ -221,6 +244,10 @@ func (mc *MasterConn) handleMessage(msg MasterMessage) {
 		mc.handlePlaybackSync(msg)
 	case "set_status":
 		mc.handleSetStatus(msg)
+	case "direct_message":
+		mc.handleDirectMessage(msg)
+	case "direct_message_read":
+		mc.handleDirectMessageRead(msg)
 	case "ping":
 		mc.Send <- []byte(`{"type":"pong","timestamp":` + fmt.Sprintf("%d", time.Now().Unix()) + `}`)
 	default:
@@ -458,15 +485,33 @@ func (mc *MasterConn) handlePlaybackSync(msg MasterMessage) {
 		return
 	}
 
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		mc.sendError("Invalid playback data")
+		return
+	}
+
 	event := RoomEvent{
 		Type:      "playback_update",
 		UserID:    mc.UserID,
 		Username:  mc.Username,
 		Timestamp: time.Now().Unix(),
-		Data:      msg.Data.(map[string]interface{}),
+		Data:      data,
 	}
 
 	mc.publishRoomEvent(*mc.currentRoom, event)
+
+	if playback := GetPlaybackStore(); playback != nil {
+		position, _ := data["timestamp"].(float64)
+		playing, _ := data["playing"].(bool)
+		if err := playback.UpdatePosition(ctx, *mc.currentRoom, mc.UserID, position, playing); err != nil {
+			log.Printf("Failed to save playback state for room %d: %v", *mc.currentRoom, err)
+		}
+	}
+
+	if tracker := GetSessionTracker(); tracker != nil {
+		tracker.Playback(*mc.currentRoom, mc.UserID, data)
+	}
 	log.Printf("User %d (%s) controlled playback in room %d", mc.UserID, role, *mc.currentRoom)
 }
 
@@ -503,6 +548,10 @@ func (mc *MasterConn) joinRoom(roomID int) {
 
 	mc.publishRoomEvent(roomID, event)
 
+	if tracker := GetSessionTracker(); tracker != nil {
+		tracker.Join(roomID, mc.UserID)
+	}
+
 	if presenceManager := GetPresenceManager(); presenceManager != nil {
 		presenceManager.SetWatching(mc.UserID, fmt.Sprintf("Room %d", roomID), map[string]interface{}{
 			"room_id": roomID,
@@ -525,6 +574,10 @@ func (mc *MasterConn) leaveRoom(roomID int) {
 
 	mc.publishRoomEvent(roomID, event)
 
+	if tracker := GetSessionTracker(); tracker != nil {
+		tracker.Leave(roomID, mc.UserID)
+	}
+
 	if presenceManager := GetPresenceManager(); presenceManager != nil {
 		presenceManager.StopWatching(mc.UserID)
 	}
@@ -601,22 +654,51 @@ func (mc *MasterConn) subscribeToNotifications() {
 }
 
 func (mc *MasterConn) publishRoomEvent(roomID int, event RoomEvent) {
+	if err := publishRoomEvent(roomID, event); err != nil {
+		log.Printf("Error publishing event: %v", err)
+	}
+}
+
+// PublishRoomEvent broadcasts an event to a room from outside a websocket
+// connection, e.g. an HTTP handler posting into the room chat
+func PublishRoomEvent(roomID int, eventType string, userID int, username string, data map[string]interface{}) error {
+	return publishRoomEvent(roomID, RoomEvent{
+		Type:      eventType,
+		UserID:    userID,
+		Username:  username,
+		Timestamp: time.Now().Unix(),
+		Data:      data,
+	})
+}
+
+func publishRoomEvent(roomID int, event RoomEvent) error {
+	if repo := GetWebhookRepository(); repo != nil {
+		repo.EnqueueRoomEventAsync(roomID, event.Type, map[string]interface{}{
+			"type":      event.Type,
+			"room_id":   roomID,
+			"user_id":   event.UserID,
+			"username":  event.Username,
+			"timestamp": event.Timestamp,
+			"data":      event.Data,
+		})
+	}
+
 	redisClient, err := GetRedisClient()
 	if err != nil {
-		log.Printf("Failed to get Redis client: %v", err)
-		return
+		return fmt.Errorf("failed to get Redis client: %v", err)
 	}
 
 	eventJSON, err := json.Marshal(event)
 	if err != nil {
-		log.Printf("Error serializing event: %v", err)
-		return
+		return fmt.Errorf("failed to serialize event: %v", err)
 	}
 
 	roomChannel := fmt.Sprintf("room:%d:events", roomID)
 	if err := redisClient.Publish(context.Background(), roomChannel, eventJSON).Err(); err != nil {
-		log.Printf("Error publishing event: %v", err)
+		return fmt.Errorf("failed to publish event: %v", err)
 	}
+
+	return nil
 }
 
 func (mc *MasterConn) sendConnectionEstablished() {
@@ -689,9 +771,22 @@ func (mc *MasterConn) writePump() {
 }
 
 func SendNotification(userID int, notificationType string, data map[string]interface{}) error {
-	redisClient, err := GetRedisClient()
-	if err != nil {
-		return fmt.Errorf("failed to get Redis client: %v", err)
+	delivery := routeNotification(userID, notificationType)
+
+	// webhooks are opted into explicitly, so they ignore the per-type
+	// channels, but like pushes they stay quiet during quiet hours and DND
+	if repo := GetWebhookRepository(); repo != nil && !delivery.Quiet {
+		repo.EnqueueUserEventAsync(userID, notificationType, map[string]interface{}{
+			"type":      notificationType,
+			"user_id":   userID,
+			"timestamp": time.Now().Unix(),
+			"data":      data,
+		})
+	}
+
+	if !delivery.Store && !delivery.Live {
+		log.Printf("Skipping %s notification for user %d (disabled)", notificationType, userID)
+		return nil
 	}
 
 	notification := map[string]interface{}{
@@ -700,6 +795,32 @@ func SendNotification(userID int, notificationType string, data map[string]inter
 		"user_id":           userID,
 		"timestamp":         time.Now().Unix(),
 		"data":              data,
+		"silent":            delivery.Silent,
+	}
+
+	if delivery.Email {
+		go sendNotificationEmail(userID, notificationType, data)
+	}
+
+	// persist first so offline users find it in their inbox by the time a
+	// push brings them back
+	if repo := GetNotificationRepository(); repo != nil && delivery.Store {
+		stored, err := repo.Create(context.Background(), userID, notificationType, data)
+		if err != nil {
+			log.Printf("Failed to store %s notification for user %d: %v", notificationType, userID, err)
+		} else {
+			notification["id"] = stored.ID
+			notification["timestamp"] = stored.CreatedAt.Unix()
+		}
+	}
+
+	if delivery.Live && !delivery.Silent {
+		pushIfOffline(userID, notificationType, data)
+	}
+
+	redisClient, err := GetRedisClient()
+	if err != nil {
+		return fmt.Errorf("failed to get Redis client: %v", err)
 	}
 
 	notificationJSON, err := json.Marshal(notification)
@@ -821,6 +942,7 @@ func SendStatusUpdate(userID int, status, activity string, customData map[string
 	if err != nil {
 		return fmt.Errorf("failed to get friends: %v", err)
 	}
+	friendIDs = statusRecipients(friendIDs)
 
 	log.Printf("👥 Sending status update to %d friends", len(friendIDs))
 
@@ -0,0 +1,131 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+	"zync-stream/notifications"
+)
+
+var (
+	globalNotificationRepo *notifications.NotificationRepository
+	globalMailer           notifications.Mailer
+)
+
+func SetNotificationRepository(repo *notifications.NotificationRepository) {
+	globalNotificationRepo = repo
+}
+
+func GetNotificationRepository() *notifications.NotificationRepository {
+	return globalNotificationRepo
+}
+
+func SetMailer(mailer notifications.Mailer) {
+	globalMailer = mailer
+}
+
+// routeNotification applies the recipient's preferences, quiet hours and
+// do-not-disturb status. Without a repository everything is delivered live.
+func routeNotification(userID int, notificationType string) notifications.Delivery {
+	repo := GetNotificationRepository()
+	if repo == nil {
+		return notifications.Delivery{Live: true}
+	}
+
+	prefs, err := repo.GetPreferences(context.Background(), userID)
+	if err != nil {
+		log.Printf("Failed to load notification preferences for user %d: %v", userID, err)
+		prefs = notifications.DefaultPreferences()
+	}
+
+	dnd := false
+	if presenceManager := GetPresenceManager(); presenceManager != nil {
+		dnd = presenceManager.IsDND(userID)
+	}
+
+	return prefs.Route(notificationType, time.Now(), dnd)
+}
+
+func sendNotificationEmail(userID int, notificationType string, data map[string]interface{}) {
+	repo := GetNotificationRepository()
+	if repo == nil || globalMailer == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	email, err := repo.GetUserEmail(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to look up email for user %d: %v", userID, err)
+		return
+	}
+
+	subject, body := notifications.EmailContent(notificationType, data)
+	if err := globalMailer.Send(ctx, email, subject, body); err != nil {
+		log.Printf("Failed to email %s notification to user %d: %v", notificationType, userID, err)
+	}
+}
+
+// statusRecipients drops friends whose preferences keep status updates from
+// reaching them right now: muted, in quiet hours or in do-not-disturb.
+// Status updates are not stored, so a silenced one is simply not sent.
+func statusRecipients(friendIDs []int) []int {
+	repo := GetNotificationRepository()
+	if repo == nil {
+		return friendIDs
+	}
+
+	prefs, err := repo.PreferencesFor(context.Background(), friendIDs, notifications.TypeStatusUpdate)
+	if err != nil {
+		log.Printf("Failed to load status update preferences: %v", err)
+		return friendIDs
+	}
+
+	presenceManager := GetPresenceManager()
+	now := time.Now()
+
+	recipients := make([]int, 0, len(friendIDs))
+	for _, friendID := range friendIDs {
+		dnd := presenceManager != nil && presenceManager.IsDND(friendID)
+		delivery := prefs[friendID].Route(notifications.TypeStatusUpdate, now, dnd)
+		if delivery.Live && !delivery.Silent {
+			recipients = append(recipients, friendID)
+		}
+	}
+	return recipients
+}
+
+// sendUnreadCount tells a freshly connected client how many inbox items it missed
+func (mc *MasterConn) sendUnreadCount() {
+	repo := GetNotificationRepository()
+	if repo == nil {
+		return
+	}
+
+	count, err := repo.UnreadCount(context.Background(), mc.UserID)
+	if err != nil {
+		log.Printf("Failed to load unread count for user %d: %v", mc.UserID, err)
+		return
+	}
+
+	event := map[string]interface{}{
+		"type":      "notification_unread_count",
+		"user_id":   mc.UserID,
+		"timestamp": time.Now().Unix(),
+		"data": map[string]interface{}{
+			"count": count,
+		},
+	}
+
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	select {
+	case mc.Send <- eventJSON:
+	case <-mc.done:
+	}
+}
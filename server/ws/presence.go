@@ -25,6 +25,13 @@ type UserPresence struct {
 	ConnectedAt  time.Time              `json:"connected_at"`
 	ManualStatus string                 `json:"manual_status"`
 	CustomData   map[string]interface{} `json:"custom_data"`
+	Privacy      PresencePrivacy        `json:"-"`
+}
+
+// PresencePrivacy controls what a user's friends get to see of their presence
+type PresencePrivacy struct {
+	HideActivity bool `json:"hide_activity"`
+	Invisible    bool `json:"invisible"`
 }
 
 type PresenceManager struct {
@@ -46,6 +53,7 @@ type UserRepository interface {
 	UpdateUserStatus(ctx context.Context, userID int, status, activity string) error
 	GetUserFriends(ctx context.Context, userID int) ([]int, error)
 	GetFriendsWithStatus(ctx context.Context, userID int) ([]FriendStatusInfo, error)
+	GetPresencePrivacy(ctx context.Context, userID int) (PresencePrivacy, error)
 }
 
 type StatusUpdate struct {
@@ -75,6 +83,11 @@ func GetPresenceManager() *PresenceManager {
 }
 
 func (pm *PresenceManager) AddConnection(userID int, username string, conn *NotificationConnection) {
+	privacy, err := pm.userRepo.GetPresencePrivacy(context.Background(), userID)
+	if err != nil {
+		log.Printf("Failed to load privacy settings for user %d: %v", userID, err)
+	}
+
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
@@ -88,6 +101,7 @@ func (pm *PresenceManager) AddConnection(userID int, username string, conn *Noti
 	if presence := pm.users[userID]; presence != nil {
 		presence.ConnectedAt = time.Now()
 		presence.LastActivity = time.Now()
+		presence.Privacy = privacy
 		log.Printf("👤 Updated existing presence for user %d", userID)
 	} else {
 		pm.users[userID] = &UserPresence{
@@ -99,6 +113,7 @@ func (pm *PresenceManager) AddConnection(userID int, username string, conn *Noti
 			ConnectedAt:  time.Now(),
 			ManualStatus: "",
 			CustomData:   make(map[string]interface{}),
+			Privacy:      privacy,
 		}
 		log.Printf("👤 Created new presence for user %d", userID)
 	}
@@ -265,22 +280,73 @@ func (pm *PresenceManager) updateUserStatus(userID int, isDisconnecting bool) {
 	if newStatus != oldStatus {
 		presence.Status = newStatus
 		log.Printf("Status changed for user %d, broadcasting to friends...", userID)
+		pm.publishStatus(presence)
 
-		go func() {
-			ctx := context.Background()
-			if err := pm.userRepo.UpdateUserStatus(ctx, userID, newStatus, presence.Activity); err != nil {
-				log.Printf("Failed to update user %d status in DB: %v", userID, err)
-			} else {
-				log.Printf("Updated user %d status in DB", userID)
-			}
-		}()
-
-		go pm.broadcastStatusToFriends(userID, newStatus, presence.Activity, presence.CustomData)
+		if newStatus == StatusWatching && !presence.Privacy.Invisible && !presence.Privacy.HideActivity {
+			go pushFriendWatching(userID, presence.Username, presence.Activity, presence.CustomData["room_id"])
+		}
 	} else {
 		log.Printf("No status change for user %d, skipping broadcast", userID)
 	}
 }
 
+// publishStatus persists and broadcasts what friends are allowed to see of
+// presence. Must be called with the mutex held.
+func (pm *PresenceManager) publishStatus(presence *UserPresence) {
+	userID := presence.UserID
+	status, activity, customData := visibleStatus(presence)
+
+	go func() {
+		ctx := context.Background()
+		if err := pm.userRepo.UpdateUserStatus(ctx, userID, status, activity); err != nil {
+			log.Printf("Failed to update user %d status in DB: %v", userID, err)
+		} else {
+			log.Printf("Updated user %d status in DB", userID)
+		}
+	}()
+
+	go pm.broadcastStatusToFriends(userID, status, activity, customData)
+}
+
+// visibleStatus applies the user's privacy settings to their live presence.
+// Invisible users appear offline; hidden activity shows as plain online.
+func visibleStatus(presence *UserPresence) (string, string, map[string]interface{}) {
+	if presence.Privacy.Invisible {
+		return StatusOffline, "", nil
+	}
+
+	if presence.Privacy.HideActivity {
+		status := presence.Status
+		if status == StatusWatching {
+			status = StatusOnline
+		}
+		return status, "", nil
+	}
+
+	return presence.Status, presence.Activity, presence.CustomData
+}
+
+// SetPrivacy applies changed privacy settings to a connected user and
+// re-broadcasts their status so friends see the effect immediately.
+func (pm *PresenceManager) SetPrivacy(userID int, privacy PresencePrivacy) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	presence, exists := pm.users[userID]
+	if !exists {
+		return
+	}
+
+	if presence.Privacy == privacy {
+		return
+	}
+
+	presence.Privacy = privacy
+	if presence.Status != StatusOffline {
+		pm.publishStatus(presence)
+	}
+}
+
 func (pm *PresenceManager) calculateEffectiveStatus(presence *UserPresence, isDisconnecting bool) string {
 	if isDisconnecting {
 		return StatusOffline
@@ -307,6 +373,15 @@ func (pm *PresenceManager) broadcastStatusToFriends(userID int, status, activity
 	}
 }
 
+// HasConnection reports whether the user has a live websocket on this server
+func (pm *PresenceManager) HasConnection(userID int) bool {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	_, exists := pm.connections[userID]
+	return exists
+}
+
 func (pm *PresenceManager) IsUserOnline(userID int) bool {
 	pm.mutex.RLock()
 	defer pm.mutex.RUnlock()
@@ -315,6 +390,15 @@ func (pm *PresenceManager) IsUserOnline(userID int) bool {
 	return exists && presence.Status != StatusOffline
 }
 
+// IsDND reports whether a connected user asked not to be disturbed
+func (pm *PresenceManager) IsDND(userID int) bool {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	presence, exists := pm.users[userID]
+	return exists && (presence.Status == StatusDND || presence.ManualStatus == StatusDND)
+}
+
 func (pm *PresenceManager) GetUserStatus(userID int) *UserPresence {
 	pm.mutex.RLock()
 	defer pm.mutex.RUnlock()
@@ -346,15 +430,17 @@ func (pm *PresenceManager) GetOnlineUsers() []UserPresence {
 }
 
 func (pm *PresenceManager) createStatusUpdateFromPresence(presence *UserPresence) StatusUpdate {
+	status, activity, customData := visibleStatus(presence)
+
 	statusUpdate := StatusUpdate{
 		Type: "status_update",
 	}
 	statusUpdate.Data.UserID = presence.UserID
 	statusUpdate.Data.Username = presence.Username
-	statusUpdate.Data.Status = presence.Status
-	statusUpdate.Data.Activity = presence.Activity
+	statusUpdate.Data.Status = status
+	statusUpdate.Data.Activity = activity
 	statusUpdate.Data.Timestamp = time.Now().Unix()
-	statusUpdate.Data.Data = presence.CustomData
+	statusUpdate.Data.Data = customData
 
 	return statusUpdate
 }
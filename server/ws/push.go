@@ -0,0 +1,115 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"zync-stream/notifications"
+	"zync-stream/push"
+)
+
+var globalPushSender *push.Sender
+
+func SetPushSender(sender *push.Sender) {
+	globalPushSender = sender
+}
+
+func GetPushSender() *push.Sender {
+	return globalPushSender
+}
+
+// isOffline reports whether the user has no live connection to this server
+func isOffline(userID int) bool {
+	presenceManager := GetPresenceManager()
+	return presenceManager == nil || !presenceManager.HasConnection(userID)
+}
+
+// pushIfOffline sends a Web Push message when the user has no open session,
+// since the realtime notification published for them would otherwise be lost
+func pushIfOffline(userID int, notificationType string, data map[string]interface{}) {
+	sender := GetPushSender()
+	if sender == nil || !push.IsPushable(notificationType) || !isOffline(userID) {
+		return
+	}
+
+	msg := pushMessage(notificationType, data)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		delivered, err := sender.Send(ctx, userID, msg)
+		if err != nil {
+			log.Printf("Failed to push %s to user %d: %v", notificationType, userID, err)
+			return
+		}
+		if delivered > 0 {
+			log.Printf("📲 Pushed %s to %d device(s) of user %d", notificationType, delivered, userID)
+		}
+	}()
+}
+
+// pushFriendWatching lets offline friends know a user started watching something
+func pushFriendWatching(userID int, username, activity string, roomID interface{}) {
+	if GetPushSender() == nil {
+		return
+	}
+
+	presenceManager := GetPresenceManager()
+	if presenceManager == nil {
+		return
+	}
+
+	friendIDs, err := presenceManager.userRepo.GetUserFriends(context.Background(), userID)
+	if err != nil {
+		log.Printf("Failed to load friends of user %d for push: %v", userID, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"user_id":  userID,
+		"username": username,
+		"activity": activity,
+	}
+	if roomID != nil {
+		data["room_id"] = roomID
+	}
+
+	for _, friendID := range friendIDs {
+		if !isOffline(friendID) {
+			continue
+		}
+		delivery := routeNotification(friendID, notifications.TypeStatusUpdate)
+		if !delivery.Live || delivery.Silent {
+			continue
+		}
+		pushIfOffline(friendID, push.TypeFriendWatching, data)
+	}
+}
+
+func pushMessage(notificationType string, data map[string]interface{}) push.Message {
+	msg := push.Message{
+		Type:  notificationType,
+		Title: "Zync",
+		Data:  data,
+	}
+
+	switch notificationType {
+	case push.TypeRoomInvitation:
+		msg.Title = "Room invitation"
+		msg.Body = fmt.Sprintf("%v invited you to watch in %v", data["inviter_name"], data["room_name"])
+	case push.TypeFriendRequestReceived:
+		msg.Title = "New friend request"
+		msg.Body = fmt.Sprintf("%v wants to be your friend", data["username"])
+	case push.TypeFriendWatching:
+		msg.Title = fmt.Sprintf("%v started watching", data["username"])
+		msg.Body = fmt.Sprintf("%v", data["activity"])
+	case push.TypeDirectMessage:
+		msg.Title = fmt.Sprintf("Message from %v", data["sender_username"])
+		msg.Body = fmt.Sprintf("%v", data["content"])
+	}
+
+	return msg
+}
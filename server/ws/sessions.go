@@ -0,0 +1,13 @@
+package ws
+
+import "zync-stream/sessions"
+
+var globalSessionTracker *sessions.Tracker
+
+func SetSessionTracker(tracker *sessions.Tracker) {
+	globalSessionTracker = tracker
+}
+
+func GetSessionTracker() *sessions.Tracker {
+	return globalSessionTracker
+}
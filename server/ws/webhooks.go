@@ -0,0 +1,13 @@
+package ws
+
+import "zync-stream/webhooks"
+
+var globalWebhookRepo *webhooks.WebhookRepository
+
+func SetWebhookRepository(repo *webhooks.WebhookRepository) {
+	globalWebhookRepo = repo
+}
+
+func GetWebhookRepository() *webhooks.WebhookRepository {
+	return globalWebhookRepo
+}